@@ -0,0 +1,36 @@
+package main
+
+import (
+	"context"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/config"
+)
+
+// auditCommand runs audit verify, which walks the audit log's hash chain and
+// prints the entry count and head hash. It fails at the first entry that was
+// modified, removed or reordered. Keep the head hash elsewhere too, since
+// entries dropped from the end can only be caught by comparing against it.
+func auditCommand(ctx context.Context, cfg *config.Config, args []string) int {
+	if len(args) != 1 || args[0] != "verify" {
+		return usageError("want audit verify")
+	}
+	db, err := openDB(ctx, cfg)
+	if err != nil {
+		logger.ErrorContext(ctx, "Database unavailable", "error", err)
+		return exitFailed
+	}
+	defer db.Close()
+
+	res, err := audit.NewLogger(db).Verify(ctx)
+	if err != nil {
+		logger.ErrorContext(ctx, "Verification failed", "error", err)
+		return exitFailed
+	}
+	printJSON(res)
+	if !res.OK() {
+		logger.ErrorContext(ctx, "Audit log broken", "broken_at", res.BrokenAt, "reason", res.Reason)
+		return exitFailed
+	}
+	return exitOK
+}
@@ -0,0 +1,227 @@
+// Command vest runs the server and the one-off tasks operators start from a
+// shell or an ECS run-task. Every command reads the server's configuration.
+//
+//	vest serve                     run the HTTP and gRPC APIs and the SFTP ingestor
+//	vest ingest [-file] PATH...    ingest local files as if uploaded over SFTP
+//	vest validate PATH...          parse files and report what would be loaded
+//	vest recompute -date DATE...   evaluate compliance alarms for dates again
+//	vest migrate [up]              apply every pending migration
+//	vest migrate down [N]          revert the latest N migrations (default 1)
+//	vest migrate status            list migrations and when they were applied
+//	vest audit verify              check the audit log's hash chain
+//	vest config print              print the settings with secrets redacted
+//
+// -config (or VEST_CONFIG) names a YAML or TOML config file and goes before
+// the command. Results are written to stdout as JSON, one object per line,
+// and logs to stderr. The exit status is 0 on success, 1 when the task fails
+// or finds a problem, and 2 for usage and configuration errors.
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/config"
+	"github.com/AndrewCharlesHay/vest/internal/database"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+)
+
+var logger = logging.Component("server")
+
+// Exit statuses
+const (
+	exitOK     = 0
+	exitFailed = 1
+	exitUsage  = 2
+)
+
+const usage = `Usage: vest [-config FILE] COMMAND [ARGS]
+
+Commands:
+  serve                     run the HTTP and gRPC APIs and the SFTP ingestor
+  ingest [-file] PATH...    ingest local files as if uploaded over SFTP
+  validate PATH...          parse files and report what would be loaded
+  recompute -date DATE...   evaluate compliance alarms for dates again
+  migrate [up]              apply every pending migration
+  migrate down [N]          revert the latest N migrations (default 1)
+  migrate status            list migrations and when they were applied
+  audit verify              check the audit log's hash chain
+  config print              print the settings with secrets redacted
+
+Exit status is 0 on success, 1 when the task fails or finds a problem and
+2 for usage and configuration errors.
+`
+
+func main() {
+	flags := flag.NewFlagSet("vest", flag.ContinueOnError)
+	flags.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	configPath := flags.String("config", os.Getenv(config.PathEnv), "YAML or TOML config file; env vars override its settings")
+	if err := flags.Parse(os.Args[1:]); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			os.Exit(exitOK)
+		}
+		os.Exit(exitUsage)
+	}
+	args := flags.Args()
+	if len(args) == 0 {
+		flags.Usage()
+		os.Exit(exitUsage)
+	}
+	os.Exit(run(*configPath, args[0], args[1:]))
+}
+
+// run dispatches a command, returning its exit status
+func run(configPath, name string, args []string) int {
+	// Commands that don't need a valid configuration
+	switch name {
+	case "validate":
+		return validate(args)
+	case "config":
+		return configCommand(configPath, args)
+	case "help":
+		fmt.Print(usage)
+		return exitOK
+	}
+
+	commands := map[string]func(ctx context.Context, cfg *config.Config, args []string) int{
+		"ingest":    ingestFiles,
+		"recompute": recompute,
+		"migrate":   migrateCommand,
+		"audit":     auditCommand,
+	}
+	task, ok := commands[name]
+	if name != "serve" && !ok {
+		return usageError("unknown command %q", name)
+	}
+
+	// Every setting is checked before anything starts
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vest:", err)
+		return exitUsage
+	}
+	if name == "serve" {
+		if len(args) > 0 {
+			return usageError("serve takes no arguments")
+		}
+		serve(cfg, configPath)
+		return exitOK
+	}
+
+	// Task output goes to stdout, so logs go to stderr
+	if err := logging.Setup(os.Stderr, cfg.Log.Format, cfg.Log.Level); err != nil {
+		fmt.Fprintln(os.Stderr, "vest:", err)
+		return exitUsage
+	}
+	// Ctrl-C or ECS stopping a run-task cancels the task's queries
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	flushSpans, err := startTracing(cfg, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vest:", err)
+		return exitFailed
+	}
+	defer flushSpans()
+	return task(ctx, cfg, args)
+}
+
+// spanFlushTimeout bounds how long exiting waits for spans to be exported
+const spanFlushTimeout = 5 * time.Second
+
+// startTracing installs the configured span exporter; the stdout exporter
+// writes to w, beside the logs. The returned function exports buffered
+// spans and must run before exiting.
+func startTracing(cfg *config.Config, w io.Writer) (flush func(), err error) {
+	shutdown, err := tracing.Setup(context.Background(), tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		Endpoint:    cfg.Tracing.Endpoint,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		ServiceName: cfg.Tracing.ServiceName,
+	}, w)
+	if err != nil {
+		return nil, err
+	}
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), spanFlushTimeout)
+		defer cancel()
+		if err := shutdown(ctx); err != nil {
+			logger.Warn("Failed to export spans", "error", err)
+		}
+	}, nil
+}
+
+// usageError prints a message and the usage, returning exitUsage
+func usageError(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "vest: "+format+"\n\n", args...)
+	fmt.Fprint(os.Stderr, usage)
+	return exitUsage
+}
+
+// openDB opens and pings the configured database
+func openDB(ctx context.Context, cfg *config.Config) (*sql.DB, error) {
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		return nil, err
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("connecting to the database: %w", err)
+	}
+	return db, nil
+}
+
+// printJSON writes v to stdout on one line
+func printJSON(v any) {
+	if err := json.NewEncoder(os.Stdout).Encode(v); err != nil {
+		logger.Error("Failed to write output", "error", err)
+	}
+}
+
+// stringList is a flag that may be repeated or given comma-separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(v string) error {
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// configCommand runs config print, which prints the settings even when they
+// are invalid and then fails
+func configCommand(configPath string, args []string) int {
+	if len(args) != 1 || args[0] != "print" {
+		return usageError("want config print")
+	}
+	cfg, err := config.Read(configPath)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "vest:", err)
+		return exitFailed
+	}
+	if err := cfg.Print(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "vest:", err)
+		return exitFailed
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintln(os.Stderr, "vest:", err)
+		return exitFailed
+	}
+	return exitOK
+}
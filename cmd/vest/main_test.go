@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRunExitStatus(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "positions.psv")
+	bad := filepath.Join(dir, "notes.txt")
+	os.WriteFile(good, []byte("ReportDate|AccountID|SecurityTicker|Shares|MarketValue|SourceSystem\n20250115|1001|GOOG|50|140.00|ReportingSystem"), 0o600)
+	os.WriteFile(bad, []byte("hello\nworld"), 0o600)
+
+	cases := []struct {
+		name string
+		cmd  string
+		args []string
+		want int
+	}{
+		{"valid file", "validate", []string{good}, exitOK},
+		{"unrecognised file", "validate", []string{good, bad}, exitFailed},
+		{"missing file", "validate", []string{filepath.Join(dir, "missing.csv")}, exitFailed},
+		{"no files", "validate", nil, exitUsage},
+		{"unknown command", "frobnicate", nil, exitUsage},
+		{"bad config subcommand", "config", []string{"edit"}, exitUsage},
+	}
+	for _, c := range cases {
+		if got := run("", c.cmd, c.args); got != c.want {
+			t.Errorf("%s: expected exit %d, got %d", c.name, c.want, got)
+		}
+	}
+}
+
+func TestRunRejectsInvalidConfig(t *testing.T) {
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("DB_HOST", "")
+	if got := run("", "migrate", []string{"status"}); got != exitUsage {
+		t.Errorf("expected exit %d without a database configured, got %d", exitUsage, got)
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/config"
+	"github.com/AndrewCharlesHay/vest/internal/migrate"
+)
+
+// migration is how migrate reports a migration
+type migration struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// migrateCommand applies or reverts the migrations built into the binary,
+// printing each one it applies, reverts or, for status, lists
+func migrateCommand(ctx context.Context, cfg *config.Config, args []string) int {
+	cmd := "up"
+	if len(args) > 0 {
+		cmd, args = args[0], args[1:]
+	}
+	steps := 1
+	switch {
+	case cmd == "down" && len(args) == 1:
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 {
+			return usageError("migrate down: invalid step count %q", args[0])
+		}
+		steps = n
+	case cmd != "up" && cmd != "down" && cmd != "status":
+		return usageError("unknown migrate command %q: want up, down [N] or status", cmd)
+	case len(args) > 0:
+		return usageError("migrate %s: unexpected arguments %q", cmd, args)
+	}
+
+	db, err := openDB(ctx, cfg)
+	if err != nil {
+		logger.ErrorContext(ctx, "Database unavailable", "error", err)
+		return exitFailed
+	}
+	defer db.Close()
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		logger.ErrorContext(ctx, "Loading migrations failed", "error", err)
+		return exitFailed
+	}
+	m := migrate.NewMigrator(db, migrations)
+
+	var done []migrate.Migration
+	switch cmd {
+	case "up":
+		done, err = m.Up(ctx)
+	case "down":
+		done, err = m.Down(ctx, steps)
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			logger.ErrorContext(ctx, "Reading migration status failed", "error", err)
+			return exitFailed
+		}
+		for _, s := range statuses {
+			printJSON(migration{Version: s.Version, Name: s.Name, AppliedAt: s.AppliedAt})
+		}
+		return exitOK
+	}
+	for _, mig := range done {
+		printJSON(migration{Version: mig.Version, Name: mig.Name})
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "Migration failed", "command", cmd, "error", err)
+		return exitFailed
+	}
+	logger.InfoContext(ctx, "Migrations complete", "command", cmd, "count", len(done))
+	return exitOK
+}
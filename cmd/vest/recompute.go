@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/config"
+)
+
+// recompute evaluates alarms for each date again, as after an ingestion,
+// printing the alarms opened and cleared. Use it after changing rules,
+// aliases or the security master.
+func recompute(ctx context.Context, cfg *config.Config, args []string) int {
+	flags := flag.NewFlagSet("recompute", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	var dates stringList
+	flags.Var(&dates, "date", "position date (YYYY-MM-DD); may be repeated")
+	if err := flags.Parse(args); err != nil {
+		return usageError("recompute: %v", err)
+	}
+	if flags.NArg() > 0 {
+		return usageError("recompute takes dates with -date, got %q", flags.Args())
+	}
+	if len(dates) == 0 {
+		return usageError("recompute needs -date")
+	}
+	for _, date := range dates {
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			return usageError("recompute: date must be YYYY-MM-DD, got %q", date)
+		}
+	}
+
+	db, err := openDB(ctx, cfg)
+	if err != nil {
+		logger.ErrorContext(ctx, "Database unavailable", "error", err)
+		return exitFailed
+	}
+	defer db.Close()
+	svc := newServices(db, cfg)
+
+	for _, date := range dates {
+		result, err := svc.monitor.Evaluate(ctx, date)
+		if err != nil {
+			logger.ErrorContext(ctx, "Alarm evaluation failed", "date", date, "error", err)
+			return exitFailed
+		}
+		printJSON(result)
+	}
+	return exitOK
+}
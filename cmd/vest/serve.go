@@ -0,0 +1,393 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/api"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+	"github.com/AndrewCharlesHay/vest/internal/config"
+	"github.com/AndrewCharlesHay/vest/internal/database"
+	"github.com/AndrewCharlesHay/vest/internal/graphql"
+	"github.com/AndrewCharlesHay/vest/internal/health"
+	"github.com/AndrewCharlesHay/vest/internal/ingest"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/metrics"
+	"github.com/AndrewCharlesHay/vest/internal/middleware"
+	"github.com/AndrewCharlesHay/vest/internal/migrate"
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+	"github.com/AndrewCharlesHay/vest/internal/rpc"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+// HTTP server timeouts. /events clears its write deadline, since a stream
+// stays open indefinitely.
+const (
+	readHeaderTimeout = 10 * time.Second
+	readTimeout       = 30 * time.Second
+	writeTimeout      = 60 * time.Second
+	idleTimeout       = 120 * time.Second
+)
+
+// serve runs the HTTP and gRPC APIs, the SFTP ingestor and the alarm
+// schedule until SIGTERM, then shuts down gracefully
+func serve(cfg *config.Config, configPath string) {
+	// JSON logs for CloudWatch; LOG_FORMAT=text reads better locally
+	if err := logging.Setup(os.Stdout, cfg.Log.Format, cfg.Log.Level); err != nil {
+		fatal("Invalid logging configuration", "error", err)
+	}
+	flushSpans, err := startTracing(cfg, os.Stdout)
+	if err != nil {
+		fatal("Tracing setup failed", "error", err)
+	}
+
+	// SIGTERM (ECS stopping the task) or Ctrl-C starts a graceful shutdown: stop
+	// accepting work, let in-flight requests and ingestion finish, then exit
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	shutdownTimeout := cfg.Server.ShutdownTimeout
+
+	// 1. DB Connection
+	db, err := database.Open(cfg.Database)
+	if err != nil {
+		fatal("Opening database failed", "error", err)
+	}
+	defer db.Close()
+
+	// Wait for DB to be ready
+	for i := 0; ; i++ {
+		err := db.PingContext(ctx)
+		if err == nil {
+			break
+		}
+		if i == 9 {
+			fatal("Database not reachable", "error", err)
+		}
+		logger.InfoContext(ctx, "Waiting for DB")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(2 * time.Second):
+		}
+	}
+
+	// Apply pending migrations. Concurrent tasks wait on an advisory lock, so
+	// only one migrates; run vest migrate instead with AUTO_MIGRATE=false.
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		fatal("Loading migrations failed", "error", err)
+	}
+	migrator := migrate.NewMigrator(db, migrations)
+	if cfg.Server.AutoMigrate {
+		if _, err := migrator.Up(ctx); err != nil {
+			fatal("Migration failed", "error", err)
+		}
+	}
+	logger.InfoContext(ctx, "Database schema initialized")
+
+	// Readiness: the database and schema are required by default, the SFTP
+	// session, ingestion and data freshness only degrade the report
+	checker := health.NewChecker(db, migrator.Latest())
+	configureReadiness(checker, cfg.Ready)
+
+	// Pool stats and open alarm counts for /metrics
+	if err := metrics.RegisterDB(db); err != nil {
+		fatal("Registering metrics failed", "error", err)
+	}
+
+	// Background work stops when ctx is cancelled; shutdown waits for it
+	var workers sync.WaitGroup
+	background := func(fn func()) {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			fn()
+		}()
+	}
+
+	// Persisted alarms: evaluated after each ingestion and on a schedule. The
+	// audit log, webhooks and the /events stream hear when they open or clear.
+	svc := newServices(db, cfg)
+	monitor, notifier, broker, auditLog := svc.monitor, svc.notifier, svc.broker, svc.audit
+	background(func() { monitor.Start(ctx, cfg.Alarms.EvalInterval) })
+
+	// The audit log outlives the servers so it records every request they finish
+	auditCtx, stopAudit := context.WithCancel(context.WithoutCancel(ctx))
+	auditDone := make(chan struct{})
+	go func() {
+		auditLog.Start(auditCtx)
+		close(auditDone)
+	}()
+	background(func() { notifier.Start(ctx) })
+	background(func() { broker.Start(ctx) })
+
+	// Responses for a date are cached until ingestion bumps its data version
+	svc.responses = cache.NewCache(db)
+
+	// 2. SFTP Connection for Ingestion
+	// Ingestion is optional; without an SFTP host the instance only serves the API
+	if cfg.SFTP.Host != "" {
+		session := health.NewSession()
+		checker.SFTP = session
+		background(func() {
+			logger.InfoContext(ctx, "Starting SFTP ingestor", "host", cfg.SFTP.Host)
+			for ctx.Err() == nil {
+				err := runIngestor(ctx, db, cfg.SFTP, session, svc, shutdownTimeout)
+				if err != nil {
+					session.Observe(err)
+					logger.ErrorContext(ctx, "Ingestor failed, retrying in 5s", "error", err)
+					select {
+					case <-ctx.Done():
+					case <-time.After(5 * time.Second):
+					}
+				}
+			}
+		})
+	}
+
+	// 3. API Server, sharing the components wired above
+	h := &api.Handler{
+		DB:       db,
+		Monitor:  monitor,
+		Webhooks: notifier,
+		Events:   broker,
+		Keys:     &auth.KeyStore{DB: db, Bootstrap: cfg.Auth.APIKey.Value()},
+		Audit:    auditLog,
+	}
+
+	// Every route shares one mux, wrapped below in auth, audit and rate limiting
+	mux := http.NewServeMux()
+	for _, route := range h.Routes(svc.responses) {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
+	// GraphQL shares the REST query layer and sits behind the same API key auth
+	gql, err := graphql.NewHandler(h)
+	if err != nil {
+		fatal("GraphQL schema failed to load", "error", err)
+	}
+	mux.Handle("/graphql", gql)
+	// Health checks are public. /health stays as an alias of /livez for
+	// existing probes.
+	mux.HandleFunc("GET /livez", health.Live)
+	mux.HandleFunc("GET /health", health.Live)
+	mux.HandleFunc("GET /readyz", checker.Ready)
+	// Prometheus scrapes without credentials; no label identifies an account
+	mux.Handle("GET /metrics", metrics.Handler())
+
+	// API keys, plus OIDC bearer tokens when an issuer is configured
+	authn := &auth.Authenticator{Keys: h.Keys}
+	if oidc := cfg.OIDC; oidc.Issuer != "" {
+		verifier := auth.NewVerifier(oidc.Issuer, oidc.Audience, auth.NewJWKS(oidc.JWKS()))
+		if oidc.RolesClaim != "" {
+			verifier.RolesClaim = oidc.RolesClaim
+		}
+		if oidc.RoleScopes != "" {
+			verifier.RoleScopes, _ = auth.ParseRoleScopes(oidc.RoleScopes) // Validated by config.Load
+		}
+		authn.Tokens = verifier
+		logger.InfoContext(ctx, "Accepting bearer tokens", "issuer", oidc.Issuer)
+	}
+
+	// Per-caller token buckets; counters are shared across instances in Postgres
+	// when RATE_LIMIT_STORE=postgres
+	var limiter *ratelimit.Limiter
+	if cfg.RateLimit.Rules != "off" {
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimit.Store == "postgres" {
+			pg := ratelimit.NewPostgresStore(db)
+			background(func() { pg.Start(ctx) })
+			store = pg
+		}
+		rules, _ := ratelimit.ParseRules(cfg.RateLimit.Rules) // Validated by config.Load
+		limiter = ratelimit.NewLimiter(rules, store)
+		logger.InfoContext(ctx, "Rate limiting", "rules", cfg.RateLimit.Rules)
+	}
+
+	// Wrap with API Key Auth, except for probes and scrapes
+	public := map[string]bool{"/health": true, "/livez": true, "/readyz": true, "/metrics": true}
+	finalHandler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if public[r.URL.Path] {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		middleware.Authenticate(authn, middleware.Audit(auditLog, middleware.RateLimit(limiter, mux))).ServeHTTP(w, r)
+	})
+
+	// gRPC for internal consumers runs alongside HTTP, checking the same API key
+	grpcPort := strconv.Itoa(cfg.Server.GRPCPort)
+	lis, err := net.Listen("tcp", ":"+grpcPort)
+	if err != nil {
+		fatal("gRPC listen failed", "error", err)
+	}
+	grpcServer := rpc.NewServer(h, authn, limiter, auditLog)
+	go func() {
+		logger.InfoContext(ctx, "gRPC listening", "port", grpcPort)
+		if err := grpcServer.Serve(lis); err != nil {
+			fatal("gRPC server failed", "error", err)
+		}
+	}()
+
+	port := strconv.Itoa(cfg.Server.Port)
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           middleware.Tracing(mux, middleware.RequestID(middleware.AccessLog(mux, middleware.Metrics(mux, finalHandler)))),
+		ReadHeaderTimeout: readHeaderTimeout,
+		ReadTimeout:       readTimeout,
+		WriteTimeout:      writeTimeout,
+		IdleTimeout:       idleTimeout,
+	}
+	go func() {
+		logger.InfoContext(ctx, "Server listening", "port", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fatal("HTTP server failed", "error", err)
+		}
+	}()
+
+	// SIGHUP rereads the config. The log level, rate limits and readiness
+	// settings change in place; anything else waits for a restart.
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	background(func() {
+		running := cfg
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-hup:
+				running = reload(ctx, configPath, running, limiter, checker)
+			}
+		}
+	})
+
+	<-ctx.Done()
+	stop() // A second signal exits immediately
+	logger.Info("Shutting down, waiting for in-flight work", "timeout", shutdownTimeout.String())
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Event streams end when the broker stops, so they don't hold up Shutdown
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		logger.Warn("HTTP shutdown incomplete", "error", err)
+	}
+	grpcStopped := make(chan struct{})
+	go func() {
+		grpcServer.GracefulStop()
+		close(grpcStopped)
+	}()
+	select {
+	case <-grpcStopped:
+	case <-shutdownCtx.Done():
+		grpcServer.Stop()
+	}
+
+	workersDone := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(workersDone)
+	}()
+	select {
+	case <-workersDone:
+	case <-shutdownCtx.Done():
+		logger.Warn("Background work did not finish before the shutdown timeout")
+	}
+
+	stopAudit()
+	select {
+	case <-auditDone:
+	case <-time.After(5 * time.Second):
+		logger.Warn("Audit log did not drain before exit")
+	}
+	flushSpans()
+	logger.Info("Shutdown complete")
+}
+
+func runIngestor(ctx context.Context, db *sql.DB, cfg config.SFTP, session *health.Session, svc *services, drain time.Duration) error {
+	sshConfig := &ssh.ClientConfig{
+		User: cfg.User,
+		Auth: []ssh.AuthMethod{
+			ssh.Password(cfg.Password.Value()),
+		},
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // For exercise
+	}
+
+	conn, err := ssh.Dial("tcp", cfg.Host, sshConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	client, err := sftp.NewClient(conn)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	session.Observe(nil)
+
+	worker := ingest.NewWorker(db, client, cfg.Dir)
+	worker.DrainTimeout = drain
+	worker.OnPoll = session.Observe
+	worker.OnIngested = svc.fileIngested
+	worker.Start(ctx) // Loops until ctx is cancelled
+	return nil
+}
+
+// configureReadiness applies the readiness settings, which config.Load has
+// validated
+func configureReadiness(checker *health.Checker, c config.Ready) {
+	required, _ := health.ParseRequired(strings.Join(c.Require, ","))
+	checker.Configure(required, c.MaxIngestAge, c.MaxDataAge)
+}
+
+// reload reads the config again and applies the settings that are safe to
+// change while serving, returning the config now in effect. An invalid
+// config is logged and ignored.
+func reload(ctx context.Context, path string, running *config.Config, limiter *ratelimit.Limiter, checker *health.Checker) *config.Config {
+	next, err := config.Load(path)
+	if err != nil {
+		logger.ErrorContext(ctx, "Config reload failed, keeping the running config", "error", err)
+		return running
+	}
+	applied, reloaded, restart := config.Reload(running, next)
+	if len(restart) > 0 {
+		logger.WarnContext(ctx, "Config changes need a restart", "keys", restart)
+	}
+	if len(reloaded) == 0 {
+		logger.InfoContext(ctx, "Config reloaded, nothing to apply")
+		return applied
+	}
+
+	if err := logging.SetLevel(applied.Log.Level); err != nil {
+		logger.ErrorContext(ctx, "Failed to set log level", "error", err)
+	}
+	configureReadiness(checker, applied.Ready)
+	switch {
+	case limiter == nil && applied.RateLimit.Rules != "off":
+		logger.WarnContext(ctx, "Rate limiting was off at startup; restart to enable it")
+	case limiter != nil && applied.RateLimit.Rules == "off":
+		limiter.SetRules(nil)
+	case limiter != nil:
+		rules, _ := ratelimit.ParseRules(applied.RateLimit.Rules)
+		limiter.SetRules(rules)
+	}
+	logger.InfoContext(ctx, "Config reloaded", "keys", reloaded)
+	return applied
+}
+
+// fatal logs msg at error level and exits
+func fatal(msg string, args ...any) {
+	logger.Error(msg, args...)
+	os.Exit(1)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/config"
+	"github.com/AndrewCharlesHay/vest/internal/events"
+	"github.com/AndrewCharlesHay/vest/internal/ingest"
+	"github.com/AndrewCharlesHay/vest/internal/webhook"
+)
+
+// services are what ingestion and alarm evaluation notify, so a file
+// ingested or an alarm raised by a one-off task is audited and announced
+// just as the server would
+type services struct {
+	monitor  *compliance.Monitor
+	notifier *webhook.Notifier
+	broker   *events.Broker
+	audit    *audit.Logger
+
+	// responses is the server's response cache; nil in one-off tasks, whose
+	// data version bumps still expire every server's cached responses
+	responses *cache.Cache
+}
+
+func newServices(db *sql.DB, cfg *config.Config) *services {
+	s := &services{
+		monitor:  compliance.NewMonitor(db),
+		notifier: webhook.NewNotifier(db),
+		broker:   events.NewBroker(db),
+		audit:    audit.NewLogger(db),
+	}
+	s.monitor.Exposure, _ = compliance.ParseExposure(cfg.Alarms.Exposure) // Validated by config.Load
+	s.monitor.OnChange = s.alarmsChanged
+	return s
+}
+
+// alarmsChanged audits and announces the alarms an evaluation opened or cleared
+func (s *services) alarmsChanged(ctx context.Context, result *compliance.EvaluationResult) {
+	for _, a := range result.Opened {
+		if err := s.audit.Change(ctx, "monitor", events.AlarmOpened, []string{a.AccountID}, a); err != nil {
+			logger.ErrorContext(ctx, "Failed to audit alarm", "alarm_id", a.ID, "error", err)
+		}
+		if err := s.notifier.Publish(ctx, webhook.EventAlarmOpened, a); err != nil {
+			logger.ErrorContext(ctx, "Failed to queue webhook", "alarm_id", a.ID, "error", err)
+		}
+		if err := s.broker.Publish(ctx, events.AlarmOpened, a); err != nil {
+			logger.ErrorContext(ctx, "Failed to publish event", "alarm_id", a.ID, "error", err)
+		}
+	}
+	for _, a := range result.Cleared {
+		if err := s.audit.Change(ctx, "monitor", events.AlarmCleared, []string{a.AccountID}, a); err != nil {
+			logger.ErrorContext(ctx, "Failed to audit alarm", "alarm_id", a.ID, "error", err)
+		}
+		if err := s.notifier.Publish(ctx, webhook.EventAlarmCleared, a); err != nil {
+			logger.ErrorContext(ctx, "Failed to queue webhook", "alarm_id", a.ID, "error", err)
+		}
+		if err := s.broker.Publish(ctx, events.AlarmCleared, a); err != nil {
+			logger.ErrorContext(ctx, "Failed to publish event", "alarm_id", a.ID, "error", err)
+		}
+	}
+}
+
+// fileIngested audits and announces a file, then evaluates alarms for each
+// date it touched
+func (s *services) fileIngested(ctx context.Context, file ingest.IngestedFile) {
+	if err := s.audit.Change(ctx, "ingestor", events.FileIngested, file.Accounts, file); err != nil {
+		logger.ErrorContext(ctx, "Failed to audit ingestion", "file", file.Name, "error", err)
+	}
+	if err := s.broker.Publish(ctx, events.FileIngested, file); err != nil {
+		logger.ErrorContext(ctx, "Failed to publish event", "file", file.Name, "error", err)
+	}
+	for _, date := range file.Dates {
+		if s.responses != nil {
+			s.responses.InvalidateDate(date)
+		}
+		if err := s.broker.Publish(ctx, events.PositionsChanged, map[string]string{"date": date}); err != nil {
+			logger.ErrorContext(ctx, "Failed to publish event", "date", date, "error", err)
+		}
+		if _, err := s.monitor.Evaluate(ctx, date); err != nil {
+			logger.ErrorContext(ctx, "Alarm evaluation failed", "date", date, "error", err)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/AndrewCharlesHay/vest/internal/ingest"
+)
+
+// validation is what validate reports for one file
+type validation struct {
+	File     string   `json:"file"`
+	Valid    bool     `json:"valid"`
+	Kind     string   `json:"kind,omitempty"`
+	Rows     int      `json:"rows"`
+	Rejected int      `json:"rejected"`
+	Dates    []string `json:"dates,omitempty"`
+	Accounts int      `json:"accounts"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// validate parses files without a database, printing what each would load.
+// It fails if a file is not recognised or any of its rows would be skipped.
+func validate(args []string) int {
+	if len(args) == 0 {
+		return usageError("validate needs at least one file")
+	}
+	status := exitOK
+	for _, path := range args {
+		v := validateFile(path)
+		if !v.Valid {
+			status = exitFailed
+		}
+		printJSON(v)
+	}
+	return status
+}
+
+func validateFile(path string) validation {
+	v := validation{File: path}
+	f, err := os.Open(path)
+	if err != nil {
+		v.Error = err.Error()
+		return v
+	}
+	defer f.Close()
+	p, err := ingest.Parse(f)
+	if err != nil {
+		v.Error = err.Error()
+		return v
+	}
+	v.Kind, v.Rows, v.Rejected, v.Dates, v.Accounts = p.Kind, p.Rows(), len(p.Rejected), p.Dates(), p.Accounts()
+	v.Valid = len(p.Rejected) == 0
+	if !v.Valid {
+		v.Error = fmt.Sprintf("%d rows would be skipped", len(p.Rejected))
+	}
+	return v
+}
@@ -0,0 +1,18 @@
+// Package db embeds the schema and its migrations so every binary carries
+// them.
+package db
+
+import "embed"
+
+// Migrations holds migrations/NNNN_name.up.sql and NNNN_name.down.sql,
+// applied in version order by internal/migrate
+//
+//go:embed migrations/*.sql
+var Migrations embed.FS
+
+// Schema is the complete current schema and the source of truth: applying
+// every migration must produce exactly this, which the migrate tests check.
+// Add a migration alongside any change here.
+//
+//go:embed schema.sql
+var Schema string
@@ -0,0 +1,17 @@
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
+DROP TABLE IF EXISTS rate_limit_buckets;
+DROP TABLE IF EXISTS entitlements;
+DROP TABLE IF EXISTS events;
+DROP TABLE IF EXISTS api_keys;
+DROP TABLE IF EXISTS data_versions;
+DROP TABLE IF EXISTS account_group_members;
+DROP TABLE IF EXISTS account_groups;
+DROP TABLE IF EXISTS account_aliases;
+DROP TABLE IF EXISTS accounts;
+DROP TABLE IF EXISTS securities;
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhook_subscriptions;
+DROP TABLE IF EXISTS alarm_events;
+DROP TABLE IF EXISTS alarms;
+DROP TABLE IF EXISTS positions;
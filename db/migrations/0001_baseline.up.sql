@@ -0,0 +1,195 @@
+-- Baseline: the schema the server created inline before versioned
+-- migrations. Every statement is idempotent, so databases that already have
+-- it record this version without changes.
+
+CREATE TABLE IF NOT EXISTS positions (
+    date DATE NOT NULL,
+    account_id TEXT NOT NULL,
+    ticker TEXT NOT NULL,
+    quantity DECIMAL(15, 2),
+    market_value DECIMAL(15, 2),
+    shares DECIMAL(15, 2),
+    source_system TEXT,
+    ingested_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (date, account_id, ticker)
+);
+CREATE INDEX IF NOT EXISTS idx_positions_account_date ON positions (account_id, date);
+
+CREATE TABLE IF NOT EXISTS alarms (
+    id BIGSERIAL PRIMARY KEY,
+    account_id VARCHAR(50) NOT NULL,
+    rule_id VARCHAR(50) NOT NULL,
+    ticker VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('open', 'acknowledged', 'resolved', 'auto_cleared')),
+    severity VARCHAR(20) NOT NULL,
+    observed_value DOUBLE PRECISION NOT NULL,
+    threshold DOUBLE PRECISION NOT NULL,
+    first_detected_date DATE NOT NULL,
+    last_seen_date DATE NOT NULL,
+    assignee VARCHAR(100),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    closed_at TIMESTAMP
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_alarms_active ON alarms (account_id, rule_id, ticker)
+    WHERE status IN ('open', 'acknowledged');
+CREATE INDEX IF NOT EXISTS idx_alarms_account_date ON alarms (account_id, first_detected_date);
+
+CREATE TABLE IF NOT EXISTS alarm_events (
+    id BIGSERIAL PRIMARY KEY,
+    alarm_id BIGINT NOT NULL REFERENCES alarms (id),
+    action VARCHAR(20) NOT NULL,
+    from_status VARCHAR(20),
+    to_status VARCHAR(20),
+    actor VARCHAR(100) NOT NULL,
+    comment TEXT,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_alarm_events_alarm ON alarm_events (alarm_id);
+
+CREATE TABLE IF NOT EXISTS webhook_subscriptions (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}',
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id BIGSERIAL PRIMARY KEY,
+    subscription_id BIGINT NOT NULL REFERENCES webhook_subscriptions (id),
+    event VARCHAR(50) NOT NULL,
+    payload JSONB,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'delivered', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_code INTEGER,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_subscription ON webhook_deliveries (subscription_id, id);
+
+CREATE TABLE IF NOT EXISTS securities (
+    ticker VARCHAR(50) PRIMARY KEY,
+    cusip VARCHAR(9),
+    isin VARCHAR(12),
+    sedol VARCHAR(7),
+    issuer VARCHAR(200),
+    asset_class VARCHAR(50),
+    sector VARCHAR(100),
+    currency CHAR(3),
+    multiplier NUMERIC(18, 6) NOT NULL DEFAULT 1,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_securities_cusip ON securities (cusip) WHERE cusip IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_securities_isin ON securities (isin) WHERE isin IS NOT NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_securities_sedol ON securities (sedol) WHERE sedol IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS accounts (
+    account_id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    type VARCHAR(50),
+    base_currency CHAR(3),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'closed')),
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS account_aliases (
+    alias VARCHAR(50) PRIMARY KEY,
+    account_id VARCHAR(50) NOT NULL REFERENCES accounts (account_id) ON DELETE CASCADE,
+    custodian VARCHAR(50)
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_aliases_account ON account_aliases (account_id);
+
+CREATE TABLE IF NOT EXISTS account_groups (
+    group_id VARCHAR(50) PRIMARY KEY,
+    name VARCHAR(200) NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    parent_id VARCHAR(50) REFERENCES account_groups (group_id)
+);
+
+CREATE TABLE IF NOT EXISTS account_group_members (
+    group_id VARCHAR(50) NOT NULL REFERENCES account_groups (group_id) ON DELETE CASCADE,
+    account_id VARCHAR(50) NOT NULL REFERENCES accounts (account_id) ON DELETE CASCADE,
+    PRIMARY KEY (group_id, account_id)
+);
+
+CREATE TABLE IF NOT EXISTS data_versions (
+    scope VARCHAR(50) PRIMARY KEY,
+    version BIGINT NOT NULL,
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS api_keys (
+    id BIGSERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    prefix VARCHAR(20) NOT NULL UNIQUE,
+    key_hash CHAR(64) NOT NULL,
+    scopes TEXT[] NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    revoked_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS events (
+    id BIGSERIAL PRIMARY KEY,
+    type VARCHAR(50) NOT NULL,
+    data JSONB NOT NULL,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS entitlements (
+    id BIGSERIAL PRIMARY KEY,
+    principal VARCHAR(255) NOT NULL,
+    account_id VARCHAR(50),
+    group_id VARCHAR(50) REFERENCES account_groups (group_id) ON DELETE CASCADE,
+    all_accounts BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((account_id IS NOT NULL)::int + (group_id IS NOT NULL)::int + all_accounts::int = 1)
+);
+CREATE INDEX IF NOT EXISTS idx_entitlements_principal ON entitlements (principal);
+
+CREATE UNLOGGED TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL,
+    full_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS audit_log (
+    id BIGSERIAL PRIMARY KEY,
+    created_at TIMESTAMPTZ NOT NULL,
+    kind VARCHAR(10) NOT NULL CHECK (kind IN ('access', 'change')),
+    principal VARCHAR(255) NOT NULL,
+    method VARCHAR(10) NOT NULL DEFAULT '',
+    route TEXT NOT NULL DEFAULT '',
+    params JSON NOT NULL,
+    accounts TEXT[] NOT NULL DEFAULT '{}',
+    status INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(100) NOT NULL DEFAULT '',
+    detail JSON NOT NULL,
+    prev_hash CHAR(64) NOT NULL,
+    hash CHAR(64) NOT NULL UNIQUE
+);
+CREATE INDEX IF NOT EXISTS idx_audit_log_principal ON audit_log (principal, id);
+CREATE INDEX IF NOT EXISTS idx_audit_log_accounts ON audit_log USING GIN (accounts);
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$ LANGUAGE plpgsql;
+CREATE OR REPLACE TRIGGER audit_log_no_update
+    BEFORE UPDATE OR DELETE ON audit_log
+    FOR EACH ROW EXECUTE FUNCTION audit_log_append_only();
+CREATE OR REPLACE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE ON audit_log
+    FOR EACH STATEMENT EXECUTE FUNCTION audit_log_append_only();
@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_positions_date_account;
+
+ALTER TABLE positions
+    ALTER COLUMN account_id TYPE TEXT,
+    ALTER COLUMN ticker TYPE TEXT,
+    ALTER COLUMN quantity TYPE DECIMAL(15, 2),
+    ALTER COLUMN market_value TYPE DECIMAL(15, 2),
+    ALTER COLUMN shares TYPE DECIMAL(15, 2),
+    ALTER COLUMN source_system TYPE TEXT;
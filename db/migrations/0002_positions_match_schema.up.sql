@@ -0,0 +1,11 @@
+-- The inline schema created positions with TEXT and DECIMAL(15, 2) columns,
+-- truncating fractional share quantities. Widen them to match db/schema.sql.
+ALTER TABLE positions
+    ALTER COLUMN account_id TYPE VARCHAR(50),
+    ALTER COLUMN ticker TYPE VARCHAR(50),
+    ALTER COLUMN quantity TYPE NUMERIC(18, 4),
+    ALTER COLUMN market_value TYPE NUMERIC(18, 2),
+    ALTER COLUMN shares TYPE NUMERIC(18, 4),
+    ALTER COLUMN source_system TYPE VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_positions_date_account ON positions (date, account_id);
@@ -52,6 +52,16 @@ resource "aws_ecs_task_definition" "app" {
     {
       name  = "vest-app"
       image = "${aws_ecr_repository.app.repository_url}:latest"
+      # Seconds between SIGTERM and SIGKILL; the app drains within SHUTDOWN_TIMEOUT (25s)
+      stopTimeout = 30
+      # Restart the task if it stops serving; /readyz is for load balancers
+      healthCheck = {
+        command     = ["CMD-SHELL", "wget -q -O /dev/null http://localhost:8080/livez || exit 1"]
+        interval    = 30
+        timeout     = 5
+        retries     = 3
+        startPeriod = 60
+      }
       portMappings = [
         {
           containerPort = 8080
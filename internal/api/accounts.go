@@ -0,0 +1,429 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+var errGroupNotFound = fmt.Errorf("account group %w", ErrNotFound)
+
+func (h *Handler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	accounts, err := h.QueryAccounts(r.Context(), "")
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, accounts)
+}
+
+// GetAccount looks an account up by internal ID or custodian alias
+func (h *Handler) GetAccount(w http.ResponseWriter, r *http.Request) {
+	var id string
+	err := h.DB.QueryRowContext(r.Context(), `
+		SELECT account_id FROM accounts WHERE account_id = $1
+		UNION ALL
+		SELECT account_id FROM account_aliases WHERE alias = $1
+		LIMIT 1
+	`, r.PathValue("id")).Scan(&id)
+	if err == sql.ErrNoRows {
+		http.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Not entitled reads as not found, without naming the account an alias resolved to
+	accounts, err := h.QueryAccounts(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		http.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, accounts[0])
+}
+
+// PutAccount creates or replaces an account and its aliases
+func (h *Handler) PutAccount(w http.ResponseWriter, r *http.Request) {
+	var a models.Account
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	a.AccountID = r.PathValue("id")
+	if a.Name == "" {
+		a.Name = a.AccountID
+	}
+	if a.Status == "" {
+		a.Status = "active"
+	}
+	if a.Status != "active" && a.Status != "closed" {
+		http.Error(w, "status must be active or closed", http.StatusBadRequest)
+		return
+	}
+	if !h.requireFullAccess(w, r) {
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(r.Context(), "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	_, err = tx.ExecContext(r.Context(), `
+		INSERT INTO accounts (account_id, name, type, base_currency, status)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5)
+		ON CONFLICT (account_id)
+		DO UPDATE SET
+			name = EXCLUDED.name,
+			type = EXCLUDED.type,
+			base_currency = EXCLUDED.base_currency,
+			status = EXCLUDED.status,
+			updated_at = CURRENT_TIMESTAMP
+	`, a.AccountID, a.Name, a.Type, strings.ToUpper(a.BaseCurrency), a.Status)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if _, err := tx.ExecContext(r.Context(), `DELETE FROM account_aliases WHERE account_id = $1`, a.AccountID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, alias := range a.Aliases {
+		if alias.Alias == "" || alias.Alias == a.AccountID {
+			continue
+		}
+		var owner string
+		err := tx.QueryRowContext(r.Context(), `
+			INSERT INTO account_aliases (alias, account_id, custodian)
+			VALUES ($1, $2, NULLIF($3, ''))
+			ON CONFLICT (alias) DO UPDATE SET alias = EXCLUDED.alias
+			RETURNING account_id
+		`, alias.Alias, a.AccountID, alias.Custodian).Scan(&owner)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if owner != a.AccountID {
+			http.Error(w, "alias "+alias.Alias+" already belongs to "+owner, http.StatusConflict)
+			return
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	accounts, err := h.QueryAccounts(r.Context(), a.AccountID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, accounts[0])
+}
+
+// QueryAccounts returns one account (or all when id is empty) with aliases
+// and groups. Accounts the caller is not entitled to are not found.
+func (h *Handler) QueryAccounts(ctx context.Context, id string) ([]models.Account, error) {
+	access, err := h.Access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT a.account_id, a.name, COALESCE(a.type, ''), COALESCE(a.base_currency, ''), a.status, a.updated_at,
+			COALESCE((SELECT string_agg(al.alias || E'\t' || COALESCE(al.custodian, ''), E'\n' ORDER BY al.alias)
+				FROM account_aliases al WHERE al.account_id = a.account_id), ''),
+			COALESCE((SELECT string_agg(m.group_id, ',' ORDER BY m.group_id)
+				FROM account_group_members m WHERE m.account_id = a.account_id), '')
+		FROM accounts a
+		WHERE ($1 = '' OR a.account_id = $1)
+		  AND ($2 OR a.account_id = ANY($3))
+		ORDER BY a.account_id
+	`, id, access.All(), access.Accounts())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []models.Account{}
+	for rows.Next() {
+		var a models.Account
+		var aliases, groups string
+		if err := rows.Scan(&a.AccountID, &a.Name, &a.Type, &a.BaseCurrency, &a.Status, &a.UpdatedAt, &aliases, &groups); err != nil {
+			return nil, err
+		}
+		a.Aliases = []models.AccountAlias{}
+		if aliases != "" {
+			for _, pair := range strings.Split(aliases, "\n") {
+				alias, custodian, _ := strings.Cut(pair, "\t")
+				a.Aliases = append(a.Aliases, models.AccountAlias{Alias: alias, Custodian: custodian})
+			}
+		}
+		if groups != "" {
+			a.Groups = strings.Split(groups, ",")
+		}
+		audit.Note(ctx, a.AccountID)
+		accounts = append(accounts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if id != "" && len(accounts) == 0 {
+		return nil, fmt.Errorf("account %s %w", id, ErrNotFound)
+	}
+	return accounts, nil
+}
+
+// ListGroups returns the group hierarchy. Member lists only name accounts
+// the caller is entitled to.
+func (h *Handler) ListGroups(w http.ResponseWriter, r *http.Request) {
+	access, err := h.Access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT g.group_id, g.name, g.type, COALESCE(g.parent_id, ''),
+			COALESCE((SELECT string_agg(m.account_id, ',' ORDER BY m.account_id)
+				FROM account_group_members m WHERE m.group_id = g.group_id), ''),
+			COALESCE((SELECT string_agg(c.group_id, ',' ORDER BY c.group_id)
+				FROM account_groups c WHERE c.parent_id = g.group_id), '')
+		FROM account_groups g
+		ORDER BY g.group_id
+	`)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	groups := []models.AccountGroup{}
+	for rows.Next() {
+		var g models.AccountGroup
+		var accounts, children string
+		if err := rows.Scan(&g.GroupID, &g.Name, &g.Type, &g.ParentID, &accounts, &children); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		g.Accounts = access.Restrict(splitList(accounts))
+		audit.Note(r.Context(), g.Accounts...)
+		if children != "" {
+			g.Children = strings.Split(children, ",")
+		}
+		groups = append(groups, g)
+	}
+	writeJSON(w, http.StatusOK, groups)
+}
+
+// GetGroup returns a group with every account beneath it resolved
+func (h *Handler) GetGroup(w http.ResponseWriter, r *http.Request) {
+	access, err := h.Access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	id := r.PathValue("id")
+	var g models.AccountGroup
+	var accounts, children string
+	err = h.DB.QueryRowContext(r.Context(), `
+		SELECT g.group_id, g.name, g.type, COALESCE(g.parent_id, ''),
+			COALESCE((SELECT string_agg(m.account_id, ',' ORDER BY m.account_id)
+				FROM account_group_members m WHERE m.group_id = g.group_id), ''),
+			COALESCE((SELECT string_agg(c.group_id, ',' ORDER BY c.group_id)
+				FROM account_groups c WHERE c.parent_id = g.group_id), '')
+		FROM account_groups g
+		WHERE g.group_id = $1
+	`, id).Scan(&g.GroupID, &g.Name, &g.Type, &g.ParentID, &accounts, &children)
+	if err == sql.ErrNoRows {
+		http.Error(w, errGroupNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.Accounts = access.Restrict(splitList(accounts))
+	if children != "" {
+		g.Children = strings.Split(children, ",")
+	}
+	if g.Resolved, err = h.groupAccounts(r.Context(), id); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	g.Resolved = access.Restrict(g.Resolved)
+	audit.Note(r.Context(), g.Accounts...)
+	audit.Note(r.Context(), g.Resolved...)
+	writeJSON(w, http.StatusOK, g)
+}
+
+// PutGroup creates or replaces a group and its direct account members
+func (h *Handler) PutGroup(w http.ResponseWriter, r *http.Request) {
+	var g models.AccountGroup
+	if err := json.NewDecoder(r.Body).Decode(&g); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	g.GroupID = r.PathValue("id")
+	if g.Name == "" {
+		g.Name = g.GroupID
+	}
+	if g.Type == "" {
+		http.Error(w, "type is required (e.g. household, strategy, firm)", http.StatusBadRequest)
+		return
+	}
+	if g.ParentID == g.GroupID {
+		http.Error(w, "a group cannot be its own parent", http.StatusBadRequest)
+		return
+	}
+	if !h.requireFullAccess(w, r) {
+		return
+	}
+
+	tx, err := h.DB.BeginTx(r.Context(), nil)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(r.Context(), "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	if g.ParentID != "" {
+		// Reject a parent that sits beneath this group, which would form a cycle
+		var cycle bool
+		err := tx.QueryRowContext(r.Context(), `
+			WITH RECURSIVE ancestors AS (
+				SELECT group_id, parent_id FROM account_groups WHERE group_id = $1
+				UNION
+				SELECT g.group_id, g.parent_id FROM account_groups g JOIN ancestors a ON g.group_id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE group_id = $2)
+		`, g.ParentID, g.GroupID).Scan(&cycle)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if cycle {
+			http.Error(w, "parent_id would create a cycle", http.StatusBadRequest)
+			return
+		}
+	}
+
+	_, err = tx.ExecContext(r.Context(), `
+		INSERT INTO account_groups (group_id, name, type, parent_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (group_id)
+		DO UPDATE SET name = EXCLUDED.name, type = EXCLUDED.type, parent_id = EXCLUDED.parent_id
+	`, g.GroupID, g.Name, g.Type, g.ParentID)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, err := tx.ExecContext(r.Context(), `DELETE FROM account_group_members WHERE group_id = $1`, g.GroupID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, acc := range g.Accounts {
+		if _, err := tx.ExecContext(r.Context(), `INSERT INTO account_group_members (group_id, account_id) VALUES ($1, $2)`, g.GroupID, acc); err != nil {
+			http.Error(w, "unknown account "+acc, http.StatusBadRequest)
+			return
+		}
+	}
+	if err := cache.Bump(r.Context(), tx, cache.ReferenceScope); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if g.Resolved, err = h.groupAccounts(r.Context(), g.GroupID); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, g)
+}
+
+func (h *Handler) DeleteGroup(w http.ResponseWriter, r *http.Request) {
+	if !h.requireFullAccess(w, r) {
+		return
+	}
+	res, err := h.DB.ExecContext(r.Context(), `DELETE FROM account_groups WHERE group_id = $1`, r.PathValue("id"))
+	if err != nil {
+		// Child groups still reference it
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, errGroupNotFound.Error(), http.StatusNotFound)
+		return
+	}
+	h.bumpReference(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// groupAccounts resolves every account in a group and its descendant groups
+func (h *Handler) groupAccounts(ctx context.Context, groupID string) ([]string, error) {
+	var exists bool
+	if err := h.DB.QueryRowContext(ctx, `SELECT EXISTS (SELECT 1 FROM account_groups WHERE group_id = $1)`, groupID).Scan(&exists); err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errGroupNotFound
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		WITH RECURSIVE tree AS (
+			SELECT group_id FROM account_groups WHERE group_id = $1
+			UNION
+			SELECT g.group_id FROM account_groups g JOIN tree t ON g.parent_id = t.group_id
+		)
+		SELECT DISTINCT m.account_id
+		FROM account_group_members m
+		JOIN tree t ON t.group_id = m.group_id
+		ORDER BY m.account_id
+	`, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	accounts := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, id)
+	}
+	return accounts, rows.Err()
+}
+
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
+}
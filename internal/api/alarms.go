@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+var (
+	errAssigneeRequired = errors.New("assignee is required")
+	errCommentRequired  = errors.New("comment is required")
+)
+
+// alarmAction is the request body for alarm lifecycle endpoints. Actor is
+// the authenticated caller; a body naming one is rejected, so nobody can
+// act under another's name.
+type alarmAction struct {
+	Actor    string `json:"actor"`
+	Comment  string `json:"comment"`
+	Assignee string `json:"assignee"`
+}
+
+// AlarmHistory lists persisted alarms and their events for auditors
+func (h *Handler) AlarmHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := compliance.HistoryFilter{
+		AccountID: q.Get("account_id"),
+		Status:    q.Get("status"),
+		From:      q.Get("from"),
+		To:        q.Get("to"),
+	}
+	for _, d := range []string{f.From, f.To} {
+		if d == "" {
+			continue
+		}
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			http.Error(w, "from/to must be YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+	}
+
+	access, err := h.Access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Accounts = access.Accounts()
+
+	alarms, err := h.Monitor.History(r.Context(), f)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	for _, a := range alarms {
+		audit.Note(r.Context(), a.AccountID)
+	}
+	writeJSON(w, http.StatusOK, alarms)
+}
+
+// GetAlarm returns one persisted alarm with its event history
+func (h *Handler) GetAlarm(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	alarm, err := h.entitledAlarm(r.Context(), id)
+	if err != nil {
+		writeAlarmError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, alarm)
+}
+
+// entitledAlarm loads an alarm, treating one on an account the caller cannot
+// see as missing
+func (h *Handler) entitledAlarm(ctx context.Context, id int64) (*models.Alarm, error) {
+	access, err := h.Access(ctx)
+	if err != nil {
+		return nil, err
+	}
+	alarm, err := h.Monitor.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !access.Allows(alarm.AccountID) {
+		return nil, compliance.ErrAlarmNotFound
+	}
+	audit.Note(ctx, alarm.AccountID)
+	return alarm, nil
+}
+
+// EvaluateAlarms runs the rules for a date and persists the outcome
+func (h *Handler) EvaluateAlarms(w http.ResponseWriter, r *http.Request) {
+	date := r.URL.Query().Get("date")
+	if _, err := time.Parse("2006-01-02", date); err != nil {
+		http.Error(w, "date parameter required as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	// The result lists alarms on every account
+	if !h.requireFullAccess(w, r) {
+		return
+	}
+	result, err := h.Monitor.Evaluate(r.Context(), date)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (h *Handler) AcknowledgeAlarm(w http.ResponseWriter, r *http.Request) {
+	h.alarmTransition(w, r, func(ctx context.Context, id int64, a alarmAction) (*models.Alarm, error) {
+		return h.Monitor.Acknowledge(ctx, id, a.Actor, a.Comment, a.Assignee)
+	})
+}
+
+func (h *Handler) ResolveAlarm(w http.ResponseWriter, r *http.Request) {
+	h.alarmTransition(w, r, func(ctx context.Context, id int64, a alarmAction) (*models.Alarm, error) {
+		return h.Monitor.Resolve(ctx, id, a.Actor, a.Comment)
+	})
+}
+
+func (h *Handler) AssignAlarm(w http.ResponseWriter, r *http.Request) {
+	h.alarmTransition(w, r, func(ctx context.Context, id int64, a alarmAction) (*models.Alarm, error) {
+		if a.Assignee == "" {
+			return nil, errAssigneeRequired
+		}
+		return h.Monitor.Assign(ctx, id, a.Actor, a.Assignee, a.Comment)
+	})
+}
+
+func (h *Handler) CommentAlarm(w http.ResponseWriter, r *http.Request) {
+	h.alarmTransition(w, r, func(ctx context.Context, id int64, a alarmAction) (*models.Alarm, error) {
+		if a.Comment == "" {
+			return nil, errCommentRequired
+		}
+		return h.Monitor.Comment(ctx, id, a.Actor, a.Comment)
+	})
+}
+
+func (h *Handler) alarmTransition(w http.ResponseWriter, r *http.Request, fn func(context.Context, int64, alarmAction) (*models.Alarm, error)) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+
+	var body alarmAction
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if body.Actor != "" {
+		http.Error(w, "actor is taken from the caller's credentials and cannot be set", http.StatusBadRequest)
+		return
+	}
+	p := auth.PrincipalFrom(r.Context())
+	if p == nil {
+		http.Error(w, "unauthenticated", http.StatusUnauthorized)
+		return
+	}
+	body.Actor = p.Label()
+	if _, err := h.entitledAlarm(r.Context(), id); err != nil {
+		writeAlarmError(w, err)
+		return
+	}
+
+	alarm, err := fn(r.Context(), id, body)
+	if err != nil {
+		writeAlarmError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, alarm)
+}
+
+func pathID(w http.ResponseWriter, r *http.Request) (int64, bool) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid id", http.StatusBadRequest)
+		return 0, false
+	}
+	return id, true
+}
+
+func writeAlarmError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, compliance.ErrAlarmNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, compliance.ErrInvalidTransition):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, errAssigneeRequired), errors.Is(err, errCommentRequired):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return
+	}
+}
@@ -0,0 +1,30 @@
+package api_test
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+func TestAlarmActorIsTheCaller(t *testing.T) {
+	s := newLeakSuite(t, testdb.Open(t))
+	path := fmt.Sprintf("/alarms/%d/comments", s.ownAlarm)
+
+	if status, body := s.do(t, s.adviser, "POST", path, `{"actor":"mallory","comment":"Not me"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected 400 for a body naming the actor, got %d: %s", status, body)
+	}
+	if status, body := s.do(t, s.adviser, "POST", path, `{"comment":"Calling the client"}`); status != http.StatusOK {
+		t.Fatalf("Expected the comment recorded, got %d: %s", status, body)
+	}
+
+	_, body := s.do(t, s.adviser, "GET", fmt.Sprintf("/alarms/%d", s.ownAlarm), "")
+	if !strings.Contains(body, `"action":"comment"`) || !strings.Contains(body, `"actor":"key:`) {
+		t.Errorf("Expected the comment recorded under the caller's key, got %s", body)
+	}
+	if strings.Contains(body, "mallory") {
+		t.Errorf("Recorded the rejected actor: %s", body)
+	}
+}
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+)
+
+// ListAudit pages through the audit log, newest first. Filters:
+// ?principal=, ?account_id=, ?kind=access|change, ?route= (path prefix),
+// ?from= and ?to= (RFC 3339 or YYYY-MM-DD), ?before=<id> and ?limit=.
+func (h *Handler) ListAudit(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := audit.Filter{
+		Principal: q.Get("principal"),
+		AccountID: q.Get("account_id"),
+		Kind:      q.Get("kind"),
+		Route:     q.Get("route"),
+	}
+	var err error
+	if f.From, err = parseAuditTime(q.Get("from")); err != nil {
+		http.Error(w, "from must be RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if f.To, err = parseAuditTime(q.Get("to")); err != nil {
+		http.Error(w, "to must be RFC 3339 or YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if f.Before, err = positiveInt(q.Get("before")); err != nil {
+		http.Error(w, "before must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	limit, err := positiveInt(q.Get("limit"))
+	if err != nil {
+		http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+		return
+	}
+	f.Limit = int(min(limit, audit.MaxLimit+1))
+
+	entries, err := h.Audit.Query(r.Context(), f)
+	if errors.Is(err, audit.ErrInvalidFilter) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, entries)
+}
+
+func parseAuditTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", v)
+}
+
+// positiveInt parses an optional query parameter, returning 0 when absent
+func positiveInt(v string) (int64, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseInt(v, 10, 64)
+	if err == nil && n <= 0 {
+		err = errors.New("not positive")
+	}
+	return n, err
+}
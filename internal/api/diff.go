@@ -0,0 +1,110 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// Position change classifications
+const (
+	ChangeOpened    = "opened"
+	ChangeClosed    = "closed"
+	ChangeIncreased = "increased"
+	ChangeDecreased = "decreased"
+	ChangeUnchanged = "unchanged"
+	ChangeReversed  = "reversed"
+)
+
+// ClassifyChange compares position size at two dates. A missing or zero
+// quantity counts as flat; size is measured by absolute quantity so a
+// growing short is "increased". A long that became a short, or the
+// reverse, is "reversed" whatever the sizes.
+func ClassifyChange(fromQty, toQty float64) string {
+	switch {
+	case fromQty == 0 && toQty == 0:
+		return ChangeUnchanged
+	case fromQty == 0:
+		return ChangeOpened
+	case toQty == 0:
+		return ChangeClosed
+	case (fromQty < 0) != (toQty < 0):
+		return ChangeReversed
+	case abs(toQty) > abs(fromQty):
+		return ChangeIncreased
+	case abs(toQty) < abs(fromQty):
+		return ChangeDecreased
+	default:
+		return ChangeUnchanged
+	}
+}
+
+// PositionsDiff compares holdings between two dates per account and ticker
+func (h *Handler) PositionsDiff(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	from, to := q.Get("from"), q.Get("to")
+	if _, err := time.Parse("2006-01-02", from); err != nil {
+		http.Error(w, "from parameter required as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+	if _, err := time.Parse("2006-01-02", to); err != nil {
+		http.Error(w, "to parameter required as YYYY-MM-DD", http.StatusBadRequest)
+		return
+	}
+
+	accountID := q.Get("account_id")
+	accounts, restricted, err := h.accountScope(r.Context(), q.Get("group"))
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+
+	// Both sides are point lookups on the (date, account_id) index
+	rows, err := h.DB.QueryContext(r.Context(), `
+		WITH f AS (
+			SELECT account_id, ticker, quantity, market_value FROM positions
+			WHERE date = $1 AND ($3 = '' OR account_id = $3) AND ($4 = FALSE OR account_id = ANY($5))
+		), t AS (
+			SELECT account_id, ticker, quantity, market_value FROM positions
+			WHERE date = $2 AND ($3 = '' OR account_id = $3) AND ($4 = FALSE OR account_id = ANY($5))
+		)
+		SELECT COALESCE(f.account_id, t.account_id), COALESCE(f.ticker, t.ticker),
+			COALESCE(f.quantity, 0), COALESCE(t.quantity, 0),
+			COALESCE(f.market_value, 0), COALESCE(t.market_value, 0)
+		FROM f
+		FULL OUTER JOIN t ON f.account_id = t.account_id AND f.ticker = t.ticker
+		ORDER BY 1, 2
+	`, from, to, accountID, restricted, accounts)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	resp := models.PositionDiffResponse{
+		From:    from,
+		To:      to,
+		Summary: map[string]int{},
+		Changes: []models.PositionDiff{},
+	}
+	for rows.Next() {
+		var d models.PositionDiff
+		if err := rows.Scan(&d.AccountID, &d.Ticker, &d.FromQuantity, &d.ToQuantity, &d.FromMarketValue, &d.ToMarketValue); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		d.QuantityDelta = d.ToQuantity - d.FromQuantity
+		d.MarketValueDelta = d.ToMarketValue - d.FromMarketValue
+		d.Change = ClassifyChange(d.FromQuantity, d.ToQuantity)
+		resp.Summary[d.Change]++
+		resp.Changes = append(resp.Changes, d)
+		audit.Note(r.Context(), d.AccountID)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
@@ -0,0 +1,28 @@
+package api
+
+import "testing"
+
+func TestClassifyChange(t *testing.T) {
+	cases := []struct {
+		from, to float64
+		want     string
+	}{
+		{0, 100, ChangeOpened},
+		{100, 0, ChangeClosed},
+		{100, 150, ChangeIncreased},
+		{100, 50, ChangeDecreased},
+		{100, 100, ChangeUnchanged},
+		{-150, -200, ChangeIncreased}, // growing short
+		{-150, -100, ChangeDecreased},
+		{0, -150, ChangeOpened},
+		{0, 0, ChangeUnchanged},
+		{100, -100, ChangeReversed}, // long to short of the same size
+		{100, -150, ChangeReversed},
+		{-100, 50, ChangeReversed},
+	}
+	for _, c := range cases {
+		if got := ClassifyChange(c.from, c.to); got != c.want {
+			t.Errorf("ClassifyChange(%v, %v) = %s, want %s", c.from, c.to, got, c.want)
+		}
+	}
+}
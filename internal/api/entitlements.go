@@ -0,0 +1,271 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+var (
+	errEntitlementNotFound = errors.New("entitlement not found")
+	errInvalidEntitlement  = errors.New("invalid entitlement")
+	errNotEntitled         = errors.New("requires access to every account")
+)
+
+// Access is the set of accounts a caller may read. The zero value allows none,
+// so a query run without an authenticated principal returns nothing.
+type Access struct {
+	all      bool
+	accounts map[string]bool
+}
+
+// FullAccess allows every account, as admins have
+func FullAccess() Access {
+	return Access{all: true}
+}
+
+// AccountAccess allows the listed accounts only
+func AccountAccess(ids ...string) Access {
+	a := Access{accounts: make(map[string]bool, len(ids))}
+	for _, id := range ids {
+		a.accounts[id] = true
+	}
+	return a
+}
+
+// All reports whether every account is allowed
+func (a Access) All() bool {
+	return a.all
+}
+
+// Allows reports whether one account is allowed
+func (a Access) Allows(accountID string) bool {
+	return a.all || a.accounts[accountID]
+}
+
+// Accounts lists the allowed accounts in order; nil when all are allowed
+func (a Access) Accounts() []string {
+	if a.all {
+		return nil
+	}
+	ids := make([]string, 0, len(a.accounts))
+	for id := range a.accounts {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// Restrict keeps the allowed accounts of ids
+func (a Access) Restrict(ids []string) []string {
+	if a.all {
+		return ids
+	}
+	out := []string{}
+	for _, id := range ids {
+		if a.accounts[id] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Access resolves the accounts the caller in ctx is entitled to. Admins see
+// every account; anyone else sees what their entitlements grant, with groups
+// expanded to every account beneath them.
+func (h *Handler) Access(ctx context.Context) (Access, error) {
+	p := auth.PrincipalFrom(ctx)
+	if p == nil {
+		return Access{}, nil
+	}
+	if p.Can(auth.ScopeAdmin) {
+		return FullAccess(), nil
+	}
+	if p.ID() == "" {
+		return Access{}, nil
+	}
+
+	// Scanned as an array so account IDs are never split on a separator
+	var all bool
+	var accounts []string
+	err := h.DB.QueryRowContext(ctx, `
+		WITH RECURSIVE granted AS (
+			SELECT account_id, group_id, all_accounts FROM entitlements WHERE principal = $1
+		), tree AS (
+			SELECT group_id FROM granted WHERE group_id IS NOT NULL
+			UNION
+			SELECT g.group_id FROM account_groups g JOIN tree t ON g.parent_id = t.group_id
+		)
+		SELECT EXISTS (SELECT 1 FROM granted WHERE all_accounts),
+			COALESCE((SELECT array_agg(DISTINCT account_id) FROM (
+				SELECT account_id FROM granted WHERE account_id IS NOT NULL
+				UNION
+				SELECT m.account_id FROM account_group_members m JOIN tree t ON t.group_id = m.group_id
+			) ids), '{}')::text[]
+	`, p.ID()).Scan(&all, pgtype.NewMap().SQLScanner(&accounts))
+	if err != nil {
+		return Access{}, err
+	}
+	if all {
+		return FullAccess(), nil
+	}
+	return AccountAccess(accounts...), nil
+}
+
+// accountScope combines a group filter with the caller's entitlements into
+// the account list a query must stay within. restricted is false only when
+// neither narrows the query.
+func (h *Handler) accountScope(ctx context.Context, group string) (accounts []string, restricted bool, err error) {
+	access, err := h.Access(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+	if group != "" {
+		if accounts, err = h.groupAccounts(ctx, group); err != nil {
+			return nil, false, err
+		}
+		return access.Restrict(accounts), true, nil
+	}
+	if access.All() {
+		return nil, false, nil
+	}
+	return access.Accounts(), true, nil
+}
+
+// requireFullAccess guards writes that affect accounts beyond the caller's own
+func (h *Handler) requireFullAccess(w http.ResponseWriter, r *http.Request) bool {
+	access, err := h.Access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	if !access.All() {
+		http.Error(w, "Forbidden: "+errNotEntitled.Error(), http.StatusForbidden)
+		return false
+	}
+	return true
+}
+
+// ListEntitlements returns every grant, or one principal's with ?principal=
+func (h *Handler) ListEntitlements(w http.ResponseWriter, r *http.Request) {
+	rows, err := h.DB.QueryContext(r.Context(), `
+		SELECT id, principal, COALESCE(account_id, ''), COALESCE(group_id, ''), all_accounts, created_at
+		FROM entitlements
+		WHERE ($1 = '' OR principal = $1)
+		ORDER BY principal, id
+	`, r.URL.Query().Get("principal"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer rows.Close()
+
+	grants := []models.Entitlement{}
+	for rows.Next() {
+		var e models.Entitlement
+		if err := rows.Scan(&e.ID, &e.Principal, &e.AccountID, &e.GroupID, &e.AllAccounts, &e.CreatedAt); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		grants = append(grants, e)
+	}
+	if err := rows.Err(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, grants)
+}
+
+// CreateEntitlement grants a principal an account, a group or every account
+func (h *Handler) CreateEntitlement(w http.ResponseWriter, r *http.Request) {
+	var e models.Entitlement
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if err := validateEntitlement(e); err != nil {
+		writeEntitlementError(w, err)
+		return
+	}
+
+	if e.GroupID != "" {
+		if _, err := h.groupAccounts(r.Context(), e.GroupID); err != nil {
+			writeEntitlementError(w, err)
+			return
+		}
+	}
+	err := h.DB.QueryRowContext(r.Context(), `
+		INSERT INTO entitlements (principal, account_id, group_id, all_accounts)
+		VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), $4)
+		RETURNING id, created_at
+	`, e.Principal, e.AccountID, e.GroupID, e.AllAccounts).Scan(&e.ID, &e.CreatedAt)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.bumpReference(r.Context())
+	writeJSON(w, http.StatusCreated, e)
+}
+
+func (h *Handler) DeleteEntitlement(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	res, err := h.DB.ExecContext(r.Context(), `DELETE FROM entitlements WHERE id = $1`, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		writeEntitlementError(w, errEntitlementNotFound)
+		return
+	}
+	h.bumpReference(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// validateEntitlement checks the principal names a key or user and that
+// exactly one of account, group or every account is granted
+func validateEntitlement(e models.Entitlement) error {
+	kind, id, _ := strings.Cut(e.Principal, ":")
+	valid := kind == "user" && id != ""
+	if kind == "key" {
+		n, err := strconv.ParseInt(id, 10, 64)
+		valid = err == nil && n > 0
+	}
+	if !valid {
+		return fmt.Errorf("%w: principal must be key:<id> or user:<subject>", errInvalidEntitlement)
+	}
+
+	granted := 0
+	for _, set := range []bool{e.AccountID != "", e.GroupID != "", e.AllAccounts} {
+		if set {
+			granted++
+		}
+	}
+	if granted != 1 {
+		return fmt.Errorf("%w: set exactly one of account_id, group_id or all_accounts", errInvalidEntitlement)
+	}
+	return nil
+}
+
+func writeEntitlementError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, errInvalidEntitlement), errors.Is(err, errGroupNotFound):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, errEntitlementNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
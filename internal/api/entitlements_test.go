@@ -0,0 +1,120 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+func TestAccess(t *testing.T) {
+	var none Access
+	if none.All() || none.Allows("ACC001") || len(none.Accounts()) != 0 || none.Accounts() == nil {
+		t.Errorf("zero value must allow nothing and restrict queries to an empty list")
+	}
+
+	some := AccountAccess("ACC002", "ACC001")
+	if !some.Allows("ACC001") || some.Allows("ACC003") {
+		t.Errorf("unexpected membership")
+	}
+	if got := some.Accounts(); len(got) != 2 || got[0] != "ACC001" {
+		t.Errorf("expected sorted accounts, got %v", got)
+	}
+	if got := some.Restrict([]string{"ACC001", "ACC003"}); len(got) != 1 || got[0] != "ACC001" {
+		t.Errorf("unexpected restriction %v", got)
+	}
+
+	full := FullAccess()
+	if !full.Allows("anything") || full.Accounts() != nil || len(full.Restrict([]string{"a", "b"})) != 2 {
+		t.Errorf("full access must not narrow anything")
+	}
+}
+
+func TestAccessKeepsAccountIDsWhole(t *testing.T) {
+	db := testdb.Open(t)
+	if _, err := db.Exec(`INSERT INTO entitlements (principal, account_id) VALUES ('key:7', 'ACC,001'), ('key:7', 'ACC002')`); err != nil {
+		t.Fatal(err)
+	}
+	h := &Handler{DB: db}
+	ctx := auth.WithPrincipal(context.Background(), &auth.Principal{KeyID: 7, Scopes: []string{auth.ScopeRead}})
+	a, err := h.Access(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !a.Allows("ACC,001") || !a.Allows("ACC002") || a.Allows("ACC") || a.Allows("001") || len(a.Accounts()) != 2 {
+		t.Errorf("expected exactly ACC,001 and ACC002, got %v", a.Accounts())
+	}
+
+	none := auth.WithPrincipal(context.Background(), &auth.Principal{KeyID: 8, Scopes: []string{auth.ScopeRead}})
+	if a, err := h.Access(none); err != nil || a.All() || len(a.Accounts()) != 0 {
+		t.Errorf("a principal without entitlements must see nothing, got %v, %v", a.Accounts(), err)
+	}
+}
+
+func TestAccessWithoutDatabase(t *testing.T) {
+	h := &Handler{}
+	if a, err := h.Access(context.Background()); err != nil || a.All() || len(a.Accounts()) != 0 {
+		t.Errorf("no principal must see nothing, got %+v, %v", a, err)
+	}
+	admin := auth.WithPrincipal(context.Background(), &auth.Principal{Name: "bootstrap", Scopes: []string{auth.ScopeAdmin}})
+	if a, err := h.Access(admin); err != nil || !a.All() {
+		t.Errorf("admins must see everything, got %+v, %v", a, err)
+	}
+}
+
+func TestValidateEntitlement(t *testing.T) {
+	valid := []models.Entitlement{
+		{Principal: "key:12", AccountID: "ACC001"},
+		{Principal: "user:3f9c-subject", GroupID: "smith-household"},
+		{Principal: "user:ops", AllAccounts: true},
+	}
+	for _, e := range valid {
+		if err := validateEntitlement(e); err != nil {
+			t.Errorf("%+v: %v", e, err)
+		}
+	}
+	invalid := []models.Entitlement{
+		{Principal: "ACC001", AccountID: "ACC001"},
+		{Principal: "key:abc", AccountID: "ACC001"},
+		{Principal: "user:", AccountID: "ACC001"},
+		{Principal: "key:12"},
+		{Principal: "key:12", AccountID: "ACC001", AllAccounts: true},
+	}
+	for _, e := range invalid {
+		if err := validateEntitlement(e); !errors.Is(err, errInvalidEntitlement) {
+			t.Errorf("%+v: expected errInvalidEntitlement, got %v", e, err)
+		}
+	}
+}
+
+func TestEventVisible(t *testing.T) {
+	event := func(v any) models.Event {
+		data, _ := json.Marshal(v)
+		return models.Event{Type: "alarm.opened", Data: data}
+	}
+	access := AccountAccess("ACC001")
+	if !eventVisible(access, event(models.Alarm{AccountID: "ACC001"})) {
+		t.Errorf("expected an alarm on an entitled account to be visible")
+	}
+	if eventVisible(access, event(models.Alarm{AccountID: "ACC002"})) {
+		t.Errorf("alarm on another adviser's account leaked")
+	}
+	for _, v := range []any{
+		map[string]any{"file": "positions_20250115.csv", "accounts": []string{"ACC001", "ACC002"}},
+		map[string]string{"date": "2025-01-15"},
+	} {
+		if eventVisible(access, event(v)) {
+			t.Errorf("event without an account leaked to a restricted caller: %v", v)
+		}
+		if !eventVisible(FullAccess(), event(v)) {
+			t.Errorf("expected full access to see events without an account: %v", v)
+		}
+	}
+	if !eventVisible(FullAccess(), event(models.Alarm{AccountID: "ACC002"})) {
+		t.Errorf("expected admins to see every event")
+	}
+}
@@ -0,0 +1,159 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// sseHeartbeat keeps idle connections open through proxies
+const sseHeartbeat = 15 * time.Second
+
+// replayPage is how many stored events are read per query when resuming
+const replayPage = 500
+
+// StreamEvents serves a Server-Sent Events stream of ingestion, position and
+// alarm events. Clients resume with Last-Event-ID (or last_event_id for
+// EventSource polyfills) and can narrow the stream with types=a,b.
+func (h *Handler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+	// A stream outlives the server's write timeout
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	var since int64
+	if lastID != "" {
+		var err error
+		if since, err = strconv.ParseInt(lastID, 10, 64); err != nil || since < 0 {
+			http.Error(w, "Last-Event-ID must be an event id", http.StatusBadRequest)
+			return
+		}
+	}
+	var types map[string]bool
+	if v := r.URL.Query().Get("types"); v != "" {
+		types = make(map[string]bool)
+		for _, t := range splitList(v) {
+			types[t] = true
+		}
+	}
+
+	// Entitlements are read once; changes apply when the client reconnects
+	access, err := h.Access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Subscribe before replaying so nothing published in between is missed;
+	// anything seen in both is skipped by id
+	sub := h.Events.Subscribe()
+	defer h.Events.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if _, err := fmt.Fprint(w, "retry: 3000\n\n"); err != nil {
+		return
+	}
+
+	send := func(e models.Event) error {
+		if e.ID <= since {
+			return nil
+		}
+		since = e.ID
+		if types != nil && !types[e.Type] {
+			return nil
+		}
+		if !eventVisible(access, e) {
+			return nil
+		}
+		if id, ok := eventAccount(e); ok {
+			audit.Note(r.Context(), id)
+		}
+		_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Type, e.Data)
+		return err
+	}
+
+	if lastID != "" {
+		for {
+			batch, err := h.Events.Since(r.Context(), since, replayPage)
+			if err != nil {
+				// Headers are sent; tell the client through the stream and let it retry
+				fmt.Fprintf(w, "event: error\ndata: {\"error\":%q}\n\n", "replay failed")
+				return
+			}
+			for _, e := range batch {
+				if err := send(e); err != nil {
+					return
+				}
+			}
+			if len(batch) < replayPage {
+				break
+			}
+		}
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.C:
+			if !ok {
+				// Dropped for falling behind; the client reconnects with Last-Event-ID
+				return
+			}
+			if err := send(e); err != nil {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
+
+// eventVisible hides events about accounts the caller is not entitled to.
+// Events without an account, such as file.ingested and positions.changed,
+// reveal ingestion activity across the whole book, so only callers with full
+// access see them.
+func eventVisible(access Access, e models.Event) bool {
+	if access.All() {
+		return true
+	}
+	id, ok := eventAccount(e)
+	return ok && id != "" && access.Allows(id)
+}
+
+// eventAccount reads the account an event is about, if any
+func eventAccount(e models.Event) (string, bool) {
+	var payload struct {
+		AccountID string `json:"account_id"`
+	}
+	if err := json.Unmarshal(e.Data, &payload); err != nil {
+		return "", false
+	}
+	return payload.AccountID, true
+}
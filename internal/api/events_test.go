@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/events"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// asAdmin serves as a caller with full access, who sees every event
+func asAdmin(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := &auth.Principal{Name: "bootstrap", Scopes: []string{auth.ScopeAdmin}}
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), p)))
+	})
+}
+
+func TestStreamEvents(t *testing.T) {
+	h := &Handler{Events: events.NewBroker(nil)}
+	srv := httptest.NewServer(asAdmin(h.StreamEvents))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?types=" + events.AlarmOpened)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	// The handler has subscribed once headers are flushed
+	h.Events.Broadcast(models.Event{ID: 1, Type: events.PositionsChanged, Data: []byte(`{"date":"2025-01-15"}`)})
+	h.Events.Broadcast(models.Event{ID: 2, Type: events.AlarmOpened, Data: []byte(`{"id":9}`)})
+
+	got := make(chan string, 1)
+	go func() {
+		var lines []string
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if strings.HasPrefix(sc.Text(), "id:") || len(lines) > 0 {
+				lines = append(lines, sc.Text())
+			}
+			if len(lines) == 4 {
+				break
+			}
+		}
+		got <- strings.Join(lines, "\n")
+	}()
+
+	select {
+	case frame := <-got:
+		want := "id: 2\nevent: alarm.opened\ndata: {\"id\":9}\n"
+		if frame != want {
+			t.Errorf("expected frame %q, got %q", want, frame)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestStreamEventsOutlivesWriteTimeout(t *testing.T) {
+	h := &Handler{Events: events.NewBroker(nil)}
+	srv := httptest.NewUnstartedServer(asAdmin(h.StreamEvents))
+	srv.Config.WriteTimeout = 50 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	time.Sleep(150 * time.Millisecond)
+	h.Events.Broadcast(models.Event{ID: 1, Type: events.AlarmOpened, Data: []byte(`{}`)})
+
+	got := make(chan bool, 1)
+	go func() {
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			if sc.Text() == "id: 1" {
+				got <- true
+				return
+			}
+		}
+		got <- false
+	}()
+	select {
+	case ok := <-got:
+		if !ok {
+			t.Fatal("stream closed by the write timeout")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
+
+func TestStreamEventsBadLastEventID(t *testing.T) {
+	h := &Handler{Events: events.NewBroker(nil)}
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rec := httptest.NewRecorder()
+	h.StreamEvents(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("expected 400, got %d", rec.Code)
+	}
+}
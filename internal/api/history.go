@@ -0,0 +1,170 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// parseDateRange reads optional from/to (YYYY-MM-DD) query params
+func parseDateRange(r *http.Request) (from, to string, ok bool) {
+	from = r.URL.Query().Get("from")
+	to = r.URL.Query().Get("to")
+	var fromT, toT time.Time
+	var err error
+	if from != "" {
+		if fromT, err = time.Parse("2006-01-02", from); err != nil {
+			return "", "", false
+		}
+	}
+	if to != "" {
+		if toT, err = time.Parse("2006-01-02", to); err != nil {
+			return "", "", false
+		}
+	}
+	if from != "" && to != "" && toT.Before(fromT) {
+		return "", "", false
+	}
+	return from, to, true
+}
+
+// AccountHistory returns daily totals for an account, or for one of its
+// tickers when ?ticker= is given.
+func (h *Handler) AccountHistory(w http.ResponseWriter, r *http.Request) {
+	accountID := r.PathValue("id")
+	from, to, ok := parseDateRange(r)
+	if !ok {
+		http.Error(w, "from/to must be YYYY-MM-DD with from <= to", http.StatusBadRequest)
+		return
+	}
+
+	access, err := h.Access(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if !access.Allows(accountID) {
+		http.Error(w, "account not found", http.StatusNotFound)
+		return
+	}
+
+	audit.Note(r.Context(), accountID)
+	resp := models.AccountHistoryResponse{AccountID: accountID, From: from, To: to}
+	if ticker := r.URL.Query().Get("ticker"); ticker != "" {
+		resp.Ticker = ticker
+		resp.Tickers, err = h.tickerHistory(r.Context(), accountID, ticker, from, to)
+	} else {
+		resp.Points, err = h.accountHistory(r.Context(), accountID, from, to)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) accountHistory(ctx context.Context, accountID, from, to string) ([]models.AccountHistoryPoint, error) {
+	// One pass over the account's dates. The lower bound is applied after LAG
+	// so the first point in range still gets a day-over-day change.
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date, total_mv, long_mv, short_mv, holdings, prev_mv
+		FROM (
+			SELECT date, total_mv, long_mv, short_mv, holdings,
+				LAG(total_mv) OVER (ORDER BY date) AS prev_mv
+			FROM (
+				SELECT date,
+					COALESCE(SUM(market_value), 0) AS total_mv,
+					COALESCE(SUM(market_value) FILTER (WHERE market_value > 0), 0) AS long_mv,
+					COALESCE(SUM(market_value) FILTER (WHERE market_value < 0), 0) AS short_mv,
+					COUNT(*) FILTER (WHERE quantity <> 0) AS holdings
+				FROM positions
+				WHERE account_id = $1
+				  AND ($3 = '' OR date <= NULLIF($3, '')::date)
+				GROUP BY date
+			) daily
+		) series
+		WHERE ($2 = '' OR date >= NULLIF($2, '')::date)
+		ORDER BY date
+	`, accountID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.AccountHistoryPoint{}
+	for rows.Next() {
+		var p models.AccountHistoryPoint
+		var d time.Time
+		var prev *float64
+		if err := rows.Scan(&d, &p.TotalMarketValue, &p.LongMarketValue, &p.ShortMarketValue, &p.Holdings, &prev); err != nil {
+			return nil, err
+		}
+		p.Date = d.Format("2006-01-02")
+		if prev != nil {
+			change := p.TotalMarketValue - *prev
+			p.Change = &change
+			if *prev != 0 {
+				pct := change / abs(*prev) * 100
+				p.ChangePct = &pct
+			}
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+func (h *Handler) tickerHistory(ctx context.Context, accountID, ticker, from, to string) ([]models.TickerHistoryPoint, error) {
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date, quantity, market_value, total_mv, prev_mv
+		FROM (
+			SELECT p.date, p.quantity, p.market_value, t.total_mv,
+				LAG(p.market_value) OVER (ORDER BY p.date) AS prev_mv
+			FROM positions p
+			JOIN (
+				SELECT date, SUM(market_value) AS total_mv
+				FROM positions
+				WHERE account_id = $1
+				  AND ($4 = '' OR date <= NULLIF($4, '')::date)
+				GROUP BY date
+			) t ON t.date = p.date
+			WHERE p.account_id = $1 AND p.ticker = $2
+		) series
+		WHERE ($3 = '' OR date >= NULLIF($3, '')::date)
+		ORDER BY date
+	`, accountID, ticker, from, to)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []models.TickerHistoryPoint{}
+	for rows.Next() {
+		var p models.TickerHistoryPoint
+		var d time.Time
+		var total float64
+		var prev *float64
+		if err := rows.Scan(&d, &p.Quantity, &p.MarketValue, &total, &prev); err != nil {
+			return nil, err
+		}
+		p.Date = d.Format("2006-01-02")
+		if total != 0 {
+			p.AccountPercent = p.MarketValue / total * 100
+		}
+		if prev != nil {
+			change := p.MarketValue - *prev
+			p.Change = &change
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
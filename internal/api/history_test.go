@@ -0,0 +1,26 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestParseDateRange(t *testing.T) {
+	cases := []struct {
+		query string
+		ok    bool
+	}{
+		{"", true},
+		{"from=2025-01-01", true},
+		{"from=2025-01-01&to=2025-01-31", true},
+		{"from=2025-01-31&to=2025-01-01", false},
+		{"from=20250101", false},
+		{"to=yesterday", false},
+	}
+	for _, c := range cases {
+		req := httptest.NewRequest("GET", "/accounts/ACC001/history?"+c.query, nil)
+		if _, _, ok := parseDateRange(req); ok != c.ok {
+			t.Errorf("parseDateRange(%q) ok = %v, want %v", c.query, ok, c.ok)
+		}
+	}
+}
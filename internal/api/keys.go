@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+)
+
+// defaultRotationOverlap is how long a rotated key keeps working
+const defaultRotationOverlap = 24 * time.Hour
+
+type issueKeyRequest struct {
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// CreateKey issues an API key. The key itself is only returned here.
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var req issueKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if req.Name == "" {
+		http.Error(w, "name is required", http.StatusBadRequest)
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		http.Error(w, "expires_at must be in the future", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.Keys.Issue(r.Context(), req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.Keys.List(r.Context())
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// RotateKey issues a replacement key; the old one expires after ?overlap= (default 24h)
+func (h *Handler) RotateKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	overlap := defaultRotationOverlap
+	if v := r.URL.Query().Get("overlap"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d < 0 {
+			http.Error(w, "overlap must be a duration such as 1h", http.StatusBadRequest)
+			return
+		}
+		overlap = d
+	}
+
+	key, err := h.Keys.Rotate(r.Context(), id, overlap)
+	if err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, key)
+}
+
+// RevokeKey disables a key immediately
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	id, ok := pathID(w, r)
+	if !ok {
+		return
+	}
+	if err := h.Keys.Revoke(r.Context(), id); err != nil {
+		writeKeyError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func writeKeyError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, auth.ErrKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, auth.ErrInvalidScope):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,350 @@
+package api_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/api"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+	"github.com/AndrewCharlesHay/vest/internal/events"
+	"github.com/AndrewCharlesHay/vest/internal/graphql"
+	"github.com/AndrewCharlesHay/vest/internal/middleware"
+	"github.com/AndrewCharlesHay/vest/internal/rpc"
+	"github.com/AndrewCharlesHay/vest/internal/rpc/vestv1"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// The leak suite calls every endpoint as adviser A, who is entitled to the
+// adviser-a group, and fails if anything identifying adviser B's client comes
+// back. It runs against Postgres when VEST_TEST_DATABASE_URL is set.
+const (
+	ownAccount   = "ACC-A1"  // In adviser-a
+	childAccount = "ACC-A2"  // In adviser-a-trust, beneath adviser-a
+	otherAccount = "ACC-B1"  // In adviser-b
+	otherAlias   = "CUST-B1" // Custodian alias of otherAccount
+	otherTicker  = "ZZOTHER" // Held only by otherAccount
+	date         = "2025-01-15"
+	adminKey     = "leak-suite-admin"
+)
+
+var forbidden = []string{otherAccount, otherAlias, otherTicker}
+
+// leakCase is one request made as adviser A. Paths may use {own} and {other}
+// for the ids of alarms on each adviser's accounts.
+type leakCase struct {
+	route  string // Pattern in Handler.Routes this case exercises
+	method string
+	path   string
+	body   string
+	status int
+	see    string // Must appear, showing the case returned data at all
+}
+
+var leakCases = []leakCase{
+	{"/blotter", "GET", "/blotter?date=" + date, "", 200, ownAccount},
+	{"/blotter", "GET", "/blotter?date=" + date + "&account_id=" + otherAccount, "", 200, ""},
+	{"/blotter", "GET", "/blotter?date=" + date + "&group=firm", "", 200, childAccount},
+	{"/positions", "GET", "/positions?date=" + date, "", 200, childAccount},
+	{"/positions", "GET", "/positions?date=" + date + "&account_id=" + otherAccount, "", 200, ""},
+	{"/positions", "GET", "/positions?date=" + date + "&group=firm", "", 200, "AAAA"},
+	{"/positions", "GET", "/positions?date=" + date + "&group=adviser-b", "", 200, ""},
+	{"GET /positions/diff", "GET", "/positions/diff?from=2025-01-15&to=2025-01-16", "", 200, ownAccount},
+	{"GET /positions/diff", "GET", "/positions/diff?from=2025-01-15&to=2025-01-16&group=adviser-b", "", 200, ""},
+	{"/alarms", "GET", "/alarms?date=" + date + "&include_all=true", "", 200, ownAccount},
+	{"/alarms", "GET", "/alarms?date=" + date + "&group=firm&include_all=true", "", 200, "AAAA"},
+	{"GET /alarms/history", "GET", "/alarms/history", "", 200, ownAccount},
+	{"GET /alarms/history", "GET", "/alarms/history?account_id=" + otherAccount, "", 200, ""},
+	{"GET /alarms/{id}", "GET", "/alarms/{own}", "", 200, ownAccount},
+	{"GET /alarms/{id}", "GET", "/alarms/{other}", "", 404, ""},
+	{"POST /alarms/{id}/acknowledge", "POST", "/alarms/{other}/acknowledge", `{}`, 404, ""},
+	{"POST /alarms/{id}/resolve", "POST", "/alarms/{other}/resolve", `{}`, 404, ""},
+	{"POST /alarms/{id}/assign", "POST", "/alarms/{other}/assign", `{"assignee":"adviser-a"}`, 404, ""},
+	{"POST /alarms/{id}/comments", "POST", "/alarms/{other}/comments", `{"comment":"mine now"}`, 404, ""},
+	{"POST /alarms/evaluate", "POST", "/alarms/evaluate?date=" + date, "", 403, ""},
+	{"GET /accounts", "GET", "/accounts", "", 200, childAccount},
+	{"GET /accounts/{id}", "GET", "/accounts/" + ownAccount, "", 200, ownAccount},
+	{"GET /accounts/{id}", "GET", "/accounts/" + otherAccount, "", 404, ""},
+	{"GET /accounts/{id}", "GET", "/accounts/" + otherAlias, "", 404, ""},
+	{"PUT /accounts/{id}", "PUT", "/accounts/" + otherAccount, `{"name":"Renamed"}`, 403, ""},
+	{"GET /accounts/{id}/history", "GET", "/accounts/" + ownAccount + "/history", "", 200, date},
+	{"GET /accounts/{id}/history", "GET", "/accounts/" + otherAccount + "/history", "", 404, ""},
+	{"GET /groups", "GET", "/groups", "", 200, ownAccount},
+	{"GET /groups/{id}", "GET", "/groups/firm", "", 200, childAccount},
+	{"GET /groups/{id}", "GET", "/groups/adviser-b", "", 200, ""},
+	{"PUT /groups/{id}", "PUT", "/groups/adviser-b", `{"type":"household","accounts":["` + ownAccount + `"]}`, 403, ""},
+	{"DELETE /groups/{id}", "DELETE", "/groups/adviser-b", "", 403, ""},
+	// Reference data carries no account information
+	{"GET /securities", "GET", "/securities", "", 200, "AAAA"},
+	{"GET /securities/{id}", "GET", "/securities/AAAA", "", 200, "AAAA"},
+	{"PUT /securities/{id}", "PUT", "/securities/TMP1", `{"issuer":"Temp Corp"}`, 200, "TMP1"},
+	{"DELETE /securities/{id}", "DELETE", "/securities/TMP1", "", 204, ""},
+	// Admin scope only
+	{"GET /webhooks", "GET", "/webhooks", "", 403, ""},
+	{"POST /webhooks", "POST", "/webhooks", `{}`, 403, ""},
+	{"DELETE /webhooks/{id}", "DELETE", "/webhooks/1", "", 403, ""},
+	{"POST /webhooks/{id}/test", "POST", "/webhooks/1/test", "", 403, ""},
+	{"GET /webhooks/{id}/deliveries", "GET", "/webhooks/1/deliveries", "", 403, ""},
+	{"GET /keys", "GET", "/keys", "", 403, ""},
+	{"POST /keys", "POST", "/keys", `{}`, 403, ""},
+	{"POST /keys/{id}/rotate", "POST", "/keys/1/rotate", "", 403, ""},
+	{"DELETE /keys/{id}", "DELETE", "/keys/1", "", 403, ""},
+	{"GET /entitlements", "GET", "/entitlements", "", 403, ""},
+	{"POST /entitlements", "POST", "/entitlements", `{"principal":"key:1","all_accounts":true}`, 403, ""},
+	{"DELETE /entitlements/{id}", "DELETE", "/entitlements/1", "", 403, ""},
+	{"GET /audit", "GET", "/audit", "", 403, ""},
+	{"GET /events", "GET", "/events?last_event_id=0", "", 200, ownAccount},
+}
+
+// TestLeakCasesCoverEveryRoute fails when an endpoint is added without a leak case
+func TestLeakCasesCoverEveryRoute(t *testing.T) {
+	routes := make(map[string]bool)
+	for _, r := range api.NewHandler(nil).Routes(cache.NewCache(nil)) {
+		routes[r.Pattern] = true
+	}
+	covered := make(map[string]bool)
+	for _, c := range leakCases {
+		if !routes[c.route] {
+			t.Errorf("leak case names unknown route %q", c.route)
+		}
+		covered[c.route] = true
+	}
+	for pattern := range routes {
+		if !covered[pattern] {
+			t.Errorf("route %q has no entitlement leak case", pattern)
+		}
+	}
+}
+
+type leakSuite struct {
+	handler  http.Handler
+	authn    *auth.Authenticator
+	h        *api.Handler
+	adviser  string // Plaintext key for adviser A
+	nobody   string // Plaintext key with no entitlements
+	ownAlarm int64
+	other    int64
+}
+
+func TestNoEndpointLeaksAccounts(t *testing.T) {
+	s := newLeakSuite(t, testdb.Open(t))
+
+	// The other adviser's data is there to leak: an admin sees it, and the
+	// cached response must not then be served to adviser A
+	for _, path := range []string{"/blotter?date=" + date, "/alarms/history", "/accounts"} {
+		if _, body := s.do(t, adminKey, "GET", path, ""); !strings.Contains(body, otherAccount) {
+			t.Fatalf("admin GET %s: expected %s in %s", path, otherAccount, body)
+		}
+	}
+
+	for _, c := range leakCases {
+		path := strings.NewReplacer("{own}", fmt.Sprint(s.ownAlarm), "{other}", fmt.Sprint(s.other)).Replace(c.path)
+		status, body := s.do(t, s.adviser, c.method, path, c.body)
+		name := c.method + " " + path
+		if status != c.status {
+			t.Errorf("%s: expected %d, got %d: %s", name, c.status, status, body)
+		}
+		if c.see != "" && !strings.Contains(body, c.see) {
+			t.Errorf("%s: expected %s in %s", name, c.see, body)
+		}
+		checkNoLeak(t, name, body)
+	}
+
+	// A key with no entitlements sees no accounts at all
+	for _, path := range []string{"/blotter?date=" + date, "/positions?date=" + date, "/accounts", "/alarms/history"} {
+		_, body := s.do(t, s.nobody, "GET", path, "")
+		if strings.Contains(body, ownAccount) || strings.Contains(body, otherAccount) {
+			t.Errorf("unentitled GET %s returned accounts: %s", path, body)
+		}
+	}
+}
+
+func TestGraphQLLeaksNoAccounts(t *testing.T) {
+	s := newLeakSuite(t, testdb.Open(t))
+	queries := map[string]string{
+		`{ blotter(date: "` + date + `") { accountId ticker account { accountId } } }`:                   ownAccount,
+		`{ blotter(date: "` + date + `", accountId: "` + otherAccount + `") { accountId } }`:             "",
+		`{ positions(date: "` + date + `", group: "firm") { accountId total allocations { key } } }`:     "AAAA",
+		`{ alarms(date: "` + date + `", includeAll: true) { accountId violations { ticker } } }`:         ownAccount,
+		`{ accounts { accountId aliases { alias } groups } }`:                                            childAccount,
+		`{ account(id: "` + otherAccount + `") { accountId blotter(date: "` + date + `") { ticker } } }`: "",
+	}
+	for query, see := range queries {
+		status, body := s.do(t, s.adviser, "POST", "/graphql", fmt.Sprintf(`{"query":%q}`, query))
+		if status != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %s", query, status, body)
+		}
+		if see != "" && !strings.Contains(body, see) {
+			t.Errorf("%s: expected %s in %s", query, see, body)
+		}
+		checkNoLeak(t, query, body)
+	}
+}
+
+func TestGRPCLeaksNoAccounts(t *testing.T) {
+	s := newLeakSuite(t, testdb.Open(t))
+	lis := bufconn.Listen(1 << 20)
+	srv := rpc.NewServer(s.h, s.authn, nil, nil)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	client := vestv1.NewVestServiceClient(conn)
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", s.adviser)
+
+	blotter, err := client.GetBlotter(ctx, &vestv1.BlotterRequest{Date: date})
+	if err != nil || !strings.Contains(blotter.String(), ownAccount) {
+		t.Errorf("GetBlotter: %v %v", blotter, err)
+	}
+	checkNoLeak(t, "GetBlotter", blotter.String())
+
+	stream, err := client.StreamBlotter(ctx, &vestv1.BlotterRequest{Date: date, Group: "firm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for {
+		row, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("StreamBlotter: %v", err)
+		}
+		checkNoLeak(t, "StreamBlotter", row.String())
+	}
+
+	positions, err := client.GetPositions(ctx, &vestv1.PositionsRequest{Date: date, Group: "firm"})
+	if err != nil {
+		t.Fatalf("GetPositions: %v", err)
+	}
+	checkNoLeak(t, "GetPositions", positions.String())
+
+	alarms, err := client.GetAlarms(ctx, &vestv1.AlarmsRequest{Date: date, IncludeAll: true, AccountId: otherAccount})
+	if err != nil {
+		t.Fatalf("GetAlarms: %v", err)
+	}
+	checkNoLeak(t, "GetAlarms", alarms.String())
+}
+
+func checkNoLeak(t *testing.T, name, body string) {
+	t.Helper()
+	for _, token := range forbidden {
+		if strings.Contains(body, token) {
+			t.Errorf("%s leaked %s: %s", name, token, body)
+		}
+	}
+}
+
+// do sends a request through the auth middleware. The context deadline ends
+// the /events stream once the stored events are replayed.
+func (s *leakSuite) do(t *testing.T, key, method, path, body string) (int, string) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req := httptest.NewRequest(method, path, strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("X-API-Key", key)
+	rec := httptest.NewRecorder()
+	s.handler.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.String()
+}
+
+// newLeakSuite seeds two advisers' books beneath one firm:
+//
+//	firm
+//	├── adviser-a          ACC-A1  (entitled to adviser A)
+//	│   └── adviser-a-trust ACC-A2
+//	└── adviser-b          ACC-B1
+func newLeakSuite(t *testing.T, db *sql.DB) *leakSuite {
+	t.Helper()
+	exec := func(query string, args ...any) {
+		t.Helper()
+		if _, err := db.Exec(query, args...); err != nil {
+			t.Fatalf("seed: %v", err)
+		}
+	}
+	exec(`INSERT INTO accounts (account_id, name) VALUES ($1, 'Adviser A client'), ($2, 'Adviser A trust'), ($3, 'Adviser B client')`,
+		ownAccount, childAccount, otherAccount)
+	exec(`INSERT INTO account_aliases (alias, account_id, custodian) VALUES ('CUST-A1', $1, 'CUSTODIAN_A'), ($2, $3, 'CUSTODIAN_A')`,
+		ownAccount, otherAlias, otherAccount)
+	exec(`INSERT INTO account_groups (group_id, name, type, parent_id) VALUES
+		('firm', 'Firm', 'firm', NULL),
+		('adviser-a', 'Adviser A', 'adviser', 'firm'),
+		('adviser-a-trust', 'Adviser A trusts', 'household', 'adviser-a'),
+		('adviser-b', 'Adviser B', 'adviser', 'firm')`)
+	exec(`INSERT INTO account_group_members (group_id, account_id) VALUES
+		('adviser-a', $1), ('adviser-a-trust', $2), ('adviser-b', $3)`, ownAccount, childAccount, otherAccount)
+	exec(`INSERT INTO positions (date, account_id, ticker, quantity, market_value) VALUES
+		('2025-01-15', $1, 'AAAA', 100, 1000), ('2025-01-15', $2, 'ABAB', 50, 500), ('2025-01-15', $3, $4, 10, 777),
+		('2025-01-16', $1, 'AAAA', 120, 1200), ('2025-01-16', $3, $4, 5, 400)`,
+		ownAccount, childAccount, otherAccount, otherTicker)
+	exec(`INSERT INTO securities (ticker, issuer) VALUES ('AAAA', 'A Corp'), ('ABAB', 'AB Corp')`)
+
+	h := api.NewHandler(db)
+	h.Keys = &auth.KeyStore{DB: db, Bootstrap: adminKey}
+	s := &leakSuite{h: h, authn: &auth.Authenticator{Keys: h.Keys}}
+
+	// Every single-holding account breaches the concentration rule
+	result, err := h.Monitor.Evaluate(context.Background(), date)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range result.Opened {
+		switch a.AccountID {
+		case ownAccount:
+			s.ownAlarm = a.ID
+		case otherAccount:
+			s.other = a.ID
+		}
+		if err := h.Events.Publish(context.Background(), events.AlarmOpened, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s.ownAlarm == 0 || s.other == 0 {
+		t.Fatalf("expected alarms on both advisers' accounts, got %+v", result.Opened)
+	}
+	// A file touching both books must not reach the adviser's stream
+	file := map[string]any{"file": "positions_20250115.csv", "dates": []string{date}, "accounts": []string{ownAccount, otherAccount}}
+	if err := h.Events.Publish(context.Background(), events.FileIngested, file); err != nil {
+		t.Fatal(err)
+	}
+
+	adviser, err := h.Keys.Issue(context.Background(), "adviser-a", []string{auth.ScopeRead, auth.ScopeIngest}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec(`INSERT INTO entitlements (principal, group_id) VALUES ($1, 'adviser-a')`, fmt.Sprintf("key:%d", adviser.ID))
+	nobody, err := h.Keys.Issue(context.Background(), "unentitled", []string{auth.ScopeRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.adviser, s.nobody = adviser.Key, nobody.Key
+
+	mux := http.NewServeMux()
+	for _, route := range h.Routes(cache.NewCache(db)) {
+		mux.HandleFunc(route.Pattern, route.Handler)
+	}
+	gql, err := graphql.NewHandler(h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mux.Handle("/graphql", gql)
+	s.handler = middleware.Authenticate(s.authn, mux)
+	return s
+}
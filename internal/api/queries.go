@@ -0,0 +1,230 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Query errors that map to client errors rather than a 500
+var (
+	ErrInvalidArgument = errors.New("invalid argument")
+	ErrNotFound        = errors.New("not found")
+)
+
+// Filter narrows the read queries shared by REST, GraphQL and gRPC. Group
+// aggregates positions and alarms across every account beneath it. Every
+// query is further limited to the accounts the principal in its context is
+// entitled to (see Access).
+type Filter struct {
+	Date      string
+	AccountID string
+	Group     string
+}
+
+type PositionOptions struct {
+	Filter
+	Exposure compliance.Exposure
+	GroupBy  string // Security master attribute to key allocations by
+}
+
+type AlarmOptions struct {
+	Filter
+	Exposure   compliance.Exposure
+	IncludeAll bool
+}
+
+// QueryBlotter returns the raw positions for a date
+func (h *Handler) QueryBlotter(ctx context.Context, f Filter) ([]models.BlotterResponse, error) {
+	var results []models.BlotterResponse
+	err := h.EachBlotterRow(ctx, f, func(b models.BlotterResponse) error {
+		results = append(results, b)
+		return nil
+	})
+	return results, err
+}
+
+// EachBlotterRow calls fn for each raw position as it is read, so streaming
+// callers never hold the whole date in memory. An error from fn stops the scan.
+func (h *Handler) EachBlotterRow(ctx context.Context, f Filter, fn func(models.BlotterResponse) error) error {
+	ctx, span := startQuery(ctx, "api.blotter", f)
+	rows := 0
+	err := h.eachBlotterRow(ctx, f, func(b models.BlotterResponse) error {
+		rows++
+		return fn(b)
+	})
+	endQuery(span, rows, err)
+	return err
+}
+
+func (h *Handler) eachBlotterRow(ctx context.Context, f Filter, fn func(models.BlotterResponse) error) error {
+	accounts, restricted, err := h.accountScope(ctx, f.Group)
+	if err != nil {
+		return err
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT date, account_id, ticker, quantity, market_value 
+		FROM positions 
+		WHERE date = $1
+		  AND ($2 = '' OR account_id = $2)
+		  AND ($3 = FALSE OR account_id = ANY($4))
+	`, f.Date, f.AccountID, restricted, accounts)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b models.BlotterResponse
+		var d time.Time // Scan date as time
+		if err := rows.Scan(&d, &b.AccountID, &b.Ticker, &b.Quantity, &b.MarketValue); err != nil {
+			continue
+		}
+		b.Date = d.Format("2006-01-02")
+		audit.Note(ctx, b.AccountID)
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// QueryPositions returns each account's allocations under the chosen exposure
+func (h *Handler) QueryPositions(ctx context.Context, o PositionOptions) ([]models.PositionResponse, error) {
+	ctx, span := startQuery(ctx, "api.positions", o.Filter,
+		attribute.String("vest.exposure", string(o.Exposure)), attribute.String("vest.group_by", o.GroupBy))
+	response, err := h.positions(ctx, o)
+	endQuery(span, len(response), err)
+	return response, err
+}
+
+func (h *Handler) positions(ctx context.Context, o PositionOptions) ([]models.PositionResponse, error) {
+	// group_by=issuer|asset_class|sector|... aggregates allocations by a security master attribute
+	var groups map[string]string
+	if o.GroupBy != "" && o.GroupBy != "ticker" {
+		column, ok := securityGroupColumns[o.GroupBy]
+		if !ok {
+			return nil, fmt.Errorf("%w: unsupported group_by %q", ErrInvalidArgument, o.GroupBy)
+		}
+		var err error
+		if groups, err = h.securityGroups(ctx, column); err != nil {
+			return nil, err
+		}
+	}
+
+	holdings, err := h.holdings(ctx, o.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	var response []models.PositionResponse
+	for _, acc := range compliance.ApplyExposure(holdings, o.Exposure) {
+		allocs := make(map[string]float64)
+		// A zero total leaves allocations undefined rather than reporting 0%
+		if !acc.Undefined() {
+			for _, hd := range acc.Holdings {
+				key := hd.Ticker
+				if groups != nil {
+					if key = groups[hd.Ticker]; key == "" {
+						key = UnclassifiedGroup
+					}
+				}
+				allocs[key] += hd.Percent()
+			}
+		}
+		response = append(response, models.PositionResponse{
+			AccountID:   acc.ID,
+			GroupID:     o.Group,
+			Exposure:    string(o.Exposure),
+			GroupBy:     o.GroupBy,
+			Total:       acc.Total,
+			Allocations: allocs,
+			Warning:     acc.Warning,
+		})
+	}
+	return response, nil
+}
+
+// QueryAlarms evaluates the compliance rules for a date
+func (h *Handler) QueryAlarms(ctx context.Context, o AlarmOptions) ([]models.AlarmResponse, error) {
+	ctx, span := startQuery(ctx, "api.alarms", o.Filter, attribute.String("vest.exposure", string(o.Exposure)))
+	response, err := h.alarms(ctx, o)
+	endQuery(span, len(response), err)
+	return response, err
+}
+
+func (h *Handler) alarms(ctx context.Context, o AlarmOptions) ([]models.AlarmResponse, error) {
+	holdings, err := h.holdings(ctx, o.Filter)
+	if err != nil {
+		return nil, err
+	}
+
+	response := compliance.Evaluate(o.Date, holdings, compliance.Options{Exposure: o.Exposure, IncludeAll: o.IncludeAll})
+	if o.Group != "" {
+		for i := range response {
+			response[i].GroupID = o.Group
+		}
+	}
+	return response, nil
+}
+
+// holdings loads the positions for a date, narrowed to one account or
+// aggregated into a single pseudo-account named after the group. Only rows
+// for the accounts the caller is entitled to are read, so a group aggregate
+// leaves the others out.
+func (h *Handler) holdings(ctx context.Context, f Filter) ([]compliance.Holding, error) {
+	accounts, restricted, err := h.accountScope(ctx, f.Group)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := h.DB.QueryContext(ctx, `
+		SELECT account_id, ticker, market_value
+		FROM positions
+		WHERE date = $1
+		  AND ($2 = '' OR account_id = $2)
+		  AND ($3 = FALSE OR account_id = ANY($4))
+	`, f.Date, f.AccountID, restricted, accounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []compliance.Holding
+	for rows.Next() {
+		var hd compliance.Holding
+		if err := rows.Scan(&hd.AccountID, &hd.Ticker, &hd.MarketValue); err != nil {
+			continue
+		}
+		holdings = append(holdings, hd)
+		audit.Note(ctx, hd.AccountID)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	if f.Group == "" {
+		return holdings, nil
+	}
+	return compliance.AggregateHoldings(holdings, accounts, f.Group), nil
+}
+
+// writeQueryError maps a Query* error to a response
+func writeQueryError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidArgument):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, ErrNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
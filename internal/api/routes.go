@@ -0,0 +1,59 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+)
+
+// Route is one REST endpoint as registered on a ServeMux
+type Route struct {
+	Pattern string
+	Handler http.HandlerFunc
+}
+
+// Routes lists the REST endpoints. Date-keyed reads answer conditional
+// requests through responses.
+func (h *Handler) Routes(responses *cache.Cache) []Route {
+	return []Route{
+		{"/blotter", responses.Wrap(h.Blotter)},
+		{"/positions", responses.Wrap(h.Positions)},
+		{"GET /positions/diff", h.PositionsDiff},
+		{"/alarms", responses.Wrap(h.Alarms)},
+		{"GET /alarms/history", h.AlarmHistory},
+		{"POST /alarms/evaluate", h.EvaluateAlarms},
+		{"GET /alarms/{id}", h.GetAlarm},
+		{"POST /alarms/{id}/acknowledge", h.AcknowledgeAlarm},
+		{"POST /alarms/{id}/resolve", h.ResolveAlarm},
+		{"POST /alarms/{id}/assign", h.AssignAlarm},
+		{"POST /alarms/{id}/comments", h.CommentAlarm},
+		{"GET /accounts", h.ListAccounts},
+		{"GET /accounts/{id}", h.GetAccount},
+		{"PUT /accounts/{id}", h.PutAccount},
+		{"GET /accounts/{id}/history", h.AccountHistory},
+		{"GET /groups", h.ListGroups},
+		{"GET /groups/{id}", h.GetGroup},
+		{"PUT /groups/{id}", h.PutGroup},
+		{"DELETE /groups/{id}", h.DeleteGroup},
+		{"GET /securities", h.ListSecurities},
+		{"GET /securities/{id}", h.GetSecurity},
+		{"PUT /securities/{id}", h.PutSecurity},
+		{"DELETE /securities/{id}", h.DeleteSecurity},
+		// Admin scope
+		{"GET /webhooks", h.ListWebhooks},
+		{"POST /webhooks", h.CreateWebhook},
+		{"DELETE /webhooks/{id}", h.DeleteWebhook},
+		{"POST /webhooks/{id}/test", h.TestWebhook},
+		{"GET /webhooks/{id}/deliveries", h.WebhookDeliveries},
+		{"GET /keys", h.ListKeys},
+		{"POST /keys", h.CreateKey},
+		{"POST /keys/{id}/rotate", h.RotateKey},
+		{"DELETE /keys/{id}", h.RevokeKey},
+		{"GET /entitlements", h.ListEntitlements},
+		{"POST /entitlements", h.CreateEntitlement},
+		{"DELETE /entitlements/{id}", h.DeleteEntitlement},
+		{"GET /audit", h.ListAudit},
+		// Server-Sent Events for dashboards; resumable with Last-Event-ID
+		{"GET /events", h.StreamEvents},
+	}
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+	"github.com/AndrewCharlesHay/vest/internal/ingest"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// securityGroupColumns maps a /positions group_by value to its securities column
+var securityGroupColumns = map[string]string{
+	"issuer":      "issuer",
+	"asset_class": "asset_class",
+	"sector":      "sector",
+	"currency":    "currency",
+	"cusip":       "cusip",
+	"isin":        "isin",
+	"sedol":       "sedol",
+}
+
+// UnclassifiedGroup is the allocation key for tickers missing from the security master
+const UnclassifiedGroup = "UNCLASSIFIED"
+
+const securityColumns = `
+	ticker, COALESCE(cusip, ''), COALESCE(isin, ''), COALESCE(sedol, ''), COALESCE(issuer, ''),
+	COALESCE(asset_class, ''), COALESCE(sector, ''), COALESCE(currency, ''), multiplier, updated_at
+`
+
+func scanSecurity(row rowScanner) (*models.Security, error) {
+	var s models.Security
+	err := row.Scan(&s.Ticker, &s.CUSIP, &s.ISIN, &s.SEDOL, &s.Issuer, &s.AssetClass, &s.Sector,
+		&s.Currency, &s.Multiplier, &s.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// ListSecurities returns the security master, optionally filtered by attribute
+func (h *Handler) ListSecurities(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	securities, err := h.QuerySecurities(r.Context(), q.Get("asset_class"), q.Get("sector"), q.Get("issuer"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, securities)
+}
+
+// GetSecurity looks a security up by ticker, CUSIP, ISIN or SEDOL
+func (h *Handler) GetSecurity(w http.ResponseWriter, r *http.Request) {
+	s, err := h.QuerySecurity(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeQueryError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, s)
+}
+
+// QuerySecurities lists the security master. Empty filters are ignored.
+func (h *Handler) QuerySecurities(ctx context.Context, assetClass, sector, issuer string) ([]models.Security, error) {
+	rows, err := h.DB.QueryContext(ctx, `SELECT `+securityColumns+`
+		FROM securities
+		WHERE ($1 = '' OR asset_class = $1)
+		  AND ($2 = '' OR sector = $2)
+		  AND ($3 = '' OR issuer = $3)
+		ORDER BY ticker
+	`, assetClass, sector, issuer)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	securities := []models.Security{}
+	for rows.Next() {
+		s, err := scanSecurity(rows)
+		if err != nil {
+			return nil, err
+		}
+		securities = append(securities, *s)
+	}
+	return securities, rows.Err()
+}
+
+// QuerySecurity looks a security up by ticker, CUSIP, ISIN or SEDOL
+func (h *Handler) QuerySecurity(ctx context.Context, id string) (*models.Security, error) {
+	s, err := scanSecurity(h.DB.QueryRowContext(ctx, `SELECT `+securityColumns+`
+		FROM securities
+		WHERE $1 IN (ticker, cusip, isin, sedol)
+		ORDER BY ticker = $1 DESC
+		LIMIT 1
+	`, id))
+	if err == sql.ErrNoRows {
+		return nil, fmt.Errorf("security %s %w", id, ErrNotFound)
+	}
+	return s, err
+}
+
+// PutSecurity creates or updates the security for a ticker
+func (h *Handler) PutSecurity(w http.ResponseWriter, r *http.Request) {
+	var s models.Security
+	if err := json.NewDecoder(r.Body).Decode(&s); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	s.Ticker = r.PathValue("id")
+	s.Currency = strings.ToUpper(s.Currency)
+	if s.Multiplier == 0 {
+		s.Multiplier = 1
+	}
+
+	_, err := h.DB.ExecContext(r.Context(), ingest.UpsertSecuritySQL, s.Ticker, s.CUSIP, s.ISIN, s.SEDOL, s.Issuer, s.AssetClass,
+		s.Sector, s.Currency, s.Multiplier)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.bumpReference(r.Context())
+	saved, err := scanSecurity(h.DB.QueryRowContext(r.Context(), `SELECT `+securityColumns+` FROM securities WHERE ticker = $1`, s.Ticker))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, http.StatusOK, saved)
+}
+
+func (h *Handler) DeleteSecurity(w http.ResponseWriter, r *http.Request) {
+	res, err := h.DB.ExecContext(r.Context(), `DELETE FROM securities WHERE ticker = $1`, r.PathValue("id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		http.Error(w, "security not found", http.StatusNotFound)
+		return
+	}
+	h.bumpReference(r.Context())
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// bumpReference marks cached responses stale after a security master or group change
+func (h *Handler) bumpReference(ctx context.Context) {
+	// The change is committed, so bump even if the client has gone
+	if err := cache.Bump(context.WithoutCancel(ctx), h.DB, cache.ReferenceScope); err != nil {
+		logger.ErrorContext(ctx, "Failed to bump reference data version", "error", err)
+	}
+}
+
+// securityGroups maps each ticker to its value for a security master column
+func (h *Handler) securityGroups(ctx context.Context, column string) (map[string]string, error) {
+	rows, err := h.DB.QueryContext(ctx, `SELECT ticker, COALESCE(`+column+`, '') FROM securities`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	groups := make(map[string]string)
+	for rows.Next() {
+		var ticker, value string
+		if err := rows.Scan(&ticker, &value); err != nil {
+			return nil, err
+		}
+		if value != "" {
+			groups[ticker] = value
+		}
+	}
+	return groups, rows.Err()
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = tracing.Tracer("api")
+
+// startQuery starts a span for one of the shared read queries. Account IDs
+// are left out, as they are from metrics.
+func startQuery(ctx context.Context, name string, f Filter, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	attrs = append(attrs,
+		attribute.String("vest.date", f.Date),
+		attribute.Bool("vest.single_account", f.AccountID != ""),
+		attribute.String("vest.group", f.Group),
+	)
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// endQuery records how many rows a query returned and ends its span
+func endQuery(span trace.Span, rows int, err error) {
+	span.SetAttributes(attribute.Int("vest.rows", rows))
+	tracing.End(span, err)
+}
+
+// encodeJSON writes v to w within its own span, so the time spent encoding a
+// large response shows apart from its query. A failed write leaves nothing
+// to report to the client, who has already been sent part of the body.
+func encodeJSON(ctx context.Context, w io.Writer, v any) {
+	_, span := tracer.Start(ctx, "json.encode")
+	tracing.End(span, json.NewEncoder(w).Encode(v))
+}
@@ -0,0 +1,219 @@
+// Package audit keeps an append-only log of API access and data changes.
+// Each entry carries the SHA-256 of the previous one, so editing or deleting
+// a row breaks the chain and Verify reports where.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+var logger = logging.Component("audit")
+
+// Entry kinds
+const (
+	KindAccess = "access"
+	KindChange = "change"
+)
+
+// GenesisHash is the PrevHash of the first entry
+var GenesisHash = strings.Repeat("0", 64)
+
+// lockID serializes appends across server instances so the chain stays linear
+const lockID = 0x76657374_61756474 // "vestaudt"
+
+// queueSize is how many entries may wait for the writer before Record
+// appends synchronously instead
+const queueSize = 4096
+
+// batchSize caps how many queued entries one transaction appends
+const batchSize = 200
+
+var ErrInvalidFilter = errors.New("invalid audit filter")
+
+// Logger appends entries to the audit_log table. Record queues entries for
+// the background writer started by Start.
+type Logger struct {
+	DB *sql.DB
+
+	queue chan models.AuditEntry
+}
+
+func NewLogger(db *sql.DB) *Logger {
+	return &Logger{DB: db, queue: make(chan models.AuditEntry, queueSize)}
+}
+
+// Record queues an entry. When the writer is behind it appends directly, so
+// entries are slowed down rather than dropped.
+func (l *Logger) Record(e models.AuditEntry) {
+	if l == nil {
+		return
+	}
+	stamp(&e)
+	select {
+	case l.queue <- e:
+	default:
+		l.write(context.Background(), []models.AuditEntry{e})
+	}
+}
+
+// Change appends a change entry before returning
+func (l *Logger) Change(ctx context.Context, principal, action string, accounts []string, detail any) error {
+	if l == nil {
+		return nil
+	}
+	data, err := json.Marshal(detail)
+	if err != nil {
+		return err
+	}
+	e := models.AuditEntry{Kind: KindChange, Principal: principal, Action: action, Accounts: accounts, Detail: data}
+	stamp(&e)
+	return l.Append(ctx, []models.AuditEntry{e})
+}
+
+// Start writes queued entries until ctx is cancelled, then drains the queue
+func (l *Logger) Start(ctx context.Context) {
+	// Entries already queued are written even after ctx is cancelled
+	writeCtx := context.WithoutCancel(ctx)
+	for {
+		select {
+		case <-ctx.Done():
+			for {
+				batch := l.drain(nil)
+				if len(batch) == 0 {
+					return
+				}
+				l.write(writeCtx, batch)
+			}
+		case e := <-l.queue:
+			l.write(writeCtx, l.drain([]models.AuditEntry{e}))
+		}
+	}
+}
+
+// drain adds whatever is already queued to batch, up to batchSize
+func (l *Logger) drain(batch []models.AuditEntry) []models.AuditEntry {
+	for len(batch) < batchSize {
+		select {
+		case e := <-l.queue:
+			batch = append(batch, e)
+		default:
+			return batch
+		}
+	}
+	return batch
+}
+
+// write appends entries, logging them in full if the database is unavailable
+// so the record survives in the process logs
+func (l *Logger) write(ctx context.Context, batch []models.AuditEntry) {
+	if err := l.Append(ctx, batch); err != nil {
+		for _, e := range batch {
+			data, _ := json.Marshal(e)
+			logger.ErrorContext(ctx, "Failed to write audit entry", "error", err, "entry", string(data))
+		}
+	}
+}
+
+// Append chains entries onto the log in one transaction
+func (l *Logger) Append(ctx context.Context, entries []models.AuditEntry) error {
+	tx, err := l.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, int64(lockID)); err != nil {
+		return err
+	}
+	prev := GenesisHash
+	if err := tx.QueryRowContext(ctx, `SELECT hash FROM audit_log ORDER BY id DESC LIMIT 1`).Scan(&prev); err != nil && err != sql.ErrNoRows {
+		return err
+	}
+	for _, e := range entries {
+		stamp(&e)
+		e.PrevHash = prev
+		e.Hash = Hash(e)
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO audit_log (created_at, kind, principal, method, route, params, accounts, status, action, detail, prev_hash, hash)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`,
+			e.CreatedAt, e.Kind, e.Principal, e.Method, e.Route, string(e.Params), e.Accounts,
+			e.Status, e.Action, string(e.Detail), e.PrevHash, e.Hash); err != nil {
+			return err
+		}
+		prev = e.Hash
+	}
+	return tx.Commit()
+}
+
+// stamp fills in the time and normalizes fields to how they read back from
+// Postgres, so the hash computed on insert matches the one Verify computes
+func stamp(e *models.AuditEntry) {
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	e.CreatedAt = e.CreatedAt.UTC().Truncate(time.Microsecond)
+	e.Params = compact(e.Params)
+	e.Detail = compact(e.Detail)
+	e.Accounts = uniqueSorted(e.Accounts)
+}
+
+func compact(raw json.RawMessage) json.RawMessage {
+	var buf bytes.Buffer
+	if len(raw) == 0 || json.Compact(&buf, raw) != nil {
+		return json.RawMessage("null")
+	}
+	return buf.Bytes()
+}
+
+func uniqueSorted(ids []string) []string {
+	out := make([]string, 0, len(ids))
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			out = append(out, id)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+// Hash is the SHA-256 over the previous hash and every recorded field
+func Hash(e models.AuditEntry) string {
+	h := sha256.New()
+	for _, field := range []string{
+		e.PrevHash,
+		e.CreatedAt.UTC().Format(time.RFC3339Nano),
+		e.Kind,
+		e.Principal,
+		e.Method,
+		e.Route,
+		string(e.Params),
+		strings.Join(e.Accounts, ","),
+		strconv.Itoa(e.Status),
+		e.Action,
+		string(e.Detail),
+	} {
+		// Length-prefixed so fields cannot run into each other
+		fmt.Fprintf(h, "%d:%s\n", len(field), field)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
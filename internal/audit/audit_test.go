@@ -0,0 +1,80 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+func TestHashCoversEveryField(t *testing.T) {
+	base := models.AuditEntry{
+		CreatedAt: time.Date(2025, 1, 15, 9, 30, 0, 123456000, time.UTC),
+		Kind:      KindAccess,
+		Principal: "key:12",
+		Method:    "GET",
+		Route:     "/positions",
+		Params:    json.RawMessage(`{"query":{"date":["2025-01-15"]}}`),
+		Accounts:  []string{"ACC001", "ACC002"},
+		Status:    200,
+		Detail:    json.RawMessage(`null`),
+		PrevHash:  GenesisHash,
+	}
+	want := Hash(base)
+	if Hash(base) != want || len(want) != 64 {
+		t.Fatalf("hash must be a stable hex SHA-256, got %q", want)
+	}
+
+	changes := map[string]func(e *models.AuditEntry){
+		"time":      func(e *models.AuditEntry) { e.CreatedAt = e.CreatedAt.Add(time.Microsecond) },
+		"principal": func(e *models.AuditEntry) { e.Principal = "key:13" },
+		"route":     func(e *models.AuditEntry) { e.Route = "/blotter" },
+		"params":    func(e *models.AuditEntry) { e.Params = json.RawMessage(`{}`) },
+		"accounts":  func(e *models.AuditEntry) { e.Accounts = []string{"ACC001"} },
+		"status":    func(e *models.AuditEntry) { e.Status = 403 },
+		"prev":      func(e *models.AuditEntry) { e.PrevHash = want },
+		// Length prefixes keep field boundaries fixed
+		"boundary": func(e *models.AuditEntry) { e.Kind, e.Principal = KindAccess+"key:12", "" },
+	}
+	for name, change := range changes {
+		e := base
+		change(&e)
+		if Hash(e) == want {
+			t.Errorf("changing %s did not change the hash", name)
+		}
+	}
+}
+
+func TestStampNormalizes(t *testing.T) {
+	e := models.AuditEntry{
+		CreatedAt: time.Date(2025, 1, 15, 9, 30, 0, 123456789, time.FixedZone("EST", -5*3600)),
+		Params:    json.RawMessage("{ \"a\": 1 }"),
+		Accounts:  []string{"ACC002", "", "ACC001", "ACC002"},
+	}
+	stamp(&e)
+	if e.CreatedAt.Location() != time.UTC || e.CreatedAt.Nanosecond() != 123456000 {
+		t.Errorf("expected UTC truncated to microseconds like Postgres, got %v", e.CreatedAt)
+	}
+	if string(e.Params) != `{"a":1}` || string(e.Detail) != "null" {
+		t.Errorf("expected compact JSON, got %s and %s", e.Params, e.Detail)
+	}
+	if len(e.Accounts) != 2 || e.Accounts[0] != "ACC001" {
+		t.Errorf("expected sorted unique accounts, got %v", e.Accounts)
+	}
+}
+
+func TestNotes(t *testing.T) {
+	Note(context.Background(), "ACC001") // Outside an audited request: ignored
+	if got := Noted(context.Background()); got != nil {
+		t.Errorf("expected nothing noted, got %v", got)
+	}
+
+	ctx := WithNotes(context.Background())
+	Note(ctx, "ACC002", "ACC001")
+	Note(ctx, "ACC002", "")
+	if got := Noted(ctx); len(got) != 2 || got[0] != "ACC001" || got[1] != "ACC002" {
+		t.Errorf("expected [ACC001 ACC002], got %v", got)
+	}
+}
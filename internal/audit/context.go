@@ -0,0 +1,56 @@
+package audit
+
+import (
+	"context"
+	"sync"
+)
+
+// notes collects the accounts a request returned. The query layer adds to it
+// as rows are read, so REST, GraphQL and gRPC are covered alike.
+type notes struct {
+	mu       sync.Mutex
+	accounts map[string]struct{}
+}
+
+type notesKey struct{}
+
+// WithNotes returns a context that collects accounts noted during a request.
+// A context already collecting is returned as it is, so outer middleware
+// sees the same accounts.
+func WithNotes(ctx context.Context) context.Context {
+	if _, ok := ctx.Value(notesKey{}).(*notes); ok {
+		return ctx
+	}
+	return context.WithValue(ctx, notesKey{}, &notes{accounts: make(map[string]struct{})})
+}
+
+// Note records that data for accounts is being returned. It does nothing
+// outside an audited request.
+func Note(ctx context.Context, accounts ...string) {
+	n, ok := ctx.Value(notesKey{}).(*notes)
+	if !ok {
+		return
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	for _, id := range accounts {
+		if id != "" {
+			n.accounts[id] = struct{}{}
+		}
+	}
+}
+
+// Noted returns the accounts noted so far, sorted
+func Noted(ctx context.Context) []string {
+	n, ok := ctx.Value(notesKey{}).(*notes)
+	if !ok {
+		return nil
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	ids := make([]string, 0, len(n.accounts))
+	for id := range n.accounts {
+		ids = append(ids, id)
+	}
+	return uniqueSorted(ids)
+}
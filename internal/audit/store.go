@@ -0,0 +1,142 @@
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// DefaultLimit and MaxLimit bound a page of Query results
+const (
+	DefaultLimit = 100
+	MaxLimit     = 1000
+)
+
+const entryColumns = `id, created_at, kind, principal, method, route, params::text,
+	array_to_string(accounts, ','), status, action, detail::text, prev_hash, hash`
+
+// Filter narrows an audit query. Empty fields are ignored.
+type Filter struct {
+	Principal string
+	AccountID string
+	Kind      string
+	Route     string // Path prefix
+	From      time.Time
+	To        time.Time
+	Before    int64 // Only entries with a lower id, for paging
+	Limit     int
+}
+
+// Query returns matching entries, newest first
+func (l *Logger) Query(ctx context.Context, f Filter) ([]models.AuditEntry, error) {
+	if f.Kind != "" && f.Kind != KindAccess && f.Kind != KindChange {
+		return nil, fmt.Errorf("%w: kind must be %s or %s", ErrInvalidFilter, KindAccess, KindChange)
+	}
+	if f.Limit <= 0 {
+		f.Limit = DefaultLimit
+	}
+	if f.Limit > MaxLimit {
+		return nil, fmt.Errorf("%w: limit must be at most %d", ErrInvalidFilter, MaxLimit)
+	}
+	var from, to *time.Time
+	if !f.From.IsZero() {
+		from = &f.From
+	}
+	if !f.To.IsZero() {
+		to = &f.To
+	}
+	rows, err := l.DB.QueryContext(ctx, `SELECT `+entryColumns+`
+		FROM audit_log
+		WHERE ($1 = '' OR principal = $1)
+		  AND ($2 = '' OR $2 = ANY(accounts))
+		  AND ($3 = '' OR kind = $3)
+		  AND ($4 = '' OR route LIKE $4 || '%')
+		  AND ($5::timestamptz IS NULL OR created_at >= $5)
+		  AND ($6::timestamptz IS NULL OR created_at < $6)
+		  AND ($7 = 0 OR id < $7)
+		ORDER BY id DESC
+		LIMIT $8
+	`, f.Principal, f.AccountID, f.Kind, escapeLike(f.Route), from, to, f.Before, f.Limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := []models.AuditEntry{}
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
+
+func scanEntry(rows *sql.Rows) (models.AuditEntry, error) {
+	var e models.AuditEntry
+	var params, accounts, detail string
+	if err := rows.Scan(&e.ID, &e.CreatedAt, &e.Kind, &e.Principal, &e.Method, &e.Route, &params,
+		&accounts, &e.Status, &e.Action, &detail, &e.PrevHash, &e.Hash); err != nil {
+		return e, err
+	}
+	e.Params = json.RawMessage(params)
+	e.Detail = json.RawMessage(detail)
+	e.Accounts = []string{}
+	if accounts != "" {
+		e.Accounts = strings.Split(accounts, ",")
+	}
+	return e, nil
+}
+
+// VerifyResult reports how much of the chain checked out
+type VerifyResult struct {
+	Entries  int64  `json:"entries"`
+	Head     string `json:"head"`                // Hash of the last entry; record it elsewhere to detect truncation
+	BrokenAt int64  `json:"broken_at,omitempty"` // First entry that does not match
+	Reason   string `json:"reason,omitempty"`
+}
+
+// OK reports whether every entry matched
+func (r VerifyResult) OK() bool {
+	return r.BrokenAt == 0
+}
+
+// Verify walks the whole log in order, recomputing each hash and checking it
+// links to the entry before
+func (l *Logger) Verify(ctx context.Context) (VerifyResult, error) {
+	res := VerifyResult{Head: GenesisHash}
+	rows, err := l.DB.QueryContext(ctx, `SELECT `+entryColumns+` FROM audit_log ORDER BY id`)
+	if err != nil {
+		return res, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		e, err := scanEntry(rows)
+		if err != nil {
+			return res, err
+		}
+		switch {
+		case e.PrevHash != res.Head:
+			res.BrokenAt, res.Reason = e.ID, "previous hash does not match the entry before; an entry was removed or reordered"
+		case Hash(e) != e.Hash:
+			res.BrokenAt, res.Reason = e.ID, "hash does not match the entry's contents; it was modified"
+		}
+		if !res.OK() {
+			return res, nil
+		}
+		res.Entries++
+		res.Head = e.Hash
+	}
+	return res, rows.Err()
+}
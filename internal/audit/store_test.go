@@ -0,0 +1,84 @@
+package audit_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+func TestChainVerifiesAndDetectsTampering(t *testing.T) {
+	db := testdb.Open(t)
+	l := audit.NewLogger(db)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		l.Start(ctx)
+		close(done)
+	}()
+	for _, account := range []string{"ACC-A1", "ACC-B1", "ACC-A1"} {
+		l.Record(models.AuditEntry{
+			Kind:      audit.KindAccess,
+			Principal: "key:1",
+			Method:    "GET",
+			Route:     "/accounts/" + account,
+			Params:    json.RawMessage(`{"query": {"date": ["2025-01-15"]}}`),
+			Accounts:  []string{account},
+			Status:    200,
+		})
+	}
+	if err := l.Change(ctx, "ingestor", "file.ingested", nil, map[string]any{"file": "positions_20250115.csv", "rows": 3}); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	<-done // Start drains the queue before returning
+
+	res, err := l.Verify(context.Background())
+	if err != nil || !res.OK() || res.Entries != 4 {
+		t.Fatalf("expected an intact chain of 4, got %+v, %v", res, err)
+	}
+
+	entries, err := l.Query(context.Background(), audit.Filter{AccountID: "ACC-A1"})
+	if err != nil || len(entries) != 2 || entries[0].ID < entries[1].ID {
+		t.Fatalf("expected both ACC-A1 reads, newest first, got %+v, %v", entries, err)
+	}
+	if entries, _ := l.Query(context.Background(), audit.Filter{Kind: audit.KindChange}); len(entries) != 1 || entries[0].Principal != "ingestor" {
+		t.Errorf("expected the ingestion change, got %+v", entries)
+	}
+	if entries, _ := l.Query(context.Background(), audit.Filter{Route: "/accounts/ACC-B", From: time.Now().Add(-time.Hour)}); len(entries) != 1 {
+		t.Errorf("expected one route prefix match, got %+v", entries)
+	}
+	if entries, _ := l.Query(context.Background(), audit.Filter{Limit: 2}); len(entries) != 2 {
+		t.Errorf("expected a page of 2, got %d", len(entries))
+	}
+
+	if _, err := db.Exec(`UPDATE audit_log SET status = 403 WHERE id = $1`, entries[0].ID); err == nil {
+		t.Fatal("expected the trigger to reject updates")
+	}
+	if _, err := db.Exec(`DELETE FROM audit_log`); err == nil {
+		t.Fatal("expected the trigger to reject deletes")
+	}
+
+	// Someone with rights to drop the trigger can still edit rows, but not unnoticed
+	if _, err := db.Exec(`ALTER TABLE audit_log DISABLE TRIGGER audit_log_no_update`); err != nil {
+		t.Fatal(err)
+	}
+	first, _ := l.Query(context.Background(), audit.Filter{Route: "/accounts/ACC-B1"})
+	if _, err := db.Exec(`UPDATE audit_log SET principal = 'key:2' WHERE id = $1`, first[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := l.Verify(context.Background()); err != nil || res.BrokenAt != first[0].ID {
+		t.Errorf("expected the edit to break the chain at %d, got %+v, %v", first[0].ID, res, err)
+	}
+	if _, err := db.Exec(`DELETE FROM audit_log WHERE id = $1`, first[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if res, err := l.Verify(context.Background()); err != nil || res.BrokenAt != first[0].ID+1 {
+		t.Errorf("expected the deletion to break the chain at the next entry, got %+v, %v", res, err)
+	}
+}
@@ -0,0 +1,222 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+var ErrUnknownKey = errors.New("signing key not found in JWKS")
+
+// JWKS caches the signing keys published by an identity provider. Source is
+// an http(s) URL or a local file path. Keys are reloaded every
+// RefreshInterval, and early when a token names a key id not yet seen, so
+// provider key rotation is picked up without a restart.
+type JWKS struct {
+	Source          string
+	RefreshInterval time.Duration
+	Client          *http.Client
+
+	mu        sync.Mutex // Guards keys and fetchedAt, never held while fetching
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	fetches   singleflight.Group
+}
+
+// minRefetch limits how often an unknown kid can force a reload
+const minRefetch = 30 * time.Second
+
+func NewJWKS(source string) *JWKS {
+	return &JWKS{
+		Source:          source,
+		RefreshInterval: time.Hour,
+		Client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Key returns the public key for a key id. An empty kid matches when the set
+// holds exactly one key.
+func (j *JWKS) Key(kid string) (crypto.PublicKey, error) {
+	keys, fetchedAt := j.cached()
+	if keys == nil || time.Since(fetchedAt) > j.RefreshInterval {
+		fresh, err := j.load()
+		if err != nil && keys == nil {
+			return nil, err
+		}
+		if err == nil {
+			keys = fresh
+		}
+	}
+	if key, ok := lookup(keys, kid); ok {
+		return key, nil
+	}
+	// Possibly a newly rotated key
+	if _, fetchedAt = j.cached(); time.Since(fetchedAt) > minRefetch {
+		keys, err := j.load()
+		if err != nil {
+			return nil, err
+		}
+		if key, ok := lookup(keys, kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: kid %q", ErrUnknownKey, kid)
+}
+
+func (j *JWKS) cached() (map[string]crypto.PublicKey, time.Time) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.keys, j.fetchedAt
+}
+
+func lookup(keys map[string]crypto.PublicKey, kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key, true
+		}
+	}
+	key, ok := keys[kid]
+	return key, ok
+}
+
+// load fetches and parses the key set, sharing one fetch between concurrent
+// callers. The map is replaced rather than modified, so keys already handed
+// out stay valid. A failed reload keeps the previous keys.
+func (j *JWKS) load() (map[string]crypto.PublicKey, error) {
+	v, err, _ := j.fetches.Do("", func() (any, error) {
+		j.mu.Lock()
+		j.fetchedAt = time.Now()
+		j.mu.Unlock()
+
+		data, err := j.read()
+		if err != nil {
+			return nil, fmt.Errorf("loading JWKS from %s: %w", j.Source, err)
+		}
+		keys, err := ParseJWKS(data)
+		if err != nil {
+			return nil, fmt.Errorf("parsing JWKS from %s: %w", j.Source, err)
+		}
+		j.mu.Lock()
+		j.keys = keys
+		j.mu.Unlock()
+		return keys, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(map[string]crypto.PublicKey), nil
+}
+
+func (j *JWKS) read() ([]byte, error) {
+	if !strings.HasPrefix(j.Source, "http://") && !strings.HasPrefix(j.Source, "https://") {
+		return os.ReadFile(j.Source)
+	}
+	resp, err := j.Client.Get(j.Source)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// ParseJWKS decodes the RSA and EC P-256 signing keys in a JWK Set
+func ParseJWKS(data []byte) (map[string]crypto.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]crypto.PublicKey)
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			n, err := decodeBigInt(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: n: %w", k.Kid, err)
+			}
+			e, err := decodeBigInt(k.E)
+			if err != nil || !e.IsInt64() {
+				return nil, fmt.Errorf("key %q: invalid exponent", k.Kid)
+			}
+			keys[k.Kid] = &rsa.PublicKey{N: n, E: int(e.Int64())}
+		case "EC":
+			if k.Crv != "P-256" {
+				continue
+			}
+			x, err := decodeBigInt(k.X)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: x: %w", k.Kid, err)
+			}
+			y, err := decodeBigInt(k.Y)
+			if err != nil {
+				return nil, fmt.Errorf("key %q: y: %w", k.Kid, err)
+			}
+			if !onP256(x, y) {
+				return nil, fmt.Errorf("key %q: point is not on P-256", k.Kid)
+			}
+			keys[k.Kid] = &ecdsa.PublicKey{Curve: elliptic.P256(), X: x, Y: y}
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no RSA or P-256 signing keys")
+	}
+	return keys, nil
+}
+
+// onP256 validates an uncompressed point through crypto/ecdh
+func onP256(x, y *big.Int) bool {
+	if x.BitLen() > 256 || y.BitLen() > 256 {
+		return false
+	}
+	point := make([]byte, 65)
+	point[0] = 4
+	x.FillBytes(point[1:33])
+	y.FillBytes(point[33:])
+	_, err := ecdh.P256().NewPublicKey(point)
+	return err == nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, errors.New("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
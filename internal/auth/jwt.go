@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Verifier validates OIDC bearer tokens: RS256 or ES256 signatures against
+// the provider's JWKS, plus issuer, audience and expiry
+type Verifier struct {
+	Issuer     string
+	Audience   string
+	Keys       *JWKS
+	RolesClaim string              // Dotted path to the roles claim, e.g. realm_access.roles
+	RoleScopes map[string][]string // IdP role -> scopes; nil maps roles named read/ingest/admin directly
+	Leeway     time.Duration       // Allowed clock skew
+}
+
+func NewVerifier(issuer, audience string, keys *JWKS) *Verifier {
+	return &Verifier{
+		Issuer:     issuer,
+		Audience:   audience,
+		Keys:       keys,
+		RolesClaim: "roles",
+		Leeway:     30 * time.Second,
+	}
+}
+
+// Authenticate validates a bearer token and maps its roles to scopes
+func (v *Verifier) Authenticate(token string) (*Principal, error) {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return v.Keys.Key(kid)
+	},
+		jwt.WithValidMethods([]string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(v.Issuer),
+		jwt.WithAudience(v.Audience),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.Leeway),
+	)
+	if err != nil {
+		// An unreachable JWKS is our fault, not the caller's
+		if errors.Is(err, jwt.ErrTokenUnverifiable) && !errors.Is(err, ErrUnknownKey) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+	}
+
+	sub, _ := claims["sub"].(string)
+	if sub == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrUnauthenticated)
+	}
+	p := &Principal{Name: sub, Subject: sub, Roles: stringsClaim(claims, v.RolesClaim)}
+	for _, c := range []string{"preferred_username", "email"} {
+		if name, ok := claims[c].(string); ok && name != "" {
+			p.Name = name
+			break
+		}
+	}
+	p.Scopes = v.scopesFor(p.Roles)
+	return p, nil
+}
+
+func (v *Verifier) scopesFor(roles []string) []string {
+	seen := make(map[string]bool)
+	var scopes []string
+	for _, role := range roles {
+		mapped := []string{role}
+		if v.RoleScopes != nil {
+			mapped = v.RoleScopes[role]
+		}
+		for _, s := range mapped {
+			if ValidScope(s) && !seen[s] {
+				seen[s] = true
+				scopes = append(scopes, s)
+			}
+		}
+	}
+	return scopes
+}
+
+// stringsClaim reads a dotted claim path holding a string array or a
+// space-separated string (as OAuth scope claims are)
+func stringsClaim(claims map[string]any, path string) []string {
+	var node any = claims
+	for _, part := range strings.Split(path, ".") {
+		m, ok := node.(map[string]any)
+		if !ok {
+			return nil
+		}
+		node = m[part]
+	}
+	switch val := node.(type) {
+	case string:
+		return strings.Fields(val)
+	case []any:
+		var out []string
+		for _, item := range val {
+			if s, ok := item.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// ParseRoleScopes reads a role mapping such as "vest-admins=admin,analysts=read"
+func ParseRoleScopes(s string) (map[string][]string, error) {
+	m := make(map[string][]string)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		role, scope, ok := strings.Cut(pair, "=")
+		if !ok || role == "" || !ValidScope(scope) {
+			return nil, fmt.Errorf("%w: %q (want role=read|ingest|admin)", ErrInvalidScope, pair)
+		}
+		m[role] = append(m[role], scope)
+	}
+	return m, nil
+}
+
+// Authenticator accepts either an API key or, when Tokens is set, an OIDC bearer token
+type Authenticator struct {
+	Keys   *KeyStore
+	Tokens *Verifier
+}
+
+// ErrBearerUnsupported is returned for bearer tokens when OIDC is not configured
+var ErrBearerUnsupported = errors.New("bearer tokens are not enabled")
+
+// Authenticate resolves the X-API-Key value or Authorization header to a
+// principal. A bearer token takes precedence when both are sent.
+func (a *Authenticator) Authenticate(ctx context.Context, apiKey, authorization string) (p *Principal, err error) {
+	ctx, span := tracer.Start(ctx, "auth.authenticate")
+	defer func() {
+		if p != nil {
+			span.SetAttributes(attribute.String("auth.principal", p.Name))
+		}
+		tracing.End(span, err)
+	}()
+
+	if scheme, token, ok := strings.Cut(authorization, " "); ok && strings.EqualFold(scheme, "Bearer") {
+		span.SetAttributes(attribute.String("auth.method", "bearer"))
+		if a.Tokens == nil {
+			return nil, fmt.Errorf("%w: %w", ErrUnauthenticated, ErrBearerUnsupported)
+		}
+		return a.Tokens.Authenticate(strings.TrimSpace(token))
+	}
+	span.SetAttributes(attribute.String("auth.method", "api_key"))
+	return a.Keys.Authenticate(ctx, apiKey)
+}
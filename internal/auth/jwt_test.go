@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://idp.example.com/realms/vest"
+	testAudience = "vest-api"
+)
+
+func b64(b []byte) string { return base64.RawURLEncoding.EncodeToString(b) }
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": b64(key.N.Bytes()), "e": b64(big.NewInt(int64(key.E)).Bytes())}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": "P-256", "x": b64(key.X.FillBytes(make([]byte, 32))), "y": b64(key.Y.FillBytes(make([]byte, 32)))}
+}
+
+func writeJWKS(t *testing.T, path string, keys ...map[string]string) {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(method, claims)
+	tok.Header["kid"] = kid
+	s, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":                testIssuer,
+		"aud":                testAudience,
+		"sub":                "user-123",
+		"preferred_username": "jdoe",
+		"exp":                time.Now().Add(time.Hour).Unix(),
+		"realm_access":       map[string]any{"roles": []string{"vest-analysts", "offline_access"}},
+	}
+}
+
+func TestVerifier(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	writeJWKS(t, path, rsaJWK("rsa-1", &rsaKey.PublicKey), ecJWK("ec-1", &ecKey.PublicKey))
+
+	v := NewVerifier(testIssuer, testAudience, NewJWKS(path))
+	v.RolesClaim = "realm_access.roles"
+	v.RoleScopes = map[string][]string{"vest-analysts": {ScopeRead}, "vest-ops": {ScopeRead, ScopeIngest}}
+
+	p, err := v.Authenticate(sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims()))
+	if err != nil {
+		t.Fatalf("RS256: %v", err)
+	}
+	if p.Name != "jdoe" || p.Subject != "user-123" || !p.Can(ScopeRead) || p.Can(ScopeIngest) {
+		t.Errorf("unexpected principal %+v", p)
+	}
+	if _, err := v.Authenticate(sign(t, jwt.SigningMethodES256, "ec-1", ecKey, validClaims())); err != nil {
+		t.Errorf("ES256: %v", err)
+	}
+
+	reject := map[string]string{}
+	claims := validClaims()
+	claims["iss"] = "https://evil.example.com"
+	reject["wrong issuer"] = sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims)
+	claims = validClaims()
+	claims["aud"] = "another-api"
+	reject["wrong audience"] = sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims)
+	claims = validClaims()
+	claims["exp"] = time.Now().Add(-time.Hour).Unix()
+	reject["expired"] = sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims)
+	claims = validClaims()
+	delete(claims, "exp")
+	reject["no expiry"] = sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, claims)
+	reject["HS256"] = sign(t, jwt.SigningMethodHS256, "rsa-1", []byte("shared"), validClaims())
+	otherKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	reject["wrong key"] = sign(t, jwt.SigningMethodRS256, "rsa-1", otherKey, validClaims())
+	reject["unknown kid"] = sign(t, jwt.SigningMethodRS256, "rsa-9", rsaKey, validClaims())
+	reject["garbage"] = "not.a.jwt"
+
+	for name, token := range reject {
+		if _, err := v.Authenticate(token); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%s: expected ErrUnauthenticated, got %v", name, err)
+		}
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	newKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	current := []map[string]string{rsaJWK("2025-01", &oldKey.PublicKey)}
+	fetches := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": current})
+	}))
+	defer srv.Close()
+
+	v := NewVerifier(testIssuer, testAudience, NewJWKS(srv.URL))
+	if _, err := v.Authenticate(sign(t, jwt.SigningMethodRS256, "2025-01", oldKey, validClaims())); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Authenticate(sign(t, jwt.SigningMethodRS256, "2025-01", oldKey, validClaims())); err != nil || fetches != 1 {
+		t.Fatalf("expected cached keys, fetches=%d err=%v", fetches, err)
+	}
+
+	// The provider rotates; a token with the new kid triggers a reload
+	current = append(current, rsaJWK("2025-02", &newKey.PublicKey))
+	v.Keys.fetchedAt = time.Now().Add(-minRefetch - time.Second)
+	if _, err := v.Authenticate(sign(t, jwt.SigningMethodRS256, "2025-02", newKey, validClaims())); err != nil {
+		t.Fatalf("rotated key: %v", err)
+	}
+	if fetches != 2 {
+		t.Errorf("expected one reload, fetches=%d", fetches)
+	}
+}
+
+func TestJWKSReloadDoesNotBlockCachedKeys(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	current := []map[string]string{rsaJWK("2025-01", &key.PublicKey)}
+	reloading := make(chan struct{})
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches.Add(1) > 1 {
+			close(reloading)
+			<-release
+		}
+		json.NewEncoder(w).Encode(map[string]any{"keys": current})
+	}))
+	defer srv.Close()
+
+	j := NewJWKS(srv.URL)
+	if _, err := j.Key("2025-01"); err != nil {
+		t.Fatal(err)
+	}
+
+	// An unknown kid starts a reload the provider is slow to answer
+	j.fetchedAt = time.Now().Add(-minRefetch - time.Second)
+	done := make(chan error)
+	go func() {
+		_, err := j.Key("2025-02")
+		done <- err
+	}()
+	<-reloading
+
+	cached := make(chan error)
+	go func() {
+		_, err := j.Key("2025-01")
+		cached <- err
+	}()
+	select {
+	case err := <-cached:
+		if err != nil {
+			t.Errorf("cached key: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a cached key lookup waited for the reload")
+	}
+
+	close(release)
+	if err := <-done; !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected ErrUnknownKey once the reload finished, got %v", err)
+	}
+}
+
+func TestJWKSUnavailable(t *testing.T) {
+	key, _ := rsa.GenerateKey(rand.Reader, 2048)
+	v := NewVerifier(testIssuer, testAudience, NewJWKS(filepath.Join(t.TempDir(), "missing.json")))
+	_, err := v.Authenticate(sign(t, jwt.SigningMethodRS256, "k", key, validClaims()))
+	if err == nil || errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected a server-side error for a missing JWKS, got %v", err)
+	}
+}
+
+func TestScopesFor(t *testing.T) {
+	v := &Verifier{}
+	if got := v.scopesFor([]string{"read", "admin", "unrelated"}); len(got) != 2 {
+		t.Errorf("expected roles named after scopes to map directly, got %v", got)
+	}
+	roles, err := ParseRoleScopes("vest-ops=ingest, vest-ops=read,vest-admins=admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	v.RoleScopes = roles
+	if got := v.scopesFor([]string{"vest-ops", "read"}); len(got) != 2 || got[0] != ScopeIngest {
+		t.Errorf("unexpected scopes %v", got)
+	}
+	if _, err := ParseRoleScopes("vest-ops=superuser"); !errors.Is(err, ErrInvalidScope) {
+		t.Errorf("expected ErrInvalidScope, got %v", err)
+	}
+}
+
+func TestAuthenticatorBearerDisabled(t *testing.T) {
+	a := &Authenticator{Keys: &KeyStore{Bootstrap: "test-secret"}}
+	if _, err := a.Authenticate(context.Background(), "", "Bearer abc"); !errors.Is(err, ErrBearerUnsupported) || !errors.Is(err, ErrUnauthenticated) {
+		t.Errorf("expected unsupported bearer, got %v", err)
+	}
+	if _, err := a.Authenticate(context.Background(), "test-secret", ""); err != nil {
+		t.Errorf("API key path: %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+// Package auth authenticates API callers and records what they may do
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+)
+
+var (
+	logger = logging.Component("auth")
+	tracer = tracing.Tracer("auth")
+)
+
+// Scopes a key can hold. Admin implies the others.
+const (
+	ScopeRead   = "read"   // Read-only endpoints
+	ScopeIngest = "ingest" // Endpoints that change positions, reference data or alarms
+	ScopeAdmin  = "admin"  // API keys and webhooks
+)
+
+// keyPrefix marks vest API keys; the next prefixLen characters identify the row
+const (
+	keyPrefix = "vest_"
+	prefixLen = len(keyPrefix) + 12
+)
+
+var (
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrKeyNotFound     = errors.New("API key not found")
+	ErrInvalidScope    = errors.New("invalid scope")
+)
+
+// Principal is an authenticated caller: an API key or an OIDC user
+type Principal struct {
+	Name    string
+	KeyID   int64  // Set for stored API keys
+	Subject string // Set for bearer tokens
+	Roles   []string
+	Scopes  []string
+}
+
+// Can reports whether the principal holds a scope
+func (p *Principal) Can(scope string) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// WithPrincipal attaches the authenticated caller to a request context
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// ID names the principal in entitlements: key:<id> for stored keys and
+// user:<subject> for bearer tokens. The bootstrap key has no ID.
+func (p *Principal) ID() string {
+	switch {
+	case p.KeyID != 0:
+		return fmt.Sprintf("key:%d", p.KeyID)
+	case p.Subject != "":
+		return "user:" + p.Subject
+	}
+	return ""
+}
+
+// Label identifies the principal in rate limits and the audit log: its ID,
+// or its name for the bootstrap key
+func (p *Principal) Label() string {
+	if id := p.ID(); id != "" {
+		return id
+	}
+	return p.Name
+}
+
+// PrincipalFrom returns the authenticated caller, or nil
+func PrincipalFrom(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// ValidScope reports whether s is a known scope
+func ValidScope(s string) bool {
+	return s == ScopeRead || s == ScopeIngest || s == ScopeAdmin
+}
+
+// KeyStore verifies API keys against the api_keys table. Bootstrap, if set,
+// is accepted as an admin key for bootstrapping the first stored key.
+type KeyStore struct {
+	DB        *sql.DB
+	Bootstrap string
+}
+
+func NewKeyStore(db *sql.DB) *KeyStore {
+	return &KeyStore{DB: db}
+}
+
+// Hash returns the stored form of a key
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Authenticate resolves a presented key to its principal
+func (k *KeyStore) Authenticate(ctx context.Context, key string) (*Principal, error) {
+	if key == "" {
+		return nil, ErrUnauthenticated
+	}
+	// Compare hashes so neither the content nor the length leaks through timing
+	if k.Bootstrap != "" && subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(Hash(k.Bootstrap))) == 1 {
+		return &Principal{Name: "bootstrap", Scopes: []string{ScopeAdmin}}, nil
+	}
+	if k.DB == nil || !strings.HasPrefix(key, keyPrefix) || len(key) <= prefixLen {
+		return nil, ErrUnauthenticated
+	}
+
+	var p Principal
+	var storedHash, scopes string
+	err := k.DB.QueryRowContext(ctx, `
+		SELECT id, name, key_hash, array_to_string(scopes, ',')
+		FROM api_keys
+		WHERE prefix = $1
+		  AND revoked_at IS NULL
+		  AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+	`, key[:prefixLen]).Scan(&p.KeyID, &p.Name, &storedHash, &scopes)
+	if err == sql.ErrNoRows {
+		return nil, ErrUnauthenticated
+	}
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(Hash(key)), []byte(storedHash)) != 1 {
+		return nil, ErrUnauthenticated
+	}
+	p.Scopes = strings.Split(scopes, ",")
+
+	// Last use is tracked to the minute to avoid a write on every request
+	if _, err := k.DB.ExecContext(ctx, `
+		UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < CURRENT_TIMESTAMP - INTERVAL '1 minute')
+	`, p.KeyID); err != nil {
+		logger.WarnContext(ctx, "Failed to record use of API key", "key_id", p.KeyID, "error", err)
+	}
+	return &p, nil
+}
+
+// newKey generates a random key
+func newKey() (string, error) {
+	b := make([]byte, 30)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return keyPrefix + hex.EncodeToString(b), nil
+}
+
+// rowQuerier is satisfied by *sql.DB and *sql.Tx
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Issue creates a key. The returned record carries the plaintext key, which
+// is not stored and cannot be retrieved again.
+func (k *KeyStore) Issue(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, error) {
+	return issue(ctx, k.DB, name, scopes, expiresAt)
+}
+
+func issue(ctx context.Context, db rowQuerier, name string, scopes []string, expiresAt *time.Time) (*models.APIKey, error) {
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	for _, s := range scopes {
+		if !ValidScope(s) {
+			return nil, fmt.Errorf("%w %q (want read, ingest or admin)", ErrInvalidScope, s)
+		}
+	}
+	key, err := newKey()
+	if err != nil {
+		return nil, err
+	}
+
+	a := &models.APIKey{Name: name, Prefix: key[:prefixLen], Key: key, Scopes: scopes, ExpiresAt: expiresAt}
+	err = db.QueryRowContext(ctx, `
+		INSERT INTO api_keys (name, prefix, key_hash, scopes, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, created_at
+	`, name, a.Prefix, Hash(key), scopes, expiresAt).Scan(&a.ID, &a.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// Rotate issues a replacement for a key with the same name and scopes. The
+// old key keeps working for overlap so callers can switch without downtime.
+// Either every step lands or none does, so a failed rotation never leaves a
+// replacement without entitlements or an old key cut short.
+func (k *KeyStore) Rotate(ctx context.Context, id int64, overlap time.Duration) (*models.APIKey, error) {
+	tx, err := k.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	// The row lock keeps concurrent rotations of one key from both succeeding
+	old, err := scanKey(tx.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM api_keys WHERE id = $1 FOR UPDATE`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrKeyNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if old.RevokedAt != nil {
+		return nil, fmt.Errorf("%w: key %d is revoked", ErrKeyNotFound, id)
+	}
+	// Its replacement would inherit the expiry and be dead on arrival
+	if old.ExpiresAt != nil && !old.ExpiresAt.After(time.Now()) {
+		return nil, fmt.Errorf("%w: key %d has expired", ErrKeyNotFound, id)
+	}
+
+	fresh, err := issue(ctx, tx, old.Name, old.Scopes, old.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	// The replacement sees the same accounts
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO entitlements (principal, account_id, group_id, all_accounts)
+		SELECT $2, account_id, group_id, all_accounts FROM entitlements WHERE principal = $1
+	`, (&Principal{KeyID: id}).ID(), (&Principal{KeyID: fresh.ID}).ID()); err != nil {
+		return nil, err
+	}
+	// Never extend an earlier expiry
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE api_keys SET expires_at = LEAST(COALESCE(expires_at, $2), $2)
+		WHERE id = $1
+	`, id, time.Now().Add(overlap)); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return fresh, nil
+}
+
+// Revoke disables a key immediately
+func (k *KeyStore) Revoke(ctx context.Context, id int64) error {
+	res, err := k.DB.ExecContext(ctx, `UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP WHERE id = $1 AND revoked_at IS NULL`, id)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrKeyNotFound
+	}
+	return nil
+}
+
+const keyColumns = `id, name, prefix, array_to_string(scopes, ','), created_at, expires_at, last_used_at, revoked_at`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row rowScanner) (*models.APIKey, error) {
+	var a models.APIKey
+	var scopes string
+	var expires, lastUsed, revoked sql.NullTime
+	if err := row.Scan(&a.ID, &a.Name, &a.Prefix, &scopes, &a.CreatedAt, &expires, &lastUsed, &revoked); err != nil {
+		return nil, err
+	}
+	a.Scopes = strings.Split(scopes, ",")
+	if expires.Valid {
+		a.ExpiresAt = &expires.Time
+	}
+	if lastUsed.Valid {
+		a.LastUsedAt = &lastUsed.Time
+	}
+	if revoked.Valid {
+		a.RevokedAt = &revoked.Time
+	}
+	return &a, nil
+}
+
+// Get returns a key's metadata
+func (k *KeyStore) Get(ctx context.Context, id int64) (*models.APIKey, error) {
+	a, err := scanKey(k.DB.QueryRowContext(ctx, `SELECT `+keyColumns+` FROM api_keys WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrKeyNotFound
+	}
+	return a, err
+}
+
+// List returns every key's metadata, newest first
+func (k *KeyStore) List(ctx context.Context) ([]models.APIKey, error) {
+	rows, err := k.DB.QueryContext(ctx, `SELECT `+keyColumns+` FROM api_keys ORDER BY id DESC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []models.APIKey{}
+	for rows.Next() {
+		a, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *a)
+	}
+	return keys, rows.Err()
+}
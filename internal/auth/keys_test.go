@@ -0,0 +1,46 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestPrincipalCan(t *testing.T) {
+	reader := &Principal{Scopes: []string{ScopeRead}}
+	if !reader.Can(ScopeRead) || reader.Can(ScopeIngest) || reader.Can(ScopeAdmin) {
+		t.Errorf("read key has wrong scopes")
+	}
+	admin := &Principal{Scopes: []string{ScopeAdmin}}
+	if !admin.Can(ScopeRead) || !admin.Can(ScopeIngest) {
+		t.Errorf("admin should imply every scope")
+	}
+}
+
+func TestBootstrapKey(t *testing.T) {
+	k := &KeyStore{Bootstrap: "test-secret"}
+	p, err := k.Authenticate(context.Background(), "test-secret")
+	if err != nil || !p.Can(ScopeAdmin) {
+		t.Fatalf("expected bootstrap admin, got %v %v", p, err)
+	}
+	for _, key := range []string{"", "test-secre", "test-secret2", "vest_short"} {
+		if _, err := k.Authenticate(context.Background(), key); !errors.Is(err, ErrUnauthenticated) {
+			t.Errorf("%q: expected ErrUnauthenticated, got %v", key, err)
+		}
+	}
+}
+
+func TestNewKey(t *testing.T) {
+	a, err := newKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := newKey()
+	if a == b || !strings.HasPrefix(a, keyPrefix) || len(a) <= prefixLen {
+		t.Errorf("unexpected keys %q, %q", a, b)
+	}
+	if len(Hash(a)) != 64 {
+		t.Errorf("expected hex SHA-256, got %q", Hash(a))
+	}
+}
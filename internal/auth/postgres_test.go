@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+func TestRotate(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	k := NewKeyStore(db)
+
+	old, err := k.Issue(ctx, "desk", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO entitlements (principal, account_id) VALUES ($1, 'ACC-1')`, (&Principal{KeyID: old.ID}).ID()); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := k.Rotate(ctx, old.ID, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh.Name != "desk" || len(fresh.Scopes) != 1 || fresh.Scopes[0] != ScopeRead {
+		t.Errorf("replacement must keep the name and scopes, got %+v", fresh)
+	}
+	if p, err := k.Authenticate(ctx, fresh.Key); err != nil || p.KeyID != fresh.ID {
+		t.Errorf("replacement must authenticate, got %+v, %v", p, err)
+	}
+	if p, err := k.Authenticate(ctx, old.Key); err != nil || p.KeyID != old.ID {
+		t.Errorf("old key must keep working during the overlap, got %+v, %v", p, err)
+	}
+	var accounts int
+	if err := db.QueryRow(`SELECT count(*) FROM entitlements WHERE principal = $1 AND account_id = 'ACC-1'`, (&Principal{KeyID: fresh.ID}).ID()).Scan(&accounts); err != nil {
+		t.Fatal(err)
+	}
+	if accounts != 1 {
+		t.Errorf("expected the replacement to see ACC-1, got %d entitlements", accounts)
+	}
+	got, err := k.Get(ctx, old.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.ExpiresAt == nil || got.ExpiresAt.After(time.Now().Add(time.Hour)) {
+		t.Errorf("expected the old key to expire within the overlap, got %v", got.ExpiresAt)
+	}
+
+	if err := k.Revoke(ctx, old.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Rotate(ctx, old.ID, time.Hour); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound rotating a revoked key, got %v", err)
+	}
+	if _, err := k.Rotate(ctx, 999999, time.Hour); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound for a missing key, got %v", err)
+	}
+
+	lapsed := time.Now().Add(-time.Minute)
+	expired, err := k.Issue(ctx, "lapsed", []string{ScopeRead}, &lapsed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Rotate(ctx, expired.ID, time.Hour); !errors.Is(err, ErrKeyNotFound) {
+		t.Errorf("expected ErrKeyNotFound rotating an expired key, got %v", err)
+	}
+	if keys, err := k.List(ctx); err != nil || len(keys) != 3 {
+		t.Errorf("a refused rotation must not issue a key, got %d keys, %v", len(keys), err)
+	}
+}
+
+func TestRotateFailureChangesNothing(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	k := NewKeyStore(db)
+
+	old, err := k.Issue(ctx, "desk", []string{ScopeRead}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Copying entitlements fails after the replacement has been inserted
+	if _, err := db.Exec(`ALTER TABLE entitlements RENAME TO entitlements_moved`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Rotate(ctx, old.ID, time.Hour); err == nil {
+		t.Fatal("expected the rotation to fail")
+	}
+
+	keys, err := k.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].ID != old.ID {
+		t.Errorf("a failed rotation must not leave a replacement behind, got %+v", keys)
+	}
+	if keys[0].ExpiresAt != nil {
+		t.Errorf("a failed rotation must not expire the old key, got %v", keys[0].ExpiresAt)
+	}
+}
@@ -0,0 +1,218 @@
+package cache
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+)
+
+var logger = logging.Component("cache")
+
+// Cache holds recent responses for date-keyed reads and answers conditional
+// requests with 304 Not Modified
+type Cache struct {
+	Versions     func(ctx context.Context, date string) (Version, error)
+	MaxEntries   int
+	MaxBodyBytes int
+
+	mu      sync.Mutex
+	lru     *list.List // Front is most recently used
+	entries map[string]*list.Element
+}
+
+type entry struct {
+	key      string
+	date     string
+	etag     string
+	body     []byte
+	accounts []string // Noted for the audit log on the miss, replayed on hits
+}
+
+func NewCache(db *sql.DB) *Cache {
+	return &Cache{
+		Versions:     func(ctx context.Context, date string) (Version, error) { return Lookup(ctx, db, date) },
+		MaxEntries:   512,
+		MaxBodyBytes: 4 << 20,
+		lru:          list.New(),
+		entries:      make(map[string]*list.Element),
+	}
+}
+
+// Wrap adds ETag and Last-Modified headers to a handler that reads the date
+// query param, replying 304 or from memory while the date's version holds
+func (c *Cache) Wrap(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		date := r.URL.Query().Get("date")
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			next(w, r)
+			return
+		}
+		if _, err := time.Parse("2006-01-02", date); err != nil {
+			next(w, r)
+			return
+		}
+		v, err := c.Versions(r.Context(), date)
+		if err != nil {
+			// Serve uncached rather than fail the read
+			logger.WarnContext(r.Context(), "Data version lookup failed", "date", date, "error", err)
+			next(w, r)
+			return
+		}
+
+		key := requestKey(r)
+		etag := fmt.Sprintf(`W/"%d.%d-%x"`, v.Data, v.Reference, keyHash(key))
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Cache-Control", "private, no-cache")
+		if !v.Modified.IsZero() {
+			w.Header().Set("Last-Modified", v.Modified.Format(http.TimeFormat))
+		}
+
+		if notModified(r, etag, v.Modified) {
+			if e, ok := c.get(key, etag); ok {
+				audit.Note(r.Context(), e.accounts...)
+			}
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		if e, ok := c.get(key, etag); ok {
+			audit.Note(r.Context(), e.accounts...)
+			if _, err := w.Write(e.body); err != nil {
+				logger.WarnContext(r.Context(), "Cached response write failed", "error", err)
+			}
+			return
+		}
+
+		rec := &recorder{ResponseWriter: w, status: http.StatusOK, maxBytes: c.MaxBodyBytes}
+		next(rec, r)
+		if rec.status == http.StatusOK && !rec.overflow {
+			c.put(&entry{key: key, date: date, etag: etag, body: rec.body.Bytes(), accounts: audit.Noted(r.Context())})
+		}
+	}
+}
+
+// InvalidateDate drops cached responses for a date. Stale entries are never
+// served anyway; this frees their memory as soon as ingestion touches a date.
+func (c *Cache) InvalidateDate(date string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for key, el := range c.entries {
+		if el.Value.(*entry).date == date {
+			c.lru.Remove(el)
+			delete(c.entries, key)
+		}
+	}
+}
+
+func (c *Cache) get(key, etag string) (*entry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	e := el.Value.(*entry)
+	if e.etag != etag {
+		c.lru.Remove(el)
+		delete(c.entries, key)
+		return nil, false
+	}
+	c.lru.MoveToFront(el)
+	return e, true
+}
+
+func (c *Cache) put(e *entry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[e.key]; ok {
+		c.lru.Remove(el)
+	}
+	c.entries[e.key] = c.lru.PushFront(e)
+	for c.lru.Len() > c.MaxEntries {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry).key)
+	}
+}
+
+// requestKey identifies a response by caller, path and sorted query, leaving
+// out credentials. Callers see different accounts, so responses are never shared.
+func requestKey(r *http.Request) string {
+	q := r.URL.Query()
+	q.Del("api_key")
+	var caller string
+	if p := auth.PrincipalFrom(r.Context()); p != nil {
+		caller = p.ID()
+		if p.Can(auth.ScopeAdmin) {
+			caller = "admin"
+		}
+	}
+	return caller + " " + r.URL.Path + "?" + q.Encode()
+}
+
+func keyHash(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return h.Sum64()
+}
+
+// notModified evaluates If-None-Match, falling back to If-Modified-Since
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		for _, tag := range strings.Split(inm, ",") {
+			tag = strings.TrimSpace(tag)
+			// Weak comparison, as for GET
+			if tag == "*" || strings.TrimPrefix(tag, "W/") == strings.TrimPrefix(etag, "W/") {
+				return true
+			}
+		}
+		return false
+	}
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !modified.IsZero() {
+		t, err := http.ParseTime(ims)
+		return err == nil && !modified.Truncate(time.Second).After(t)
+	}
+	return false
+}
+
+// recorder passes a response through while keeping a copy of the body
+type recorder struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	overflow bool
+	maxBytes int
+}
+
+func (rec *recorder) WriteHeader(status int) {
+	rec.status = status
+	// Errors aren't tied to the data version
+	if status != http.StatusOK {
+		rec.Header().Del("ETag")
+		rec.Header().Del("Last-Modified")
+		rec.Header().Del("Cache-Control")
+	}
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *recorder) Write(b []byte) (int, error) {
+	if !rec.overflow {
+		if rec.maxBytes > 0 && rec.body.Len()+len(b) > rec.maxBytes {
+			rec.overflow = true
+			rec.body.Reset()
+		} else {
+			rec.body.Write(b)
+		}
+	}
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,165 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+)
+
+func newTestCache(v *Version) (*Cache, *int) {
+	c := NewCache(nil)
+	c.Versions = func(_ context.Context, date string) (Version, error) { return *v, nil }
+	calls := 0
+	return c, &calls
+}
+
+func TestWrap(t *testing.T) {
+	modified := time.Date(2025, 1, 16, 9, 30, 0, 0, time.UTC)
+	v := &Version{Data: 1, Reference: 3, Modified: modified}
+	c, calls := newTestCache(v)
+	h := c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Write([]byte(`[{"account_id":"ACC001"}]`))
+	})
+
+	get := func(header, value string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/positions?date=2025-01-15&exposure=gross", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec
+	}
+
+	first := get("", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" {
+		t.Fatalf("expected 200 with ETag, got %d %q", first.Code, etag)
+	}
+	if lm := first.Header().Get("Last-Modified"); lm != modified.Format(http.TimeFormat) {
+		t.Errorf("unexpected Last-Modified %q", lm)
+	}
+
+	// Served from memory
+	if second := get("", ""); second.Body.String() != first.Body.String() || *calls != 1 {
+		t.Errorf("expected cached body without a second query, calls=%d", *calls)
+	}
+
+	if rec := get("If-None-Match", etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Errorf("expected 304 for matching ETag, got %d", rec.Code)
+	}
+	if rec := get("If-Modified-Since", modified.Format(http.TimeFormat)); rec.Code != http.StatusNotModified {
+		t.Errorf("expected 304 for If-Modified-Since, got %d", rec.Code)
+	}
+	if rec := get("If-Modified-Since", modified.Add(-time.Minute).Format(http.TimeFormat)); rec.Code != http.StatusOK {
+		t.Errorf("expected 200 for older If-Modified-Since, got %d", rec.Code)
+	}
+
+	// Ingestion bumps the version: the old ETag no longer matches and the handler re-runs
+	v.Data = 2
+	rec := get("If-None-Match", etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Errorf("expected fresh 200 after version bump, got %d %q", rec.Code, rec.Header().Get("ETag"))
+	}
+	if *calls != 2 {
+		t.Errorf("expected handler to re-run after version bump, calls=%d", *calls)
+	}
+}
+
+func TestWrapReplaysAuditedAccounts(t *testing.T) {
+	c, calls := newTestCache(&Version{Data: 1})
+	h := c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		audit.Note(r.Context(), "ACC001")
+		w.Write([]byte(`[{"account_id":"ACC001"}]`))
+	})
+	get := func(etag string) []string {
+		req := httptest.NewRequest(http.MethodGet, "/blotter?date=2025-01-15", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		ctx := audit.WithNotes(req.Context())
+		rec := httptest.NewRecorder()
+		h(rec, req.WithContext(ctx))
+		return audit.Noted(ctx)
+	}
+
+	get("")
+	if got := get(""); len(got) != 1 || got[0] != "ACC001" || *calls != 1 {
+		t.Errorf("expected a cache hit to note ACC001, got %v with %d calls", got, *calls)
+	}
+	etag := fmt.Sprintf(`W/"1.0-%x"`, keyHash(" /blotter?date=2025-01-15"))
+	if got := get(etag); len(got) != 1 || *calls != 1 {
+		t.Errorf("expected a 304 to note ACC001, got %v with %d calls", got, *calls)
+	}
+}
+
+func TestWrapSkipsErrors(t *testing.T) {
+	c, calls := newTestCache(&Version{Data: 1})
+	h := c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		http.Error(w, "unknown group", http.StatusNotFound)
+	})
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		h(rec, httptest.NewRequest(http.MethodGet, "/alarms?date=2025-01-15&group=nope", nil))
+		if rec.Code != http.StatusNotFound || rec.Header().Get("ETag") != "" {
+			t.Errorf("expected uncached 404 without ETag, got %d %q", rec.Code, rec.Header().Get("ETag"))
+		}
+	}
+	if *calls != 2 {
+		t.Errorf("errors must not be cached, calls=%d", *calls)
+	}
+}
+
+func TestInvalidateDate(t *testing.T) {
+	c, calls := newTestCache(&Version{Data: 1})
+	h := c.Wrap(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		w.Write([]byte(`[]`))
+	})
+	for _, target := range []string{"/blotter?date=2025-01-15", "/blotter?date=2025-01-16", "/blotter?date=2025-01-15"} {
+		h(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, target, nil))
+	}
+	if *calls != 2 {
+		t.Fatalf("expected one query per date, calls=%d", *calls)
+	}
+
+	c.InvalidateDate("2025-01-15")
+	if len(c.entries) != 1 {
+		t.Errorf("expected only 2025-01-16 to remain, got %d entries", len(c.entries))
+	}
+}
+
+func TestRequestKeyIgnoresAPIKey(t *testing.T) {
+	a := httptest.NewRequest(http.MethodGet, "/positions?date=2025-01-15&api_key=one&exposure=net", nil)
+	b := httptest.NewRequest(http.MethodGet, "/positions?exposure=net&date=2025-01-15", nil)
+	if requestKey(a) != requestKey(b) {
+		t.Errorf("expected equal keys, got %q and %q", requestKey(a), requestKey(b))
+	}
+}
+
+func TestRequestKeySeparatesCallers(t *testing.T) {
+	as := func(p *auth.Principal) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/positions?date=2025-01-15", nil)
+		return r.WithContext(auth.WithPrincipal(r.Context(), p))
+	}
+	adviserA := as(&auth.Principal{KeyID: 1, Scopes: []string{auth.ScopeRead}})
+	adviserB := as(&auth.Principal{Subject: "b", Scopes: []string{auth.ScopeRead}})
+	if requestKey(adviserA) == requestKey(adviserB) {
+		t.Errorf("advisers share a cache key %q", requestKey(adviserA))
+	}
+	admin := as(&auth.Principal{KeyID: 2, Scopes: []string{auth.ScopeAdmin}})
+	bootstrap := as(&auth.Principal{Name: "bootstrap", Scopes: []string{auth.ScopeAdmin}})
+	if requestKey(admin) != requestKey(bootstrap) {
+		t.Errorf("expected admins to share responses, got %q and %q", requestKey(admin), requestKey(bootstrap))
+	}
+}
@@ -0,0 +1,67 @@
+// Package cache serves repeat reads of a date from memory. Every date has a
+// data version bumped whenever ingestion touches it, and reference data
+// (securities, account groups) shares one more; a cached response is reused
+// only while both versions are unchanged.
+package cache
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// ReferenceScope versions the security master and account groups, which
+// change how every date's positions are grouped and filtered
+const ReferenceScope = "reference"
+
+// BumpSQL increments the version of scope $1. Run it in the same transaction
+// as the change so readers never see new data under an old version.
+const BumpSQL = `
+	INSERT INTO data_versions (scope, version, updated_at)
+	VALUES ($1, 1, CURRENT_TIMESTAMP)
+	ON CONFLICT (scope) DO UPDATE SET
+		version = data_versions.version + 1,
+		updated_at = CURRENT_TIMESTAMP
+`
+
+// Version identifies the data a response for one date was built from
+type Version struct {
+	Data      int64     // Version of the date's positions
+	Reference int64     // Version of the reference data
+	Modified  time.Time // Latest change to either; zero if never recorded
+}
+
+// Execer is satisfied by *sql.DB and *sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Bump increments the version of each scope (a YYYY-MM-DD date or ReferenceScope)
+func Bump(ctx context.Context, db Execer, scopes ...string) error {
+	for _, scope := range scopes {
+		if _, err := db.ExecContext(ctx, BumpSQL, scope); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Lookup reads the current version of a date
+func Lookup(ctx context.Context, db *sql.DB, date string) (Version, error) {
+	var v Version
+	var modified sql.NullTime
+	err := db.QueryRowContext(ctx, `
+		SELECT COALESCE(MAX(version) FILTER (WHERE scope = $1), 0),
+		       COALESCE(MAX(version) FILTER (WHERE scope = $2), 0),
+		       MAX(updated_at)
+		FROM data_versions
+		WHERE scope IN ($1, $2)
+	`, date, ReferenceScope).Scan(&v.Data, &v.Reference, &modified)
+	if err != nil {
+		return Version{}, err
+	}
+	if modified.Valid {
+		v.Modified = modified.Time.UTC()
+	}
+	return v, nil
+}
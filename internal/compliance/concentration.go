@@ -0,0 +1,108 @@
+package compliance
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// ConcentrationRuleID identifies the single-holding concentration rule
+const ConcentrationRuleID = "CONCENTRATION_20"
+
+// ConcentrationThreshold is the max % of an account a single holding may represent
+const ConcentrationThreshold = 20.0
+
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Holding is one ticker within an account along with the account's total market value
+type Holding struct {
+	AccountID    string
+	Ticker       string
+	MarketValue  float64
+	AccountTotal float64
+}
+
+// Percent returns the share of the account this holding represents
+func (h Holding) Percent() float64 {
+	if h.AccountTotal == 0 {
+		return 0
+	}
+	return (h.MarketValue / h.AccountTotal) * 100
+}
+
+// Severity grades a breach: anything at or above twice the threshold is critical
+func Severity(observed, threshold float64) string {
+	if observed >= threshold*2 {
+		return SeverityCritical
+	}
+	return SeverityWarning
+}
+
+// Options controls how Evaluate measures and reports accounts
+type Options struct {
+	Exposure   Exposure
+	IncludeAll bool // Also return accounts without a breach
+}
+
+// Evaluate runs the concentration rule over the holdings for a date. A
+// holding breaches when its absolute share of the account exceeds the
+// threshold, so large shorts count too. Accounts whose total is zero cannot
+// be evaluated and are always returned with a warning.
+func Evaluate(date string, holdings []Holding, opts Options) []models.AlarmResponse {
+	if opts.Exposure == "" {
+		opts.Exposure = ExposureNet
+	}
+
+	response := []models.AlarmResponse{}
+	for _, acc := range ApplyExposure(holdings, opts.Exposure) {
+		violations := []models.Violation{}
+		if !acc.Undefined() {
+			for _, h := range acc.Holdings {
+				pct := h.Percent()
+				if abs(pct) > ConcentrationThreshold {
+					violations = append(violations, models.Violation{
+						RuleID:        ConcentrationRuleID,
+						Ticker:        h.Ticker,
+						ObservedValue: pct,
+						Threshold:     ConcentrationThreshold,
+						Severity:      Severity(abs(pct), ConcentrationThreshold),
+						MarketValue:   h.MarketValue,
+						AccountTotal:  h.AccountTotal,
+						Message:       fmt.Sprintf("%s is %.2f%% of portfolio", h.Ticker, pct),
+					})
+				}
+			}
+		}
+		if len(violations) == 0 && !opts.IncludeAll && !acc.Undefined() {
+			continue
+		}
+
+		// Largest breach first so the message leads with the worst offender
+		sort.Slice(violations, func(i, j int) bool {
+			return abs(violations[i].ObservedValue) > abs(violations[j].ObservedValue)
+		})
+
+		alarm := models.AlarmResponse{
+			Date:         date,
+			AccountID:    acc.ID,
+			Exposure:     string(opts.Exposure),
+			HasViolation: len(violations) > 0,
+			Violations:   violations,
+			Warning:      acc.Warning,
+		}
+		if alarm.HasViolation {
+			msgs := make([]string, len(violations))
+			for i, v := range violations {
+				msgs[i] = v.Message
+			}
+			alarm.ViolationInfo = strings.Join(msgs, "; ")
+		}
+		response = append(response, alarm)
+	}
+	return response
+}
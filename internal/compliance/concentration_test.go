@@ -0,0 +1,103 @@
+package compliance
+
+import (
+	"testing"
+)
+
+func sampleHoldings() []Holding {
+	return []Holding{
+		// ACC001 from the sample report
+		{AccountID: "ACC001", Ticker: "AAPL", MarketValue: 18550.00},
+		{AccountID: "ACC001", Ticker: "MSFT", MarketValue: 21012.50},
+		{AccountID: "ACC001", Ticker: "GOOGL", MarketValue: 14280.00},
+		// ACC004
+		{AccountID: "ACC004", Ticker: "AAPL", MarketValue: 92750.00},
+		{AccountID: "ACC004", Ticker: "MSFT", MarketValue: 126075.00},
+		// Ten equal holdings, no breach
+		{AccountID: "ACC005", Ticker: "A", MarketValue: 10},
+		{AccountID: "ACC005", Ticker: "B", MarketValue: 10},
+		{AccountID: "ACC005", Ticker: "C", MarketValue: 10},
+		{AccountID: "ACC005", Ticker: "D", MarketValue: 10},
+		{AccountID: "ACC005", Ticker: "E", MarketValue: 10},
+		{AccountID: "ACC005", Ticker: "F", MarketValue: 10},
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	alarms := Evaluate("2025-01-15", sampleHoldings(), Options{})
+
+	if len(alarms) != 2 {
+		t.Fatalf("Expected 2 alarms, got %d: %+v", len(alarms), alarms)
+	}
+	if alarms[0].AccountID != "ACC001" || alarms[1].AccountID != "ACC004" {
+		t.Errorf("Expected alarms sorted by account, got %s, %s", alarms[0].AccountID, alarms[1].AccountID)
+	}
+
+	acc4 := alarms[1]
+	if !acc4.HasViolation || len(acc4.Violations) != 2 {
+		t.Fatalf("Expected 2 violations for ACC004, got %+v", acc4.Violations)
+	}
+	if acc4.Exposure != "net" {
+		t.Errorf("Expected default net exposure, got %s", acc4.Exposure)
+	}
+	v := acc4.Violations[0]
+	if v.Ticker != "MSFT" {
+		t.Errorf("Expected largest breach first, got %s", v.Ticker)
+	}
+	if v.RuleID != ConcentrationRuleID || v.Threshold != ConcentrationThreshold {
+		t.Errorf("Unexpected rule fields: %+v", v)
+	}
+	if v.Severity != SeverityCritical {
+		t.Errorf("Expected critical severity for %.2f%%, got %s", v.ObservedValue, v.Severity)
+	}
+	if v.MarketValue != 126075.00 || v.AccountTotal != 218825.00 {
+		t.Errorf("Unexpected values: %+v", v)
+	}
+	if acc4.ViolationInfo != "MSFT is 57.61% of portfolio; AAPL is 42.39% of portfolio" {
+		t.Errorf("Unexpected message: %q", acc4.ViolationInfo)
+	}
+}
+
+func TestEvaluate_IncludeAll(t *testing.T) {
+	alarms := Evaluate("2025-01-15", sampleHoldings(), Options{IncludeAll: true})
+
+	if len(alarms) != 3 {
+		t.Fatalf("Expected 3 accounts, got %d", len(alarms))
+	}
+	clean := alarms[2]
+	if clean.AccountID != "ACC005" || clean.HasViolation {
+		t.Errorf("Expected ACC005 without violation, got %+v", clean)
+	}
+	if clean.Violations == nil || len(clean.Violations) != 0 {
+		t.Errorf("Expected empty violations list, got %v", clean.Violations)
+	}
+}
+
+func TestEvaluate_ZeroTotal(t *testing.T) {
+	holdings := []Holding{
+		{AccountID: "HEDGED", Ticker: "AAPL", MarketValue: 1000},
+		{AccountID: "HEDGED", Ticker: "SPY", MarketValue: -1000},
+	}
+	alarms := Evaluate("2025-01-15", holdings, Options{})
+	if len(alarms) != 1 {
+		t.Fatalf("Expected zero-total account to be reported, got %+v", alarms)
+	}
+	if alarms[0].HasViolation || alarms[0].Warning == "" {
+		t.Errorf("Expected warning without violation, got %+v", alarms[0])
+	}
+
+	// Gross exposure makes the same account measurable
+	alarms = Evaluate("2025-01-15", holdings, Options{Exposure: ExposureGross})
+	if len(alarms) != 1 || len(alarms[0].Violations) != 2 {
+		t.Fatalf("Expected both legs to breach at 50%% gross, got %+v", alarms)
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	if s := Severity(25, 20); s != SeverityWarning {
+		t.Errorf("Expected warning, got %s", s)
+	}
+	if s := Severity(40, 20); s != SeverityCritical {
+		t.Errorf("Expected critical, got %s", s)
+	}
+}
@@ -0,0 +1,106 @@
+package compliance
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Exposure selects which holdings count towards an account's total and how
+// that total is measured when computing allocations.
+type Exposure string
+
+const (
+	ExposureNet   Exposure = "net"   // sum of signed market values
+	ExposureGross Exposure = "gross" // sum of absolute market values
+	ExposureLong  Exposure = "long"  // long holdings over the long total
+	ExposureShort Exposure = "short" // short holdings over the absolute short total
+)
+
+// ParseExposure validates an exposure query value. Empty means net.
+func ParseExposure(s string) (Exposure, error) {
+	switch e := Exposure(s); e {
+	case "":
+		return ExposureNet, nil
+	case ExposureNet, ExposureGross, ExposureLong, ExposureShort:
+		return e, nil
+	default:
+		return "", fmt.Errorf("exposure must be one of net, gross, long, short")
+	}
+}
+
+// includes reports whether a holding with this market value counts under e
+func (e Exposure) includes(mv float64) bool {
+	switch e {
+	case ExposureLong:
+		return mv > 0
+	case ExposureShort:
+		return mv < 0
+	default:
+		return true
+	}
+}
+
+// Account is one account's holdings under an exposure mode. Each holding's
+// AccountTotal is set to Total, which is never negative.
+type Account struct {
+	ID       string
+	Total    float64
+	Warning  string // Set when the total is zero or had to be adjusted
+	Holdings []Holding
+}
+
+// Undefined reports whether allocations cannot be computed for the account
+func (a Account) Undefined() bool {
+	return a.Total == 0
+}
+
+// ApplyExposure groups holdings by account and computes each account's
+// denominator. A zero total leaves allocations undefined; a negative net
+// total is replaced by its absolute value so each allocation keeps the sign
+// of its own holding. Both cases are flagged in Warning.
+func ApplyExposure(holdings []Holding, e Exposure) []Account {
+	byID := make(map[string]*Account)
+	var ids []string
+	for _, h := range holdings {
+		acc, ok := byID[h.AccountID]
+		if !ok {
+			acc = &Account{ID: h.AccountID}
+			byID[h.AccountID] = acc
+			ids = append(ids, h.AccountID)
+		}
+		if !e.includes(h.MarketValue) {
+			continue
+		}
+		acc.Holdings = append(acc.Holdings, h)
+		if e == ExposureNet {
+			acc.Total += h.MarketValue
+		} else {
+			acc.Total += abs(h.MarketValue)
+		}
+	}
+	sort.Strings(ids)
+
+	accounts := make([]Account, 0, len(ids))
+	for _, id := range ids {
+		acc := byID[id]
+		switch {
+		case acc.Total == 0:
+			acc.Warning = fmt.Sprintf("%s total is zero; allocations are undefined", e)
+		case acc.Total < 0:
+			acc.Warning = fmt.Sprintf("net total is negative (%.2f); allocations use its absolute value", acc.Total)
+			acc.Total = -acc.Total
+		}
+		for i := range acc.Holdings {
+			acc.Holdings[i].AccountTotal = acc.Total
+		}
+		accounts = append(accounts, *acc)
+	}
+	return accounts
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
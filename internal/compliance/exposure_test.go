@@ -0,0 +1,86 @@
+package compliance
+
+import (
+	"math"
+	"testing"
+)
+
+// ACC003 from the sample report: a large TSLA short against an NVDA long
+func acc003() []Holding {
+	return []Holding{
+		{AccountID: "ACC003", Ticker: "TSLA", MarketValue: -35767.50},
+		{AccountID: "ACC003", Ticker: "NVDA", MarketValue: 40424.00},
+	}
+}
+
+func allocations(t *testing.T, e Exposure) (Account, map[string]float64) {
+	t.Helper()
+	accounts := ApplyExposure(acc003(), e)
+	if len(accounts) != 1 {
+		t.Fatalf("Expected 1 account, got %d", len(accounts))
+	}
+	out := make(map[string]float64)
+	for _, h := range accounts[0].Holdings {
+		out[h.Ticker] = math.Round(h.Percent()*100) / 100
+	}
+	return accounts[0], out
+}
+
+func TestApplyExposure(t *testing.T) {
+	cases := []struct {
+		exposure Exposure
+		total    float64
+		want     map[string]float64
+	}{
+		{ExposureNet, 4656.50, map[string]float64{"TSLA": -768.12, "NVDA": 868.12}},
+		{ExposureGross, 76191.50, map[string]float64{"TSLA": -46.94, "NVDA": 53.06}},
+		{ExposureLong, 40424.00, map[string]float64{"NVDA": 100}},
+		{ExposureShort, 35767.50, map[string]float64{"TSLA": -100}},
+	}
+	for _, c := range cases {
+		acc, got := allocations(t, c.exposure)
+		if math.Abs(acc.Total-c.total) > 0.001 {
+			t.Errorf("%s: total = %.2f, want %.2f", c.exposure, acc.Total, c.total)
+		}
+		if len(got) != len(c.want) {
+			t.Errorf("%s: got %v, want %v", c.exposure, got, c.want)
+			continue
+		}
+		for ticker, pct := range c.want {
+			if got[ticker] != pct {
+				t.Errorf("%s: %s = %.2f, want %.2f", c.exposure, ticker, got[ticker], pct)
+			}
+		}
+	}
+}
+
+func TestApplyExposure_NegativeNet(t *testing.T) {
+	holdings := []Holding{
+		{AccountID: "SHORTY", Ticker: "TSLA", MarketValue: -300},
+		{AccountID: "SHORTY", Ticker: "AAPL", MarketValue: 100},
+	}
+	acc := ApplyExposure(holdings, ExposureNet)[0]
+	if acc.Total != 200 || acc.Warning == "" {
+		t.Fatalf("Expected absolute total with warning, got %+v", acc)
+	}
+	// Each allocation keeps the sign of its own holding
+	if acc.Holdings[0].Percent() != -150 || acc.Holdings[1].Percent() != 50 {
+		t.Errorf("Unexpected allocations: %v, %v", acc.Holdings[0].Percent(), acc.Holdings[1].Percent())
+	}
+}
+
+func TestApplyExposure_NoMatchingHoldings(t *testing.T) {
+	acc := ApplyExposure(acc003()[:1], ExposureLong)[0]
+	if !acc.Undefined() || len(acc.Holdings) != 0 || acc.Warning == "" {
+		t.Errorf("Expected undefined long exposure for short-only account, got %+v", acc)
+	}
+}
+
+func TestParseExposure(t *testing.T) {
+	if e, err := ParseExposure(""); err != nil || e != ExposureNet {
+		t.Errorf("Expected net default, got %q, %v", e, err)
+	}
+	if _, err := ParseExposure("leveraged"); err == nil {
+		t.Error("Expected error for unknown exposure")
+	}
+}
@@ -0,0 +1,31 @@
+package compliance
+
+import "sort"
+
+// AggregateHoldings combines the holdings of the given accounts into a single
+// pseudo-account keyed by groupID, summing market value per ticker.
+func AggregateHoldings(holdings []Holding, accounts []string, groupID string) []Holding {
+	members := make(map[string]bool, len(accounts))
+	for _, a := range accounts {
+		members[a] = true
+	}
+
+	byTicker := make(map[string]float64)
+	for _, h := range holdings {
+		if members[h.AccountID] {
+			byTicker[h.Ticker] += h.MarketValue
+		}
+	}
+
+	tickers := make([]string, 0, len(byTicker))
+	for t := range byTicker {
+		tickers = append(tickers, t)
+	}
+	sort.Strings(tickers)
+
+	out := make([]Holding, 0, len(tickers))
+	for _, t := range tickers {
+		out = append(out, Holding{AccountID: groupID, Ticker: t, MarketValue: byTicker[t]})
+	}
+	return out
+}
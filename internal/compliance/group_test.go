@@ -0,0 +1,27 @@
+package compliance
+
+import "testing"
+
+func TestAggregateHoldings(t *testing.T) {
+	holdings := []Holding{
+		{AccountID: "ACC001", Ticker: "AAPL", MarketValue: 18550.00},
+		{AccountID: "ACC002", Ticker: "AAPL", MarketValue: 37100.00},
+		{AccountID: "ACC002", Ticker: "NVDA", MarketValue: 60636.00},
+		{AccountID: "ACC004", Ticker: "MSFT", MarketValue: 126075.00},
+	}
+
+	out := AggregateHoldings(holdings, []string{"ACC001", "ACC002"}, "HH-SMITH")
+	if len(out) != 2 {
+		t.Fatalf("Expected 2 tickers, got %+v", out)
+	}
+	if out[0].AccountID != "HH-SMITH" || out[0].Ticker != "AAPL" || out[0].MarketValue != 55650.00 {
+		t.Errorf("Unexpected AAPL aggregate: %+v", out[0])
+	}
+	if out[1].Ticker != "NVDA" || out[1].MarketValue != 60636.00 {
+		t.Errorf("Unexpected NVDA aggregate: %+v", out[1])
+	}
+
+	if out := AggregateHoldings(holdings, nil, "EMPTY"); len(out) != 0 {
+		t.Errorf("Expected no holdings for empty group, got %+v", out)
+	}
+}
@@ -0,0 +1,189 @@
+package compliance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+// Market values for ACC-1 in which AAA breaches the 20% rule, and in which
+// nothing does
+var (
+	breaching = map[string]float64{"AAA": 30, "BBB": 20, "CCC": 20, "DDD": 15, "EEE": 15}
+	compliant = map[string]float64{"AAA": 10, "BBB": 20, "CCC": 20, "DDD": 20, "EEE": 20, "FFF": 10}
+)
+
+func setPositions(t *testing.T, db *sql.DB, date string, values map[string]float64) {
+	t.Helper()
+	for ticker, mv := range values {
+		if _, err := db.Exec(`
+			INSERT INTO positions (date, account_id, ticker, quantity, market_value)
+			VALUES ($1, 'ACC-1', $2, 1, $3)
+		`, date, ticker, mv); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func evaluate(t *testing.T, m *Monitor, date string) *EvaluationResult {
+	t.Helper()
+	result, err := m.Evaluate(context.Background(), date)
+	if err != nil {
+		t.Fatalf("Evaluate %s: %v", date, err)
+	}
+	return result
+}
+
+func actions(a *models.Alarm) []string {
+	var out []string
+	for _, e := range a.Events {
+		out = append(out, e.Action)
+	}
+	return out
+}
+
+func TestEvaluateOpensAndAutoClears(t *testing.T) {
+	db := testdb.Open(t)
+	m := NewMonitor(db)
+	changes := 0
+	m.OnChange = func(context.Context, *EvaluationResult) { changes++ }
+	setPositions(t, db, "2025-01-15", breaching)
+	setPositions(t, db, "2025-01-16", compliant)
+
+	result := evaluate(t, m, "2025-01-15")
+	if len(result.Opened) != 1 || len(result.Cleared) != 0 {
+		t.Fatalf("Expected one alarm opened, got %+v", result)
+	}
+	opened := result.Opened[0]
+	if opened.Ticker != "AAA" || opened.Status != models.AlarmOpen || opened.Severity != SeverityWarning ||
+		opened.FirstDetectedDate != "2025-01-15" || math.Abs(opened.ObservedValue-30) > 1e-9 {
+		t.Errorf("Unexpected alarm %+v", opened)
+	}
+
+	// The same breach again only refreshes the alarm
+	if result := evaluate(t, m, "2025-01-15"); len(result.Opened) != 0 || len(result.Cleared) != 0 {
+		t.Errorf("Expected no changes re-evaluating, got %+v", result)
+	}
+
+	result = evaluate(t, m, "2025-01-16")
+	if len(result.Cleared) != 1 || result.Cleared[0].ID != opened.ID || result.Cleared[0].Status != models.AlarmAutoCleared || result.Cleared[0].ClosedAt == nil {
+		t.Fatalf("Expected the alarm auto-cleared, got %+v", result)
+	}
+	if changes != 2 {
+		t.Errorf("Expected OnChange for the opening and the clearing only, got %d calls", changes)
+	}
+
+	// A closed alarm already covers the older date, so it isn't reopened
+	if result := evaluate(t, m, "2025-01-15"); len(result.Opened) != 0 {
+		t.Errorf("Expected no alarm reopened for a date the closed one covers, got %+v", result)
+	}
+	alarm, err := m.Get(context.Background(), opened.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := actions(alarm); !slices.Equal(got, []string{"detected", "auto_cleared"}) || alarm.Events[1].Actor != SystemActor {
+		t.Errorf("Expected detected then auto_cleared by the system, got %q", got)
+	}
+}
+
+func TestBreachReopensOnlyAfterClosing(t *testing.T) {
+	db := testdb.Open(t)
+	m := NewMonitor(db)
+	ctx := context.Background()
+	for _, date := range []string{"2025-01-15", "2025-01-16", "2025-01-17"} {
+		setPositions(t, db, date, breaching)
+	}
+
+	first := evaluate(t, m, "2025-01-15").Opened[0]
+	if _, err := m.Acknowledge(ctx, first.ID, "alice", "Looking into it", ""); err != nil {
+		t.Fatal(err)
+	}
+	// An acknowledged alarm stays acknowledged while the breach continues
+	if result := evaluate(t, m, "2025-01-16"); len(result.Opened) != 0 {
+		t.Errorf("Expected the acknowledged alarm refreshed, not a new one, got %+v", result)
+	}
+	alarm, err := m.Get(ctx, first.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alarm.Status != models.AlarmAcknowledged || alarm.LastSeenDate != "2025-01-16" || alarm.FirstDetectedDate != "2025-01-15" {
+		t.Errorf("Expected the acknowledged alarm last seen on the 16th, got %+v", alarm)
+	}
+
+	if _, err := m.Resolve(ctx, first.ID, "alice", "Client approved"); err != nil {
+		t.Fatal(err)
+	}
+	// Resolving covers the dates already seen; a later breach opens a new alarm
+	if result := evaluate(t, m, "2025-01-16"); len(result.Opened) != 0 {
+		t.Errorf("Expected no reopening on a date the resolved alarm covers, got %+v", result)
+	}
+	result := evaluate(t, m, "2025-01-17")
+	if len(result.Opened) != 1 || result.Opened[0].ID == first.ID || result.Opened[0].FirstDetectedDate != "2025-01-17" {
+		t.Fatalf("Expected a new alarm for the later breach, got %+v", result)
+	}
+	if alarm, _ := m.Get(ctx, first.ID); alarm.Status != models.AlarmResolved {
+		t.Errorf("Expected the resolved alarm left resolved, got %s", alarm.Status)
+	}
+}
+
+func TestAlarmTransitions(t *testing.T) {
+	db := testdb.Open(t)
+	m := NewMonitor(db)
+	ctx := context.Background()
+	setPositions(t, db, "2025-01-15", breaching)
+	id := evaluate(t, m, "2025-01-15").Opened[0].ID
+
+	alarm, err := m.Assign(ctx, id, "alice", "bob", "Bob covers this account")
+	if err != nil || alarm.Status != models.AlarmOpen || alarm.Assignee != "bob" {
+		t.Fatalf("Expected an open alarm assigned to bob, got %+v, %v", alarm, err)
+	}
+	alarm, err = m.Acknowledge(ctx, id, "bob", "", "carol")
+	if err != nil || alarm.Status != models.AlarmAcknowledged || alarm.Assignee != "carol" {
+		t.Fatalf("Expected acknowledged and reassigned, got %+v, %v", alarm, err)
+	}
+	if _, err := m.Comment(ctx, id, "carol", "Waiting on the client"); err != nil {
+		t.Fatal(err)
+	}
+	alarm, err = m.Resolve(ctx, id, "carol", "Sold down")
+	if err != nil || alarm.Status != models.AlarmResolved || alarm.ClosedAt == nil {
+		t.Fatalf("Expected resolved and closed, got %+v, %v", alarm, err)
+	}
+
+	for name, op := range map[string]func() (*models.Alarm, error){
+		"acknowledge": func() (*models.Alarm, error) { return m.Acknowledge(ctx, id, "bob", "", "") },
+		"resolve":     func() (*models.Alarm, error) { return m.Resolve(ctx, id, "bob", "") },
+		"assign":      func() (*models.Alarm, error) { return m.Assign(ctx, id, "bob", "dave", "") },
+	} {
+		if _, err := op(); !errors.Is(err, ErrInvalidTransition) {
+			t.Errorf("Expected ErrInvalidTransition to %s a resolved alarm, got %v", name, err)
+		}
+	}
+	// Comments are allowed in any state
+	if _, err := m.Comment(ctx, id, "dave", "Closed out in review"); err != nil {
+		t.Errorf("Expected a comment on a resolved alarm, got %v", err)
+	}
+	if _, err := m.Acknowledge(ctx, id+1000, "bob", "", ""); !errors.Is(err, ErrAlarmNotFound) {
+		t.Errorf("Expected ErrAlarmNotFound, got %v", err)
+	}
+
+	history, err := m.History(ctx, HistoryFilter{AccountID: "ACC-1", Status: models.AlarmResolved})
+	if err != nil || len(history) != 1 {
+		t.Fatalf("Expected the resolved alarm in its history, got %+v, %v", history, err)
+	}
+	if got := actions(&history[0]); !slices.Equal(got, []string{"detected", "assigned", "acknowledged", "comment", "resolved", "comment"}) {
+		t.Errorf("Unexpected events %q", got)
+	}
+	resolved := history[0].Events[4]
+	if resolved.FromStatus != models.AlarmAcknowledged || resolved.ToStatus != models.AlarmResolved || resolved.Actor != "carol" || resolved.Comment != "Sold down" {
+		t.Errorf("Unexpected resolve event %+v", resolved)
+	}
+	if history, _ := m.History(ctx, HistoryFilter{Accounts: []string{"ACC-2"}}); len(history) != 0 {
+		t.Errorf("Expected no alarms for other accounts, got %+v", history)
+	}
+}
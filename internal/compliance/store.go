@@ -0,0 +1,510 @@
+package compliance
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var (
+	logger = logging.Component("compliance")
+	tracer = tracing.Tracer("compliance")
+)
+
+var (
+	ErrAlarmNotFound     = errors.New("alarm not found")
+	ErrInvalidTransition = errors.New("invalid alarm transition")
+)
+
+// SystemActor is recorded on events raised by evaluation rather than a person
+const SystemActor = "system"
+
+// LoadHoldings returns every position for a date, for evaluating alarms
+// across the whole book. AccountTotal is left unset; ApplyExposure fills it
+// in for the chosen exposure mode.
+func LoadHoldings(ctx context.Context, db *sql.DB, date string) ([]Holding, error) {
+	rows, err := db.QueryContext(ctx, `
+		SELECT account_id, ticker, market_value
+		FROM positions
+		WHERE date = $1
+	`, date)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var holdings []Holding
+	for rows.Next() {
+		var h Holding
+		if err := rows.Scan(&h.AccountID, &h.Ticker, &h.MarketValue); err != nil {
+			continue
+		}
+		holdings = append(holdings, h)
+	}
+	return holdings, rows.Err()
+}
+
+// EvaluationResult lists the alarms whose state changed during an evaluation
+type EvaluationResult struct {
+	Date    string         `json:"date"`
+	Opened  []models.Alarm `json:"opened"`
+	Cleared []models.Alarm `json:"cleared"`
+}
+
+// Monitor persists alarms and manages their lifecycle
+type Monitor struct {
+	DB       *sql.DB
+	Exposure Exposure // How account totals are measured; defaults to net
+
+	// OnChange, if set, is called after an evaluation that opened or cleared
+	// alarms. Its context is not cancelled with the evaluation's, since the
+	// changes are already committed.
+	OnChange func(ctx context.Context, result *EvaluationResult)
+}
+
+func NewMonitor(db *sql.DB) *Monitor {
+	return &Monitor{DB: db, Exposure: ExposureNet}
+}
+
+// Start evaluates the most recent position date on every tick
+func (m *Monitor) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var latest sql.NullTime
+			if err := m.DB.QueryRowContext(ctx, `SELECT MAX(date) FROM positions`).Scan(&latest); err != nil {
+				logger.ErrorContext(ctx, "Alarm evaluation failed to find latest date", "error", err)
+				continue
+			}
+			if !latest.Valid {
+				continue
+			}
+			if _, err := m.Evaluate(ctx, latest.Time.Format("2006-01-02")); err != nil {
+				logger.ErrorContext(ctx, "Scheduled alarm evaluation failed", "error", err)
+			}
+		}
+	}
+}
+
+// alarmIDs lists alarm ids for logging, so an alarm can be traced back to
+// the evaluation and ingestion that opened it
+func alarmIDs(alarms []models.Alarm) []int64 {
+	ids := make([]int64, len(alarms))
+	for i, a := range alarms {
+		ids[i] = a.ID
+	}
+	return ids
+}
+
+// Evaluate runs the rules for a date, opening alarms for new breaches and
+// auto-clearing active alarms whose breach is no longer present.
+func (m *Monitor) Evaluate(ctx context.Context, date string) (*EvaluationResult, error) {
+	ctx, span := tracer.Start(ctx, "compliance.evaluate", trace.WithAttributes(attribute.String("vest.date", date)))
+	result, err := m.evaluate(ctx, date)
+	if result != nil {
+		span.SetAttributes(attribute.Int("vest.alarms_opened", len(result.Opened)), attribute.Int("vest.alarms_cleared", len(result.Cleared)))
+	}
+	tracing.End(span, err)
+	return result, err
+}
+
+func (m *Monitor) evaluate(ctx context.Context, date string) (*EvaluationResult, error) {
+	start := time.Now()
+	holdings, err := LoadHoldings(ctx, m.DB, date)
+	if err != nil {
+		return nil, err
+	}
+	evaluated := Evaluate(date, holdings, Options{Exposure: m.Exposure, IncludeAll: true})
+
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	result := &EvaluationResult{Date: date, Opened: []models.Alarm{}, Cleared: []models.Alarm{}}
+	accounts := make([]string, 0, len(evaluated))
+	breaching := make(map[string]bool) // account|rule|ticker
+
+	// Accounts with a zero total can't be evaluated, so neither open nor clear their alarms
+	undefined := make(map[string]bool)
+	for _, acc := range ApplyExposure(holdings, m.Exposure) {
+		if acc.Undefined() {
+			undefined[acc.ID] = true
+		}
+	}
+
+	for _, acc := range evaluated {
+		if undefined[acc.AccountID] {
+			continue
+		}
+		accounts = append(accounts, acc.AccountID)
+		for _, v := range acc.Violations {
+			breaching[acc.AccountID+"|"+v.RuleID+"|"+v.Ticker] = true
+			alarm, err := upsertBreach(ctx, tx, date, acc.AccountID, v)
+			if err != nil {
+				return nil, err
+			}
+			if alarm != nil {
+				result.Opened = append(result.Opened, *alarm)
+			}
+		}
+	}
+
+	// Only accounts with data on this date can clear, and never on a date older than the breach
+	rows, err := tx.QueryContext(ctx, `
+		SELECT id, account_id, rule_id, ticker
+		FROM alarms
+		WHERE account_id = ANY($1) AND status IN ($2, $3) AND last_seen_date <= $4
+		FOR UPDATE
+	`, accounts, models.AlarmOpen, models.AlarmAcknowledged, date)
+	if err != nil {
+		return nil, err
+	}
+	var toClear []int64
+	for rows.Next() {
+		var id int64
+		var accID, ruleID, ticker string
+		if err := rows.Scan(&id, &accID, &ruleID, &ticker); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if !breaching[accID+"|"+ruleID+"|"+ticker] {
+			toClear = append(toClear, id)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range toClear {
+		alarm, err := transition(ctx, tx, id, models.AlarmAutoCleared, "auto_cleared", SystemActor,
+			fmt.Sprintf("Breach no longer present on %s", date), "")
+		if err != nil {
+			return nil, err
+		}
+		result.Cleared = append(result.Cleared, *alarm)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	level := slog.LevelDebug
+	if len(result.Opened) > 0 || len(result.Cleared) > 0 {
+		level = slog.LevelInfo
+	}
+	logger.Log(ctx, level, "Alarms evaluated",
+		"date", date,
+		"accounts", len(accounts),
+		"opened", alarmIDs(result.Opened),
+		"cleared", alarmIDs(result.Cleared),
+		"duration_ms", time.Since(start).Milliseconds(),
+	)
+	if len(result.Opened) > 0 || len(result.Cleared) > 0 {
+		if m.OnChange != nil {
+			m.OnChange(context.WithoutCancel(ctx), result)
+		}
+	}
+	return result, nil
+}
+
+// upsertBreach refreshes the active alarm for a breach or opens a new one.
+// It returns the alarm only when one was opened.
+func upsertBreach(ctx context.Context, tx *sql.Tx, date, accountID string, v models.Violation) (*models.Alarm, error) {
+	var id int64
+	var status string
+	var lastSeen time.Time
+	err := tx.QueryRowContext(ctx, `
+		SELECT id, status, last_seen_date
+		FROM alarms
+		WHERE account_id = $1 AND rule_id = $2 AND ticker = $3
+		ORDER BY id DESC
+		LIMIT 1
+		FOR UPDATE
+	`, accountID, v.RuleID, v.Ticker).Scan(&id, &status, &lastSeen)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if err == nil {
+		if status == models.AlarmOpen || status == models.AlarmAcknowledged {
+			_, err := tx.ExecContext(ctx, `
+				UPDATE alarms
+				SET last_seen_date = GREATEST(last_seen_date, $2),
+					observed_value = $3,
+					severity = $4,
+					updated_at = CURRENT_TIMESTAMP
+				WHERE id = $1
+			`, id, date, v.ObservedValue, v.Severity)
+			return nil, err
+		}
+		// A closed alarm already covers this date; only a later breach reopens
+		if lastSeen.Format("2006-01-02") >= date {
+			return nil, nil
+		}
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO alarms (account_id, rule_id, ticker, status, severity, observed_value, threshold, first_detected_date, last_seen_date)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
+		ON CONFLICT (account_id, rule_id, ticker) WHERE status IN ('open', 'acknowledged') DO NOTHING
+		RETURNING id
+	`, accountID, v.RuleID, v.Ticker, models.AlarmOpen, v.Severity, v.ObservedValue, v.Threshold, date).Scan(&id)
+	if err == sql.ErrNoRows {
+		// Another evaluator opened it concurrently
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := addEvent(ctx, tx, id, "detected", "", models.AlarmOpen, SystemActor, v.Message); err != nil {
+		return nil, err
+	}
+	return getAlarm(ctx, tx, id)
+}
+
+// Acknowledge marks an open alarm as being handled, optionally assigning it
+func (m *Monitor) Acknowledge(ctx context.Context, id int64, actor, comment, assignee string) (*models.Alarm, error) {
+	return m.apply(ctx, id, func(tx *sql.Tx, status string) (*models.Alarm, error) {
+		if status != models.AlarmOpen {
+			return nil, fmt.Errorf("%w: cannot acknowledge %s alarm", ErrInvalidTransition, status)
+		}
+		return transition(ctx, tx, id, models.AlarmAcknowledged, "acknowledged", actor, comment, assignee)
+	})
+}
+
+// Resolve closes an open or acknowledged alarm
+func (m *Monitor) Resolve(ctx context.Context, id int64, actor, comment string) (*models.Alarm, error) {
+	return m.apply(ctx, id, func(tx *sql.Tx, status string) (*models.Alarm, error) {
+		if status != models.AlarmOpen && status != models.AlarmAcknowledged {
+			return nil, fmt.Errorf("%w: cannot resolve %s alarm", ErrInvalidTransition, status)
+		}
+		return transition(ctx, tx, id, models.AlarmResolved, "resolved", actor, comment, "")
+	})
+}
+
+// Assign sets the person responsible for an active alarm
+func (m *Monitor) Assign(ctx context.Context, id int64, actor, assignee, comment string) (*models.Alarm, error) {
+	return m.apply(ctx, id, func(tx *sql.Tx, status string) (*models.Alarm, error) {
+		if status != models.AlarmOpen && status != models.AlarmAcknowledged {
+			return nil, fmt.Errorf("%w: cannot assign %s alarm", ErrInvalidTransition, status)
+		}
+		if _, err := tx.ExecContext(ctx, `UPDATE alarms SET assignee = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1`, id, assignee); err != nil {
+			return nil, err
+		}
+		if err := addEvent(ctx, tx, id, "assigned", status, status, actor, comment); err != nil {
+			return nil, err
+		}
+		return getAlarm(ctx, tx, id)
+	})
+}
+
+// Comment appends a note to an alarm in any state
+func (m *Monitor) Comment(ctx context.Context, id int64, actor, comment string) (*models.Alarm, error) {
+	return m.apply(ctx, id, func(tx *sql.Tx, status string) (*models.Alarm, error) {
+		if err := addEvent(ctx, tx, id, "comment", "", "", actor, comment); err != nil {
+			return nil, err
+		}
+		return getAlarm(ctx, tx, id)
+	})
+}
+
+// apply locks the alarm and runs fn inside a transaction
+func (m *Monitor) apply(ctx context.Context, id int64, fn func(tx *sql.Tx, status string) (*models.Alarm, error)) (*models.Alarm, error) {
+	tx, err := m.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM alarms WHERE id = $1 FOR UPDATE`, id).Scan(&status)
+	if err == sql.ErrNoRows {
+		return nil, ErrAlarmNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	alarm, err := fn(tx, status)
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return alarm, nil
+}
+
+func transition(ctx context.Context, tx *sql.Tx, id int64, to, action, actor, comment, assignee string) (*models.Alarm, error) {
+	var from string
+	closing := to == models.AlarmResolved || to == models.AlarmAutoCleared
+	err := tx.QueryRowContext(ctx, `
+		UPDATE alarms a
+		SET status = $2,
+			assignee = COALESCE(NULLIF($3, ''), a.assignee),
+			closed_at = CASE WHEN $4 THEN CURRENT_TIMESTAMP ELSE a.closed_at END,
+			updated_at = CURRENT_TIMESTAMP
+		FROM alarms prev
+		WHERE a.id = $1 AND prev.id = a.id
+		RETURNING prev.status
+	`, id, to, assignee, closing).Scan(&from)
+	if err != nil {
+		return nil, err
+	}
+	if err := addEvent(ctx, tx, id, action, from, to, actor, comment); err != nil {
+		return nil, err
+	}
+	return getAlarm(ctx, tx, id)
+}
+
+func addEvent(ctx context.Context, tx *sql.Tx, alarmID int64, action, from, to, actor, comment string) error {
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO alarm_events (alarm_id, action, from_status, to_status, actor, comment)
+		VALUES ($1, $2, NULLIF($3, ''), NULLIF($4, ''), $5, NULLIF($6, ''))
+	`, alarmID, action, from, to, actor, comment)
+	return err
+}
+
+const alarmColumns = `
+	id, account_id, rule_id, ticker, status, severity, observed_value, threshold,
+	first_detected_date, last_seen_date, COALESCE(assignee, ''), created_at, updated_at, closed_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAlarm(row rowScanner) (*models.Alarm, error) {
+	var a models.Alarm
+	var first, last time.Time
+	var closed sql.NullTime
+	if err := row.Scan(&a.ID, &a.AccountID, &a.RuleID, &a.Ticker, &a.Status, &a.Severity, &a.ObservedValue,
+		&a.Threshold, &first, &last, &a.Assignee, &a.CreatedAt, &a.UpdatedAt, &closed); err != nil {
+		return nil, err
+	}
+	a.FirstDetectedDate = first.Format("2006-01-02")
+	a.LastSeenDate = last.Format("2006-01-02")
+	if closed.Valid {
+		a.ClosedAt = &closed.Time
+	}
+	return &a, nil
+}
+
+func getAlarm(ctx context.Context, tx *sql.Tx, id int64) (*models.Alarm, error) {
+	return scanAlarm(tx.QueryRowContext(ctx, `SELECT `+alarmColumns+` FROM alarms WHERE id = $1`, id))
+}
+
+// Get returns one alarm with its full event history
+func (m *Monitor) Get(ctx context.Context, id int64) (*models.Alarm, error) {
+	alarm, err := scanAlarm(m.DB.QueryRowContext(ctx, `SELECT `+alarmColumns+` FROM alarms WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, ErrAlarmNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	alarms := []models.Alarm{*alarm}
+	if err := m.attachEvents(ctx, alarms); err != nil {
+		return nil, err
+	}
+	return &alarms[0], nil
+}
+
+// HistoryFilter narrows an alarm history query. Empty fields are ignored.
+type HistoryFilter struct {
+	AccountID string
+	Status    string
+	From      string   // alarms active on or after this date
+	To        string   // alarms first detected on or before this date
+	Accounts  []string // when non-nil, only alarms on these accounts
+}
+
+// History returns persisted alarms with their events, newest first
+func (m *Monitor) History(ctx context.Context, f HistoryFilter) ([]models.Alarm, error) {
+	rows, err := m.DB.QueryContext(ctx, `SELECT `+alarmColumns+`
+		FROM alarms
+		WHERE ($1 = '' OR account_id = $1)
+		  AND ($2 = '' OR status = $2)
+		  AND ($3 = '' OR last_seen_date >= NULLIF($3, '')::date)
+		  AND ($4 = '' OR first_detected_date <= NULLIF($4, '')::date)
+		  AND ($5 = FALSE OR account_id = ANY($6))
+		ORDER BY first_detected_date DESC, id DESC
+	`, f.AccountID, f.Status, f.From, f.To, f.Accounts != nil, f.Accounts)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alarms := []models.Alarm{}
+	for rows.Next() {
+		a, err := scanAlarm(rows)
+		if err != nil {
+			return nil, err
+		}
+		alarms = append(alarms, *a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	if err := m.attachEvents(ctx, alarms); err != nil {
+		return nil, err
+	}
+	return alarms, nil
+}
+
+func (m *Monitor) attachEvents(ctx context.Context, alarms []models.Alarm) error {
+	if len(alarms) == 0 {
+		return nil
+	}
+	ids := make([]int64, len(alarms))
+	index := make(map[int64]int, len(alarms))
+	for i, a := range alarms {
+		ids[i] = a.ID
+		index[a.ID] = i
+	}
+
+	rows, err := m.DB.QueryContext(ctx, `
+		SELECT id, alarm_id, action, COALESCE(from_status, ''), COALESCE(to_status, ''), actor, COALESCE(comment, ''), created_at
+		FROM alarm_events
+		WHERE alarm_id = ANY($1)
+		ORDER BY created_at, id
+	`, ids)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e models.AlarmEvent
+		if err := rows.Scan(&e.ID, &e.AlarmID, &e.Action, &e.FromStatus, &e.ToStatus, &e.Actor, &e.Comment, &e.CreatedAt); err != nil {
+			return err
+		}
+		i := index[e.AlarmID]
+		alarms[i].Events = append(alarms[i].Events, e)
+	}
+	return rows.Err()
+}
@@ -0,0 +1,243 @@
+// Package config loads the server's settings: defaults, then an optional
+// YAML or TOML file, then environment variables, which keep the names the
+// server has always read. Secrets may be kept in files and are redacted when
+// the configuration is printed.
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// PathEnv names the variable holding the config file's path
+const PathEnv = "VEST_CONFIG"
+
+var (
+	ErrInvalid       = errors.New("invalid configuration")
+	ErrUnknownFormat = errors.New("config file must end in .yaml, .yml or .toml")
+)
+
+// Config is every setting the server reads. The env tag names the variable
+// that overrides each field, and reload marks fields a SIGHUP applies without
+// a restart.
+type Config struct {
+	Server    Server    `yaml:"server" toml:"server"`
+	Log       Log       `yaml:"log" toml:"log"`
+	Database  Database  `yaml:"database" toml:"database"`
+	Auth      Auth      `yaml:"auth" toml:"auth"`
+	OIDC      OIDC      `yaml:"oidc" toml:"oidc"`
+	RateLimit RateLimit `yaml:"rate_limit" toml:"rate_limit"`
+	Alarms    Alarms    `yaml:"alarms" toml:"alarms"`
+	Ready     Ready     `yaml:"ready" toml:"ready"`
+	SFTP      SFTP      `yaml:"sftp" toml:"sftp"`
+	Tracing   Tracing   `yaml:"tracing" toml:"tracing"`
+}
+
+type Server struct {
+	Port            int           `yaml:"port" toml:"port" env:"PORT"`
+	GRPCPort        int           `yaml:"grpc_port" toml:"grpc_port" env:"GRPC_PORT"`
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" toml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	AutoMigrate     bool          `yaml:"auto_migrate" toml:"auto_migrate" env:"AUTO_MIGRATE"`
+}
+
+type Log struct {
+	Level  string `yaml:"level" toml:"level" env:"LOG_LEVEL" reload:"true"`
+	Format string `yaml:"format" toml:"format" env:"LOG_FORMAT"`
+}
+
+// Database is either a URL or the parts injected from Secrets Manager
+type Database struct {
+	URL      Secret `yaml:"url" toml:"url" env:"DATABASE_URL"`
+	Host     string `yaml:"host" toml:"host" env:"DB_HOST"`
+	Port     int    `yaml:"port" toml:"port" env:"DB_PORT"`
+	User     string `yaml:"user" toml:"user" env:"DB_USER"`
+	Password Secret `yaml:"password" toml:"password" env:"DB_PASSWORD"`
+	Name     string `yaml:"name" toml:"name" env:"DB_NAME"`
+}
+
+type Auth struct {
+	// APIKey is accepted as an admin key for bootstrapping the first stored key
+	APIKey Secret `yaml:"api_key" toml:"api_key" env:"API_KEY"`
+}
+
+type OIDC struct {
+	Issuer     string `yaml:"issuer" toml:"issuer" env:"OIDC_ISSUER"`
+	Audience   string `yaml:"audience" toml:"audience" env:"OIDC_AUDIENCE"`
+	JWKSURL    string `yaml:"jwks_url" toml:"jwks_url" env:"OIDC_JWKS_URL"`
+	JWKSFile   string `yaml:"jwks_file" toml:"jwks_file" env:"OIDC_JWKS_FILE"`
+	RolesClaim string `yaml:"roles_claim" toml:"roles_claim" env:"OIDC_ROLES_CLAIM"`
+	RoleScopes string `yaml:"role_scopes" toml:"role_scopes" env:"OIDC_ROLE_SCOPES"`
+}
+
+type RateLimit struct {
+	// Rules are as ratelimit.ParseRules reads them, or "off"
+	Rules string `yaml:"rules" toml:"rules" env:"RATE_LIMITS" reload:"true"`
+	Store string `yaml:"store" toml:"store" env:"RATE_LIMIT_STORE"`
+}
+
+type Alarms struct {
+	EvalInterval time.Duration `yaml:"eval_interval" toml:"eval_interval" env:"ALARM_EVAL_INTERVAL"`
+	Exposure     string        `yaml:"exposure" toml:"exposure" env:"ALARM_EXPOSURE"`
+}
+
+type Ready struct {
+	Require      []string      `yaml:"require" toml:"require" env:"READY_REQUIRE" reload:"true"`
+	MaxIngestAge time.Duration `yaml:"max_ingest_age" toml:"max_ingest_age" env:"READY_MAX_INGEST_AGE" reload:"true"`
+	MaxDataAge   time.Duration `yaml:"max_data_age" toml:"max_data_age" env:"READY_MAX_DATA_AGE" reload:"true"`
+}
+
+// SFTP is the custodian drop; ingestion is off without a host
+type SFTP struct {
+	Host     string `yaml:"host" toml:"host" env:"SFTP_HOST"`
+	User     string `yaml:"user" toml:"user" env:"SFTP_USER"`
+	Password Secret `yaml:"password" toml:"password" env:"SFTP_PASS"`
+	Dir      string `yaml:"dir" toml:"dir" env:"SFTP_DIR"`
+}
+
+// Tracing exports OpenTelemetry spans. The endpoint and service name keep
+// the variables every OpenTelemetry SDK reads.
+type Tracing struct {
+	// Exporter is none, otlp or stdout
+	Exporter string `yaml:"exporter" toml:"exporter" env:"TRACING_EXPORTER"`
+	// Endpoint is the collector's base URL, such as http://localhost:4318;
+	// empty for the OTLP default
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`
+	ServiceName string  `yaml:"service_name" toml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+// Default returns the settings used when nothing overrides them
+func Default() *Config {
+	return &Config{
+		Server: Server{
+			Port:     8080,
+			GRPCPort: 9090,
+			// Leaves a few seconds of ECS's 30 second stop timeout for the
+			// audit log to drain
+			ShutdownTimeout: 25 * time.Second,
+			AutoMigrate:     true,
+		},
+		Log:      Log{Level: "info", Format: "json"},
+		Database: Database{Port: 5432},
+		// Each caller gets 20 requests a second with bursts of 40
+		RateLimit: RateLimit{Rules: "*=20/s:40", Store: "memory"},
+		Alarms:    Alarms{EvalInterval: 5 * time.Minute, Exposure: "net"},
+		Ready: Ready{
+			Require:      []string{"database", "migrations"},
+			MaxIngestAge: 72 * time.Hour,
+			MaxDataAge:   96 * time.Hour,
+		},
+		Tracing: Tracing{Exporter: "none", SampleRatio: 1, ServiceName: "vest"},
+	}
+}
+
+// Load reads the config with Read and validates it
+func Load(path string) (*Config, error) {
+	c, err := Read(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Read returns the defaults overridden by the file at path, if path is not
+// empty, and then the environment, with secret files read
+func Read(path string) (*Config, error) {
+	c := Default()
+	if path != "" {
+		if err := c.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := c.applyEnv(os.LookupEnv); err != nil {
+		return nil, err
+	}
+	if err := c.resolveSecrets(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// readFile decodes a YAML or TOML file over c, rejecting unknown keys so a
+// misspelt setting is not silently ignored
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			keys := make([]string, len(undecoded))
+			for i, k := range undecoded {
+				keys[i] = k.String()
+			}
+			return fmt.Errorf("%s: unknown keys %s", path, strings.Join(keys, ", "))
+		}
+	default:
+		return fmt.Errorf("%w, got %q", ErrUnknownFormat, path)
+	}
+	return nil
+}
+
+// DSN returns URL, or builds one from the parts
+func (d Database) DSN() string {
+	if d.URL.Value() != "" {
+		return d.URL.Value()
+	}
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(d.User, d.Password.Value()),
+		Host:   net.JoinHostPort(d.Host, strconv.Itoa(d.Port)),
+		Path:   "/" + d.Name,
+	}
+	return u.String()
+}
+
+// JWKS returns where signing keys are read from: the URL, else the file
+func (o OIDC) JWKS() string {
+	if o.JWKSURL != "" {
+		return o.JWKSURL
+	}
+	return o.JWKSFile
+}
+
+// Print writes c as YAML with secrets redacted
+func (c *Config) Print(w io.Writer) error {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(c); err != nil {
+		return err
+	}
+	if err := enc.Close(); err != nil {
+		return err
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
@@ -0,0 +1,160 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFileThenEnv(t *testing.T) {
+	secret := writeFile(t, "db-password", "s3cret\n")
+	for name, content := range map[string]string{
+		"vest.yaml": "server:\n  port: 8081\n  shutdown_timeout: 10s\ndatabase:\n  host: db\n  port: 6432\n  user: vest\n  password: file:" + secret + "\n  name: vest\nready:\n  require: [database]\n",
+		"vest.toml": "[server]\nport = 8081\nshutdown_timeout = \"10s\"\n[database]\nhost = \"db\"\nport = 6432\nuser = \"vest\"\npassword = \"file:" + secret + "\"\nname = \"vest\"\n[ready]\nrequire = [\"database\"]\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			t.Setenv("PORT", "9000")
+			t.Setenv("READY_REQUIRE", "database, freshness")
+			t.Setenv("TRACING_SAMPLE_RATIO", "0.25")
+			c, err := Load(writeFile(t, name, content))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Server.Port != 9000 || c.Server.ShutdownTimeout != 10*time.Second || c.Server.GRPCPort != 9090 {
+				t.Errorf("expected the env port over the file, the file timeout and the default gRPC port, got %+v", c.Server)
+			}
+			if !slices.Equal(c.Ready.Require, []string{"database", "freshness"}) {
+				t.Errorf("expected READY_REQUIRE split on commas, got %q", c.Ready.Require)
+			}
+			if c.Tracing.SampleRatio != 0.25 || c.Tracing.Exporter != "none" {
+				t.Errorf("expected the env sample ratio and no exporter by default, got %+v", c.Tracing)
+			}
+			if got := c.Database.DSN(); got != "postgres://vest:s3cret@db:6432/vest" {
+				t.Errorf("expected the password from its file and the configured port, got %s", got)
+			}
+		})
+	}
+}
+
+func TestSecretFileEnv(t *testing.T) {
+	t.Setenv("DATABASE_URL_FILE", writeFile(t, "url", "postgres://u:p@h/db\n"))
+	c, err := Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Database.URL.Value() != "postgres://u:p@h/db" {
+		t.Errorf("expected DATABASE_URL read from DATABASE_URL_FILE, got %q", c.Database.URL.Value())
+	}
+
+	t.Setenv("DATABASE_URL", "postgres://other")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), "not both") {
+		t.Errorf("expected an error for both DATABASE_URL and DATABASE_URL_FILE, got %v", err)
+	}
+}
+
+func TestDSNEscapesPassword(t *testing.T) {
+	d := Database{Host: "db", Port: 5432, User: "vest", Password: NewSecret("p@ss/word"), Name: "vest"}
+	if got := d.DSN(); got != "postgres://vest:p%40ss%2Fword@db:5432/vest" {
+		t.Errorf("got %s", got)
+	}
+}
+
+func TestValidateListsEveryProblem(t *testing.T) {
+	t.Setenv("PORT", "0")
+	t.Setenv("RATE_LIMITS", "*=fast")
+	t.Setenv("OIDC_ISSUER", "https://idp.example.com")
+	t.Setenv("TRACING_EXPORTER", "jaeger")
+	_, err := Load("")
+	if !errors.Is(err, ErrInvalid) {
+		t.Fatalf("expected ErrInvalid, got %v", err)
+	}
+	for _, want := range []string{
+		"server.port (PORT): must be between 1 and 65535, got 0",
+		"rate_limit.rules (RATE_LIMITS)",
+		"oidc.audience (OIDC_AUDIENCE): required with oidc.issuer",
+		"database.url (DATABASE_URL): required unless",
+		`tracing.exporter (TRACING_EXPORTER): must be none, otlp or stdout, got "jaeger"`,
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected %q in %v", want, err)
+		}
+	}
+
+	t.Setenv("SHUTDOWN_TIMEOUT", "soon")
+	if _, err := Load(""); err == nil || !strings.Contains(err.Error(), `SHUTDOWN_TIMEOUT: invalid duration "soon"`) {
+		t.Errorf("expected a parse error naming the variable, got %v", err)
+	}
+}
+
+func TestUnknownKeysRejected(t *testing.T) {
+	for name, content := range map[string]string{
+		"vest.yaml": "server:\n  prot: 8081\n",
+		"vest.toml": "[server]\nprot = 8081\n",
+	} {
+		if _, err := Read(writeFile(t, name, content)); err == nil || !strings.Contains(err.Error(), "prot") {
+			t.Errorf("%s: expected the misspelt key to be rejected, got %v", name, err)
+		}
+	}
+	if _, err := Read(writeFile(t, "vest.json", "{}")); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
+
+func TestPrintRedactsSecrets(t *testing.T) {
+	c := Default()
+	c.Database.URL = NewSecret("postgres://vest:hunter2@db/vest")
+	c.Auth.APIKey = NewSecret("hunter2")
+	if err := c.SFTP.Password.UnmarshalText([]byte("file:/run/secrets/sftp")); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := c.Print(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("printed a secret:\n%s", out)
+	}
+	for _, want := range []string{"url: " + Redacted, "api_key: " + Redacted, "password: file:/run/secrets/sftp", "shutdown_timeout: 25s"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestReloadAppliesSafeFields(t *testing.T) {
+	running := Default()
+	next := Default()
+	next.Log.Level = "debug"
+	next.RateLimit.Rules = "*=5/s"
+	next.Ready.MaxDataAge = time.Hour
+	next.Server.Port = 8081
+
+	applied, reloaded, restart := Reload(running, next)
+	if !slices.Equal(reloaded, []string{"log.level", "rate_limit.rules", "ready.max_data_age"}) {
+		t.Errorf("reloaded %q", reloaded)
+	}
+	if !slices.Equal(restart, []string{"server.port"}) {
+		t.Errorf("restart %q", restart)
+	}
+	if applied.Log.Level != "debug" || applied.Ready.MaxDataAge != time.Hour || applied.Server.Port != 8080 {
+		t.Errorf("expected only the safe fields applied, got %+v", applied)
+	}
+	if running.Log.Level != "info" {
+		t.Error("Reload must not change the running config")
+	}
+}
@@ -0,0 +1,202 @@
+package config
+
+import (
+	"encoding"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Redacted replaces a secret's value when printed
+const Redacted = "REDACTED"
+
+// filePrefix marks a secret held in a file, as in "file:/run/secrets/db"
+const filePrefix = "file:"
+
+// Secret is a value, such as a password, that is never printed. Written as
+// file:<path> it is read from that file when the config loads, and the env
+// var <NAME>_FILE sets the path the same way.
+type Secret struct {
+	value string
+	file  string
+}
+
+// NewSecret returns a secret holding v
+func NewSecret(v string) Secret {
+	return Secret{value: v}
+}
+
+// Value returns the secret itself
+func (s Secret) Value() string {
+	return s.value
+}
+
+func (s *Secret) UnmarshalText(text []byte) error {
+	if path, ok := strings.CutPrefix(string(text), filePrefix); ok {
+		*s = Secret{file: path}
+		return nil
+	}
+	*s = Secret{value: string(text)}
+	return nil
+}
+
+// MarshalText shows where a file secret comes from, but never a value
+func (s Secret) MarshalText() ([]byte, error) {
+	switch {
+	case s.file != "":
+		return []byte(filePrefix + s.file), nil
+	case s.value != "":
+		return []byte(Redacted), nil
+	}
+	return nil, nil
+}
+
+func (s Secret) String() string {
+	b, _ := s.MarshalText()
+	return string(b)
+}
+
+// read loads a file secret, dropping the trailing newline most editors and
+// secret mounts leave
+func (s *Secret) read() error {
+	if s.file == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.file)
+	if err != nil {
+		return err
+	}
+	s.value = strings.TrimRight(string(b), "\r\n")
+	return nil
+}
+
+var (
+	secretType   = reflect.TypeFor[Secret]()
+	durationType = reflect.TypeFor[time.Duration]()
+)
+
+// field is a setting, named by its dotted file key
+type field struct {
+	key    string
+	env    string
+	reload bool
+	value  reflect.Value
+}
+
+// fields lists the settings in c in declaration order
+func (c *Config) fields() []field {
+	var out []field
+	var walk func(v reflect.Value, prefix string)
+	walk = func(v reflect.Value, prefix string) {
+		for i := range v.NumField() {
+			sf := v.Type().Field(i)
+			key := prefix + sf.Tag.Get("yaml")
+			if sf.Type.Kind() == reflect.Struct && sf.Type != secretType {
+				walk(v.Field(i), key+".")
+				continue
+			}
+			out = append(out, field{key: key, env: sf.Tag.Get("env"), reload: sf.Tag.Get("reload") == "true", value: v.Field(i)})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), "")
+	return out
+}
+
+// applyEnv overrides c with each variable lookup finds set and not empty
+func (c *Config) applyEnv(lookup func(string) (string, bool)) error {
+	var problems []string
+	for _, f := range c.fields() {
+		if f.env == "" {
+			continue
+		}
+		v, _ := lookup(f.env)
+		if f.value.Type() == secretType {
+			if path, _ := lookup(f.env + "_FILE"); path != "" {
+				if v != "" {
+					problems = append(problems, fmt.Sprintf("%s: set %s or %s_FILE, not both", f.key, f.env, f.env))
+					continue
+				}
+				v = filePrefix + path
+			}
+		}
+		if v == "" {
+			continue
+		}
+		if err := set(f.value, v); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", f.env, err))
+		}
+	}
+	return invalid(problems)
+}
+
+// set parses v into a field
+func set(field reflect.Value, v string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(v))
+	}
+	if field.Type() == durationType {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid duration %q, want a value such as 30s or 5m", v)
+		}
+		field.SetInt(int64(d))
+		return nil
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(v)
+	case reflect.Int:
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", v)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", v)
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q, want true or false", v)
+		}
+		field.SetBool(b)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(v, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		field.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported type %s", field.Type())
+	}
+	return nil
+}
+
+// resolveSecrets reads secrets held in files
+func (c *Config) resolveSecrets() error {
+	var problems []string
+	for _, f := range c.fields() {
+		if s, ok := f.value.Addr().Interface().(*Secret); ok {
+			if err := s.read(); err != nil {
+				problems = append(problems, fmt.Sprintf("%s: %v", f.key, err))
+			}
+		}
+	}
+	return invalid(problems)
+}
+
+// invalid returns one error listing every problem, or nil
+func invalid(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrInvalid, strings.Join(problems, "; "))
+}
@@ -0,0 +1,24 @@
+package config
+
+import "reflect"
+
+// Reload takes the settings a SIGHUP may change from next, returning the
+// config now in effect. reloaded lists the keys it changed, and restart those
+// that differ but only take effect when the server restarts.
+func Reload(running, next *Config) (applied *Config, reloaded, restart []string) {
+	applied = new(Config)
+	*applied = *running
+	current, updated := applied.fields(), next.fields()
+	for i, f := range current {
+		if reflect.DeepEqual(f.value.Interface(), updated[i].value.Interface()) {
+			continue
+		}
+		if !f.reload {
+			restart = append(restart, f.key)
+			continue
+		}
+		f.value.Set(updated[i].value)
+		reloaded = append(reloaded, f.key)
+	}
+	return applied, reloaded, restart
+}
@@ -0,0 +1,128 @@
+package config
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/health"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+)
+
+// Validate checks every setting, returning one error that lists each problem
+// by file key and env var
+func (c *Config) Validate() error {
+	envs := make(map[string]string)
+	for _, f := range c.fields() {
+		envs[f.key] = f.env
+	}
+	var problems []string
+	report := func(key, format string, args ...any) {
+		name := key
+		if env := envs[key]; env != "" {
+			name += " (" + env + ")"
+		}
+		problems = append(problems, name+": "+fmt.Sprintf(format, args...))
+	}
+
+	for key, port := range map[string]int{"server.port": c.Server.Port, "server.grpc_port": c.Server.GRPCPort, "database.port": c.Database.Port} {
+		if port < 1 || port > 65535 {
+			report(key, "must be between 1 and 65535, got %d", port)
+		}
+	}
+	if c.Server.Port == c.Server.GRPCPort {
+		report("server.grpc_port", "must differ from server.port %d", c.Server.Port)
+	}
+	if c.Server.ShutdownTimeout <= 0 {
+		report("server.shutdown_timeout", "must be positive, got %s", c.Server.ShutdownTimeout)
+	}
+
+	if _, err := logging.ParseLevel(c.Log.Level); err != nil {
+		report("log.level", "%v", err)
+	}
+	if !logging.ValidFormat(c.Log.Format) {
+		report("log.format", "must be json or text, got %q", c.Log.Format)
+	}
+
+	if c.Database.URL.Value() == "" {
+		var missing []string
+		for key, v := range map[string]string{"database.host": c.Database.Host, "database.user": c.Database.User, "database.password": c.Database.Password.Value(), "database.name": c.Database.Name} {
+			if v == "" {
+				missing = append(missing, key+" ("+envs[key]+")")
+			}
+		}
+		if len(missing) > 0 {
+			report("database.url", "required unless host, user, password and name are set; missing %s", strings.Join(sorted(missing), ", "))
+		}
+	}
+
+	if c.OIDC.Issuer != "" {
+		if c.OIDC.Audience == "" {
+			report("oidc.audience", "required with oidc.issuer")
+		}
+		if c.OIDC.JWKS() == "" {
+			report("oidc.jwks_url", "required with oidc.issuer, or set oidc.jwks_file")
+		}
+	}
+	if _, err := auth.ParseRoleScopes(c.OIDC.RoleScopes); err != nil {
+		report("oidc.role_scopes", "%v", err)
+	}
+
+	if c.RateLimit.Rules != "off" {
+		if _, err := ratelimit.ParseRules(c.RateLimit.Rules); err != nil {
+			report("rate_limit.rules", "%v", err)
+		}
+	}
+	if c.RateLimit.Store != "memory" && c.RateLimit.Store != "postgres" {
+		report("rate_limit.store", "must be memory or postgres, got %q", c.RateLimit.Store)
+	}
+
+	if c.Alarms.EvalInterval <= 0 {
+		report("alarms.eval_interval", "must be positive, got %s", c.Alarms.EvalInterval)
+	}
+	if _, err := compliance.ParseExposure(c.Alarms.Exposure); err != nil {
+		report("alarms.exposure", "%v, got %q", err, c.Alarms.Exposure)
+	}
+
+	if _, err := health.ParseRequired(strings.Join(c.Ready.Require, ",")); err != nil {
+		report("ready.require", "%v", err)
+	}
+	if c.Ready.MaxIngestAge <= 0 {
+		report("ready.max_ingest_age", "must be positive, got %s", c.Ready.MaxIngestAge)
+	}
+	if c.Ready.MaxDataAge <= 0 {
+		report("ready.max_data_age", "must be positive, got %s", c.Ready.MaxDataAge)
+	}
+
+	if c.SFTP.Host != "" && c.SFTP.User == "" {
+		report("sftp.user", "required with sftp.host")
+	}
+
+	if !tracing.ValidExporter(c.Tracing.Exporter) {
+		report("tracing.exporter", "must be none, otlp or stdout, got %q", c.Tracing.Exporter)
+	}
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+			report("tracing.endpoint", "must be a URL such as http://localhost:4318, got %q", c.Tracing.Endpoint)
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		report("tracing.sample_ratio", "must be between 0 and 1, got %g", c.Tracing.SampleRatio)
+	}
+	if c.Tracing.ServiceName == "" {
+		report("tracing.service_name", "required")
+	}
+
+	return invalid(sorted(problems))
+}
+
+// sorted returns s in order, for stable messages
+func sorted(s []string) []string {
+	slices.Sort(s)
+	return s
+}
@@ -0,0 +1,31 @@
+// Package database opens the Postgres connection shared by the server and
+// the maintenance commands.
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+
+	"github.com/AndrewCharlesHay/vest/internal/config"
+	"github.com/XSAM/otelsql"
+	_ "github.com/jackc/pgx/v5/stdlib" // PG driver
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Open opens a pool for the configured database. Queries run within a span
+// get a child span of their own; pings, session resets and row iteration
+// don't, nor do queries outside a trace such as the pool's housekeeping.
+func Open(c config.Database) (*sql.DB, error) {
+	return otelsql.Open("pgx", c.DSN(),
+		otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL),
+		otelsql.WithSpanOptions(otelsql.SpanOptions{
+			OmitConnResetSession: true,
+			OmitRows:             true,
+			SpanFilter: func(ctx context.Context, _ otelsql.Method, _ string, _ []driver.NamedValue) bool {
+				return trace.SpanContextFromContext(ctx).IsValid()
+			},
+		}),
+	)
+}
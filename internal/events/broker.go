@@ -0,0 +1,267 @@
+// Package events fans change notifications out to /events subscribers.
+// Events are stored in Postgres and announced with NOTIFY, so every server
+// instance sees events published by any other and clients can resume from
+// the last id they saw.
+package events
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+var logger = logging.Component("events")
+
+// Event types
+const (
+	FileIngested     = "file.ingested"
+	PositionsChanged = "positions.changed"
+	AlarmOpened      = "alarm.opened"
+	AlarmCleared     = "alarm.cleared"
+)
+
+// notifyChannel is the Postgres LISTEN/NOTIFY channel carrying new event ids
+const notifyChannel = "vest_events"
+
+// subscriberBuffer is how many events a slow client may fall behind before it
+// is dropped; it reconnects with Last-Event-ID and replays from the table
+const subscriberBuffer = 64
+
+// Subscription receives events broadcast after it was created. C is closed
+// if the subscriber falls too far behind.
+type Subscription struct {
+	C <-chan models.Event
+	c chan models.Event
+}
+
+// Broker stores published events and broadcasts them to local subscribers
+type Broker struct {
+	DB           *sql.DB
+	PollInterval time.Duration // Catch-up poll if a notification is missed
+	Retention    time.Duration // How long events remain available for replay
+
+	mu      sync.Mutex
+	subs    map[*Subscription]struct{}
+	lastID  int64 // Highest id broadcast so far
+	stopped bool  // Start has returned; new subscriptions end at once
+}
+
+func NewBroker(db *sql.DB) *Broker {
+	return &Broker{
+		DB:           db,
+		PollInterval: 30 * time.Second,
+		Retention:    24 * time.Hour,
+		subs:         make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish stores an event and notifies every listening instance
+func (b *Broker) Publish(ctx context.Context, eventType string, data any) error {
+	body, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	tx, err := b.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	// Serialise publishers so ids commit in order; otherwise a listener could
+	// read id N+1 before N commits and never see N
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1))`, notifyChannel); err != nil {
+		return err
+	}
+	var id int64
+	if err := tx.QueryRowContext(ctx, `INSERT INTO events (type, data) VALUES ($1, $2) RETURNING id`, eventType, body).Scan(&id); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `SELECT pg_notify($1, $2)`, notifyChannel, fmt.Sprint(id)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Since returns up to limit stored events after id, oldest first
+func (b *Broker) Since(ctx context.Context, id int64, limit int) ([]models.Event, error) {
+	rows, err := b.DB.QueryContext(ctx, `
+		SELECT id, type, data, created_at FROM events
+		WHERE id > $1
+		ORDER BY id
+		LIMIT $2
+	`, id, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []models.Event
+	for rows.Next() {
+		var e models.Event
+		var data []byte
+		if err := rows.Scan(&e.ID, &e.Type, &data, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		e.Data = data
+		out = append(out, e)
+	}
+	return out, rows.Err()
+}
+
+// Subscribe registers a local subscriber; call Unsubscribe when done
+func (b *Broker) Subscribe() *Subscription {
+	c := make(chan models.Event, subscriberBuffer)
+	s := &Subscription{C: c, c: c}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.stopped {
+		close(c)
+		return s
+	}
+	b.subs[s] = struct{}{}
+	return s
+}
+
+func (b *Broker) Unsubscribe(s *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[s]; ok {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+// Broadcast delivers an event to every local subscriber, dropping any that are full
+func (b *Broker) Broadcast(e models.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if e.ID > b.lastID {
+		b.lastID = e.ID
+	}
+	for s := range b.subs {
+		select {
+		case s.c <- e:
+		default:
+			logger.Warn("Dropping slow event subscriber", "event_id", e.ID)
+			delete(b.subs, s)
+			close(s.c)
+		}
+	}
+}
+
+// Start listens for notifications and broadcasts new events until ctx is
+// cancelled, then ends every subscription so streaming clients disconnect
+// and resume from another instance
+func (b *Broker) Start(ctx context.Context) {
+	defer b.stop()
+
+	// Only events published from now on are broadcast; older ones are replayed on request
+	var latest int64
+	if err := b.DB.QueryRowContext(ctx, `SELECT COALESCE(MAX(id), 0) FROM events`).Scan(&latest); err != nil {
+		logger.ErrorContext(ctx, "Failed to read latest event id", "error", err)
+	}
+	b.mu.Lock()
+	b.lastID = latest
+	b.mu.Unlock()
+
+	for ctx.Err() == nil {
+		if err := b.listen(ctx); err != nil && ctx.Err() == nil {
+			logger.ErrorContext(ctx, "Event listener failed, retrying in 5s", "error", err)
+			select {
+			case <-ctx.Done():
+			case <-time.After(5 * time.Second):
+			}
+		}
+	}
+}
+
+func (b *Broker) stop() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.stopped = true
+	for s := range b.subs {
+		delete(b.subs, s)
+		close(s.c)
+	}
+}
+
+// listen holds one connection in LISTEN mode, polling for new events on
+// each notification and every PollInterval
+func (b *Broker) listen(ctx context.Context) error {
+	conn, err := b.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("LISTEN needs the pgx driver, got %T", driverConn)
+		}
+		if _, err := pc.Conn().Exec(ctx, "LISTEN "+notifyChannel); err != nil {
+			return err
+		}
+
+		lastPrune := time.Time{}
+		for {
+			// Catch up first: covers events published before LISTEN took effect
+			if err := b.poll(ctx); err != nil {
+				return err
+			}
+			if time.Since(lastPrune) > time.Hour {
+				b.prune(ctx)
+				lastPrune = time.Now()
+			}
+
+			waitCtx, cancel := context.WithTimeout(ctx, b.PollInterval)
+			_, err := pc.Conn().WaitForNotification(waitCtx)
+			cancel()
+			if ctx.Err() != nil {
+				return nil
+			}
+			if err != nil && !errors.Is(err, context.DeadlineExceeded) {
+				return err
+			}
+		}
+	})
+}
+
+// poll broadcasts every stored event newer than the last one broadcast
+func (b *Broker) poll(ctx context.Context) error {
+	for {
+		b.mu.Lock()
+		last := b.lastID
+		b.mu.Unlock()
+
+		batch, err := b.Since(ctx, last, 500)
+		if err != nil {
+			return err
+		}
+		for _, e := range batch {
+			b.Broadcast(e)
+		}
+		if len(batch) < 500 {
+			return nil
+		}
+	}
+}
+
+func (b *Broker) prune(ctx context.Context) {
+	if _, err := b.DB.ExecContext(ctx, `DELETE FROM events WHERE created_at < $1`, time.Now().Add(-b.Retention)); err != nil {
+		logger.ErrorContext(ctx, "Failed to prune events", "error", err)
+	}
+}
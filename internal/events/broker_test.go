@@ -0,0 +1,57 @@
+package events
+
+import (
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+func TestBroadcast(t *testing.T) {
+	b := NewBroker(nil)
+	a, c := b.Subscribe(), b.Subscribe()
+	defer b.Unsubscribe(a)
+	defer b.Unsubscribe(c)
+
+	b.Broadcast(models.Event{ID: 7, Type: AlarmOpened, Data: []byte(`{}`)})
+	for _, s := range []*Subscription{a, c} {
+		if e := <-s.C; e.ID != 7 || e.Type != AlarmOpened {
+			t.Errorf("unexpected event %+v", e)
+		}
+	}
+	if b.lastID != 7 {
+		t.Errorf("expected last broadcast id 7, got %d", b.lastID)
+	}
+}
+
+func TestBroadcastDropsSlowSubscriber(t *testing.T) {
+	b := NewBroker(nil)
+	slow := b.Subscribe()
+
+	for i := 1; i <= subscriberBuffer+1; i++ {
+		b.Broadcast(models.Event{ID: int64(i), Type: PositionsChanged})
+	}
+
+	n := 0
+	for range slow.C {
+		n++
+	}
+	if n != subscriberBuffer {
+		t.Errorf("expected %d buffered events before close, got %d", subscriberBuffer, n)
+	}
+	// Unsubscribing after a drop must not close the channel twice
+	b.Unsubscribe(slow)
+}
+
+func TestStopEndsSubscriptions(t *testing.T) {
+	b := NewBroker(nil)
+	before := b.Subscribe()
+	b.stop()
+	after := b.Subscribe()
+
+	for _, s := range []*Subscription{before, after} {
+		if _, ok := <-s.C; ok {
+			t.Error("expected the subscription to be closed")
+		}
+		b.Unsubscribe(s)
+	}
+}
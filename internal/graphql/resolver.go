@@ -0,0 +1,461 @@
+// Package graphql exposes the read API as a GraphQL schema. Resolvers call
+// the same query methods as the REST handlers in internal/api.
+package graphql
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AndrewCharlesHay/vest/internal/api"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	graphql "github.com/graph-gophers/graphql-go"
+	"github.com/graph-gophers/graphql-go/relay"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+// Source is the query layer resolvers read from; *api.Handler implements it
+type Source interface {
+	QueryBlotter(ctx context.Context, f api.Filter) ([]models.BlotterResponse, error)
+	QueryPositions(ctx context.Context, o api.PositionOptions) ([]models.PositionResponse, error)
+	QueryAlarms(ctx context.Context, o api.AlarmOptions) ([]models.AlarmResponse, error)
+	QueryAccounts(ctx context.Context, id string) ([]models.Account, error)
+	QuerySecurities(ctx context.Context, assetClass, sector, issuer string) ([]models.Security, error)
+	QuerySecurity(ctx context.Context, id string) (*models.Security, error)
+}
+
+// NewHandler parses the schema and returns an HTTP handler for POST /graphql
+func NewHandler(src Source) (http.Handler, error) {
+	schema, err := graphql.ParseSchema(schemaSDL, &Resolver{src: src})
+	if err != nil {
+		return nil, err
+	}
+	gql := &relay.Handler{Schema: schema}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "GraphQL queries must be POSTed", http.StatusMethodNotAllowed)
+			return
+		}
+		// Account and security lookups are memoised for the life of one request
+		ctx := context.WithValue(r.Context(), loaderKey{}, newLoader(r.Context(), src))
+		gql.ServeHTTP(w, r.WithContext(ctx))
+	}), nil
+}
+
+type loaderKey struct{}
+
+// loader caches connected lookups so a list of N rows costs one query per
+// distinct key. It is scoped to one request, and so to one caller.
+type loader struct {
+	ctx        context.Context
+	src        Source
+	mu         sync.Mutex
+	accounts   map[string]*models.Account
+	securities map[string]*models.Security
+}
+
+func newLoader(ctx context.Context, src Source) *loader {
+	return &loader{ctx: ctx, src: src, accounts: map[string]*models.Account{}, securities: map[string]*models.Security{}}
+}
+
+func loaderFrom(ctx context.Context, src Source) *loader {
+	if l, ok := ctx.Value(loaderKey{}).(*loader); ok {
+		return l
+	}
+	return newLoader(ctx, src)
+}
+
+func (l *loader) account(id string) (*models.Account, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if a, ok := l.accounts[id]; ok {
+		return a, nil
+	}
+	var acc *models.Account
+	accounts, err := l.src.QueryAccounts(l.ctx, id)
+	switch {
+	case errors.Is(err, api.ErrNotFound):
+	case err != nil:
+		return nil, err
+	case len(accounts) > 0:
+		acc = &accounts[0]
+	}
+	l.accounts[id] = acc
+	return acc, nil
+}
+
+func (l *loader) security(id string) (*models.Security, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if s, ok := l.securities[id]; ok {
+		return s, nil
+	}
+	s, err := l.src.QuerySecurity(l.ctx, id)
+	if errors.Is(err, api.ErrNotFound) {
+		s, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	l.securities[id] = s
+	return s, nil
+}
+
+// Resolver is the root Query resolver
+type Resolver struct {
+	src Source
+}
+
+// exposureArg converts the Exposure enum (defaulted to NET by the schema)
+func exposureArg(e string) (compliance.Exposure, error) {
+	return compliance.ParseExposure(strings.ToLower(e))
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+type blotterArgs struct {
+	Date      string
+	AccountID *string
+	Group     *string
+}
+
+func (r *Resolver) Blotter(ctx context.Context, args blotterArgs) ([]*blotterRowResolver, error) {
+	rows, err := r.src.QueryBlotter(ctx, api.Filter{Date: args.Date, AccountID: deref(args.AccountID), Group: deref(args.Group)})
+	if err != nil {
+		return nil, err
+	}
+	return r.blotterRows(rows), nil
+}
+
+func (r *Resolver) blotterRows(rows []models.BlotterResponse) []*blotterRowResolver {
+	out := make([]*blotterRowResolver, len(rows))
+	for i := range rows {
+		out[i] = &blotterRowResolver{src: r.src, row: rows[i]}
+	}
+	return out
+}
+
+type positionsArgs struct {
+	Date      string
+	Exposure  string
+	GroupBy   *string
+	AccountID *string
+	Group     *string
+}
+
+func (r *Resolver) Positions(ctx context.Context, args positionsArgs) ([]*positionResolver, error) {
+	exposure, err := exposureArg(args.Exposure)
+	if err != nil {
+		return nil, err
+	}
+	positions, err := r.src.QueryPositions(ctx, api.PositionOptions{
+		Filter:   api.Filter{Date: args.Date, AccountID: deref(args.AccountID), Group: deref(args.Group)},
+		Exposure: exposure,
+		GroupBy:  deref(args.GroupBy),
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*positionResolver, len(positions))
+	for i := range positions {
+		out[i] = &positionResolver{src: r.src, p: positions[i]}
+	}
+	return out, nil
+}
+
+type alarmsArgs struct {
+	Date       string
+	Exposure   string
+	IncludeAll bool
+	AccountID  *string
+	Group      *string
+}
+
+func (r *Resolver) Alarms(ctx context.Context, args alarmsArgs) ([]*alarmResolver, error) {
+	exposure, err := exposureArg(args.Exposure)
+	if err != nil {
+		return nil, err
+	}
+	alarms, err := r.src.QueryAlarms(ctx, api.AlarmOptions{
+		Filter:     api.Filter{Date: args.Date, AccountID: deref(args.AccountID), Group: deref(args.Group)},
+		Exposure:   exposure,
+		IncludeAll: args.IncludeAll,
+	})
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*alarmResolver, len(alarms))
+	for i := range alarms {
+		out[i] = &alarmResolver{src: r.src, a: alarms[i]}
+	}
+	return out, nil
+}
+
+func (r *Resolver) Accounts(ctx context.Context) ([]*accountResolver, error) {
+	accounts, err := r.src.QueryAccounts(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*accountResolver, len(accounts))
+	for i := range accounts {
+		out[i] = &accountResolver{src: r.src, a: accounts[i]}
+	}
+	return out, nil
+}
+
+func (r *Resolver) Account(ctx context.Context, args struct{ ID string }) (*accountResolver, error) {
+	return resolveAccount(ctx, r.src, args.ID)
+}
+
+type securitiesArgs struct {
+	AssetClass *string
+	Sector     *string
+	Issuer     *string
+}
+
+func (r *Resolver) Securities(ctx context.Context, args securitiesArgs) ([]*securityResolver, error) {
+	securities, err := r.src.QuerySecurities(ctx, deref(args.AssetClass), deref(args.Sector), deref(args.Issuer))
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*securityResolver, len(securities))
+	for i := range securities {
+		out[i] = &securityResolver{s: securities[i]}
+	}
+	return out, nil
+}
+
+func (r *Resolver) Security(ctx context.Context, args struct{ ID string }) (*securityResolver, error) {
+	return resolveSecurity(ctx, r.src, args.ID)
+}
+
+func resolveAccount(ctx context.Context, src Source, id string) (*accountResolver, error) {
+	a, err := loaderFrom(ctx, src).account(id)
+	if err != nil || a == nil {
+		return nil, err
+	}
+	return &accountResolver{src: src, a: *a}, nil
+}
+
+func resolveSecurity(ctx context.Context, src Source, id string) (*securityResolver, error) {
+	s, err := loaderFrom(ctx, src).security(id)
+	if err != nil || s == nil {
+		return nil, err
+	}
+	return &securityResolver{s: *s}, nil
+}
+
+type blotterRowResolver struct {
+	src Source
+	row models.BlotterResponse
+}
+
+func (b *blotterRowResolver) Date() string         { return b.row.Date }
+func (b *blotterRowResolver) AccountID() string    { return b.row.AccountID }
+func (b *blotterRowResolver) Ticker() string       { return b.row.Ticker }
+func (b *blotterRowResolver) Quantity() float64    { return b.row.Quantity }
+func (b *blotterRowResolver) MarketValue() float64 { return b.row.MarketValue }
+
+func (b *blotterRowResolver) Account(ctx context.Context) (*accountResolver, error) {
+	return resolveAccount(ctx, b.src, b.row.AccountID)
+}
+
+func (b *blotterRowResolver) Security(ctx context.Context) (*securityResolver, error) {
+	return resolveSecurity(ctx, b.src, b.row.Ticker)
+}
+
+type positionResolver struct {
+	src Source
+	p   models.PositionResponse
+}
+
+func (p *positionResolver) AccountID() string { return p.p.AccountID }
+func (p *positionResolver) GroupID() *string  { return optional(p.p.GroupID) }
+func (p *positionResolver) Exposure() string  { return strings.ToUpper(p.p.Exposure) }
+func (p *positionResolver) GroupBy() *string  { return optional(p.p.GroupBy) }
+func (p *positionResolver) Total() float64    { return p.p.Total }
+func (p *positionResolver) Warning() *string  { return optional(p.p.Warning) }
+
+// Allocations are returned largest first
+func (p *positionResolver) Allocations() []*allocationResolver {
+	out := make([]*allocationResolver, 0, len(p.p.Allocations))
+	for key, pct := range p.p.Allocations {
+		out = append(out, &allocationResolver{src: p.src, key: key, percent: pct, byTicker: p.p.GroupBy == "" || p.p.GroupBy == "ticker"})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].percent != out[j].percent {
+			return out[i].percent > out[j].percent
+		}
+		return out[i].key < out[j].key
+	})
+	return out
+}
+
+func (p *positionResolver) Account(ctx context.Context) (*accountResolver, error) {
+	if p.p.GroupID != "" {
+		return nil, nil
+	}
+	return resolveAccount(ctx, p.src, p.p.AccountID)
+}
+
+type allocationResolver struct {
+	src      Source
+	key      string
+	percent  float64
+	byTicker bool
+}
+
+func (a *allocationResolver) Key() string      { return a.key }
+func (a *allocationResolver) Percent() float64 { return a.percent }
+
+func (a *allocationResolver) Security(ctx context.Context) (*securityResolver, error) {
+	if !a.byTicker {
+		return nil, nil
+	}
+	return resolveSecurity(ctx, a.src, a.key)
+}
+
+type alarmResolver struct {
+	src Source
+	a   models.AlarmResponse
+}
+
+func (a *alarmResolver) Date() string           { return a.a.Date }
+func (a *alarmResolver) AccountID() string      { return a.a.AccountID }
+func (a *alarmResolver) GroupID() *string       { return optional(a.a.GroupID) }
+func (a *alarmResolver) Exposure() string       { return strings.ToUpper(a.a.Exposure) }
+func (a *alarmResolver) HasViolation() bool     { return a.a.HasViolation }
+func (a *alarmResolver) ViolationInfo() *string { return optional(a.a.ViolationInfo) }
+func (a *alarmResolver) Warning() *string       { return optional(a.a.Warning) }
+
+func (a *alarmResolver) Violations() []*violationResolver {
+	out := make([]*violationResolver, len(a.a.Violations))
+	for i := range a.a.Violations {
+		out[i] = &violationResolver{src: a.src, v: a.a.Violations[i]}
+	}
+	return out
+}
+
+func (a *alarmResolver) Account(ctx context.Context) (*accountResolver, error) {
+	if a.a.GroupID != "" {
+		return nil, nil
+	}
+	return resolveAccount(ctx, a.src, a.a.AccountID)
+}
+
+type violationResolver struct {
+	src Source
+	v   models.Violation
+}
+
+func (v *violationResolver) RuleID() string         { return v.v.RuleID }
+func (v *violationResolver) Ticker() string         { return v.v.Ticker }
+func (v *violationResolver) ObservedValue() float64 { return v.v.ObservedValue }
+func (v *violationResolver) Threshold() float64     { return v.v.Threshold }
+func (v *violationResolver) Severity() string       { return v.v.Severity }
+func (v *violationResolver) MarketValue() float64   { return v.v.MarketValue }
+func (v *violationResolver) AccountTotal() float64  { return v.v.AccountTotal }
+func (v *violationResolver) Message() string        { return v.v.Message }
+
+func (v *violationResolver) Security(ctx context.Context) (*securityResolver, error) {
+	return resolveSecurity(ctx, v.src, v.v.Ticker)
+}
+
+type accountResolver struct {
+	src Source
+	a   models.Account
+}
+
+func (a *accountResolver) AccountID() string     { return a.a.AccountID }
+func (a *accountResolver) Name() string          { return a.a.Name }
+func (a *accountResolver) Type() *string         { return optional(a.a.Type) }
+func (a *accountResolver) BaseCurrency() *string { return optional(a.a.BaseCurrency) }
+func (a *accountResolver) Status() string        { return a.a.Status }
+
+func (a *accountResolver) Aliases() []*aliasResolver {
+	out := make([]*aliasResolver, len(a.a.Aliases))
+	for i := range a.a.Aliases {
+		out[i] = &aliasResolver{a: a.a.Aliases[i]}
+	}
+	return out
+}
+
+func (a *accountResolver) Groups() []string {
+	if a.a.Groups == nil {
+		return []string{}
+	}
+	return a.a.Groups
+}
+
+func (a *accountResolver) Blotter(ctx context.Context, args struct{ Date string }) ([]*blotterRowResolver, error) {
+	rows, err := a.src.QueryBlotter(ctx, api.Filter{Date: args.Date, AccountID: a.a.AccountID})
+	if err != nil {
+		return nil, err
+	}
+	return (&Resolver{src: a.src}).blotterRows(rows), nil
+}
+
+func (a *accountResolver) Positions(ctx context.Context, args struct {
+	Date     string
+	Exposure string
+	GroupBy  *string
+}) (*positionResolver, error) {
+	accountID := a.a.AccountID
+	positions, err := (&Resolver{src: a.src}).Positions(ctx, positionsArgs{Date: args.Date, Exposure: args.Exposure, GroupBy: args.GroupBy, AccountID: &accountID})
+	if err != nil || len(positions) == 0 {
+		return nil, err
+	}
+	return positions[0], nil
+}
+
+func (a *accountResolver) Alarms(ctx context.Context, args struct {
+	Date     string
+	Exposure string
+}) (*alarmResolver, error) {
+	accountID := a.a.AccountID
+	alarms, err := (&Resolver{src: a.src}).Alarms(ctx, alarmsArgs{Date: args.Date, Exposure: args.Exposure, IncludeAll: true, AccountID: &accountID})
+	if err != nil || len(alarms) == 0 {
+		return nil, err
+	}
+	return alarms[0], nil
+}
+
+type aliasResolver struct {
+	a models.AccountAlias
+}
+
+func (a *aliasResolver) Alias() string      { return a.a.Alias }
+func (a *aliasResolver) Custodian() *string { return optional(a.a.Custodian) }
+
+type securityResolver struct {
+	s models.Security
+}
+
+func (s *securityResolver) Ticker() string      { return s.s.Ticker }
+func (s *securityResolver) Cusip() *string      { return optional(s.s.CUSIP) }
+func (s *securityResolver) Isin() *string       { return optional(s.s.ISIN) }
+func (s *securityResolver) Sedol() *string      { return optional(s.s.SEDOL) }
+func (s *securityResolver) Issuer() *string     { return optional(s.s.Issuer) }
+func (s *securityResolver) AssetClass() *string { return optional(s.s.AssetClass) }
+func (s *securityResolver) Sector() *string     { return optional(s.s.Sector) }
+func (s *securityResolver) Currency() *string   { return optional(s.s.Currency) }
+func (s *securityResolver) Multiplier() float64 { return s.s.Multiplier }
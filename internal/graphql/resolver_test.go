@@ -0,0 +1,155 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/api"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// fakeSource serves the sample report data without a database
+type fakeSource struct {
+	securityLookups int
+}
+
+func (f *fakeSource) QueryBlotter(ctx context.Context, flt api.Filter) ([]models.BlotterResponse, error) {
+	return []models.BlotterResponse{
+		{Date: flt.Date, AccountID: "ACC001", Ticker: "AAPL", Quantity: 100, MarketValue: 18550},
+		{Date: flt.Date, AccountID: "ACC001", Ticker: "MSFT", Quantity: 50, MarketValue: 21012.50},
+		{Date: flt.Date, AccountID: "ACC002", Ticker: "AAPL", Quantity: 200, MarketValue: 37100},
+	}, nil
+}
+
+func (f *fakeSource) QueryPositions(ctx context.Context, o api.PositionOptions) ([]models.PositionResponse, error) {
+	return []models.PositionResponse{{
+		AccountID:   "ACC001",
+		Exposure:    string(o.Exposure),
+		Total:       39562.50,
+		Allocations: map[string]float64{"AAPL": 46.89, "MSFT": 53.11},
+	}}, nil
+}
+
+func (f *fakeSource) QueryAlarms(ctx context.Context, o api.AlarmOptions) ([]models.AlarmResponse, error) {
+	holdings := []compliance.Holding{
+		{AccountID: "ACC001", Ticker: "AAPL", MarketValue: 18550},
+		{AccountID: "ACC001", Ticker: "MSFT", MarketValue: 21012.50},
+	}
+	return compliance.Evaluate(o.Date, holdings, compliance.Options{Exposure: o.Exposure, IncludeAll: o.IncludeAll}), nil
+}
+
+func (f *fakeSource) QueryAccounts(ctx context.Context, id string) ([]models.Account, error) {
+	if id != "" && id != "ACC001" {
+		return nil, fmt.Errorf("account %s %w", id, api.ErrNotFound)
+	}
+	return []models.Account{{AccountID: "ACC001", Name: "Smith Family", Status: "active",
+		Aliases: []models.AccountAlias{{Alias: "1001", Custodian: "CUSTODIAN_A"}}}}, nil
+}
+
+func (f *fakeSource) QuerySecurities(_ context.Context, assetClass, sector, issuer string) ([]models.Security, error) {
+	return []models.Security{{Ticker: "AAPL", Issuer: "Apple Inc", Multiplier: 1}}, nil
+}
+
+func (f *fakeSource) QuerySecurity(_ context.Context, id string) (*models.Security, error) {
+	f.securityLookups++
+	if id != "AAPL" {
+		return nil, fmt.Errorf("security %s %w", id, api.ErrNotFound)
+	}
+	return &models.Security{Ticker: "AAPL", Issuer: "Apple Inc", Sector: "Technology", Multiplier: 1}, nil
+}
+
+func execute(t *testing.T, src Source, query string) map[string]any {
+	t.Helper()
+	h, err := NewHandler(src)
+	if err != nil {
+		t.Fatalf("Schema failed to parse: %v", err)
+	}
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	var resp struct {
+		Data   map[string]any `json:"data"`
+		Errors []any          `json:"errors"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Invalid response %q: %v", rec.Body.String(), err)
+	}
+	if len(resp.Errors) > 0 {
+		t.Fatalf("Query errors: %v", resp.Errors)
+	}
+	return resp.Data
+}
+
+func TestConnectedQuery(t *testing.T) {
+	src := &fakeSource{}
+	data := execute(t, src, `{
+		blotter(date: "2025-01-15") { accountId ticker account { name } security { issuer } }
+		alarms(date: "2025-01-15", exposure: GROSS) { accountId exposure hasViolation violations { ticker severity } }
+	}`)
+
+	rows := data["blotter"].([]any)
+	if len(rows) != 3 {
+		t.Fatalf("Expected 3 blotter rows, got %d", len(rows))
+	}
+	first := rows[0].(map[string]any)
+	if first["account"].(map[string]any)["name"] != "Smith Family" {
+		t.Errorf("Expected connected account, got %v", first["account"])
+	}
+	if first["security"].(map[string]any)["issuer"] != "Apple Inc" {
+		t.Errorf("Expected connected security, got %v", first["security"])
+	}
+	if rows[1].(map[string]any)["security"] != nil {
+		t.Errorf("Expected null security for unknown ticker, got %v", rows[1])
+	}
+	if src.securityLookups != 2 {
+		t.Errorf("Expected lookups to be memoised per ticker, got %d", src.securityLookups)
+	}
+
+	alarms := data["alarms"].([]any)
+	alarm := alarms[0].(map[string]any)
+	if alarm["exposure"] != "GROSS" || alarm["hasViolation"] != true {
+		t.Errorf("Unexpected alarm: %v", alarm)
+	}
+}
+
+func TestAccountPositions(t *testing.T) {
+	data := execute(t, &fakeSource{}, `{
+		account(id: "ACC001") {
+			aliases { alias custodian }
+			positions(date: "2025-01-15") { total allocations { key percent } }
+		}
+		missing: account(id: "NOPE") { name }
+	}`)
+
+	acc := data["account"].(map[string]any)
+	allocs := acc["positions"].(map[string]any)["allocations"].([]any)
+	if allocs[0].(map[string]any)["key"] != "MSFT" {
+		t.Errorf("Expected largest allocation first, got %v", allocs)
+	}
+	if acc["aliases"].([]any)[0].(map[string]any)["alias"] != "1001" {
+		t.Errorf("Unexpected aliases: %v", acc["aliases"])
+	}
+	if data["missing"] != nil {
+		t.Errorf("Expected null for unknown account, got %v", data["missing"])
+	}
+}
+
+func TestRejectsGet(t *testing.T) {
+	h, err := NewHandler(&fakeSource{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/graphql", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("Expected 405, got %d", rec.Code)
+	}
+}
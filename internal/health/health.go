@@ -0,0 +1,306 @@
+// Package health serves liveness and readiness checks. Liveness only says the
+// process is serving; readiness checks the database, schema, SFTP session,
+// ingestion and data freshness, and fails with 503 when a required check does.
+package health
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/migrate"
+)
+
+// Check and report statuses. A failing required check makes the report
+// unavailable; any other failure only degrades it.
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+	StatusFailing     = "failing"
+	StatusDisabled    = "disabled"
+)
+
+// Check names
+const (
+	CheckDatabase   = "database"
+	CheckMigrations = "migrations"
+	CheckSFTP       = "sftp"
+	CheckIngestion  = "ingestion"
+	CheckFreshness  = "freshness"
+)
+
+var ErrUnknownCheck = errors.New("unknown health check")
+
+// checks lists every check name
+var checks = []string{CheckDatabase, CheckMigrations, CheckSFTP, CheckIngestion, CheckFreshness}
+
+// ParseRequired reads a comma-separated list of check names, such as
+// "database,migrations,ingestion"
+func ParseRequired(v string) (map[string]bool, error) {
+	required := make(map[string]bool)
+	for _, name := range strings.Split(v, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if !slices.Contains(checks, name) {
+			return nil, fmt.Errorf("%w: %q (want %s)", ErrUnknownCheck, name, strings.Join(checks, ", "))
+		}
+		required[name] = true
+	}
+	return required, nil
+}
+
+// Defaults for NewChecker
+const (
+	DefaultMaxIngestAge = 72 * time.Hour
+	DefaultMaxDataAge   = 96 * time.Hour
+	DefaultCacheFor     = 5 * time.Second
+	checkTimeout        = 3 * time.Second
+)
+
+// Checker runs the readiness checks
+type Checker struct {
+	DB *sql.DB
+
+	// Migration is the schema version this build expects
+	Migration int64
+
+	// SFTP is the ingestor's session; nil when ingestion is off, which
+	// disables the sftp and ingestion checks
+	SFTP *Session
+
+	// MaxIngestAge is how long after the last ingested file, and MaxDataAge
+	// how old a custodian's latest positions, before the report degrades
+	MaxIngestAge time.Duration
+	MaxDataAge   time.Duration
+
+	// Required names the checks that make the instance unready when failing
+	Required map[string]bool
+
+	// CacheFor reuses a report for frequent probes
+	CacheFor time.Duration
+
+	mu     sync.Mutex
+	cached *Report
+	at     time.Time
+}
+
+func NewChecker(db *sql.DB, migration int64) *Checker {
+	return &Checker{
+		DB:           db,
+		Migration:    migration,
+		MaxIngestAge: DefaultMaxIngestAge,
+		MaxDataAge:   DefaultMaxDataAge,
+		Required:     map[string]bool{CheckDatabase: true, CheckMigrations: true},
+		CacheFor:     DefaultCacheFor,
+	}
+}
+
+// Configure changes the required checks and age limits, taking effect from
+// the next report
+func (c *Checker) Configure(required map[string]bool, maxIngestAge, maxDataAge time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.Required, c.MaxIngestAge, c.MaxDataAge = required, maxIngestAge, maxDataAge
+	c.cached = nil
+}
+
+// Report is the readiness response
+type Report struct {
+	Status    string           `json:"status"`
+	CheckedAt time.Time        `json:"checked_at"`
+	Checks    map[string]Check `json:"checks"`
+}
+
+// Check is one dependency's result. Fields beyond Status depend on the check.
+type Check struct {
+	Status      string      `json:"status"`
+	Required    bool        `json:"required"`
+	Error       string      `json:"error,omitempty"`
+	LatencyMS   *int64      `json:"latency_ms,omitempty"`
+	Version     *int64      `json:"version,omitempty"`
+	Expected    *int64      `json:"expected,omitempty"`
+	Connected   *bool       `json:"connected,omitempty"`
+	Since       *time.Time  `json:"since,omitempty"`
+	LastSuccess *time.Time  `json:"last_success,omitempty"`
+	AgeSeconds  *int64      `json:"age_seconds,omitempty"`
+	Custodians  []Custodian `json:"custodians,omitempty"`
+}
+
+// Custodian is the latest position date loaded from one source system
+type Custodian struct {
+	Source     string `json:"source"`
+	LatestDate string `json:"latest_date"`
+	AgeHours   int64  `json:"age_hours"`
+	Status     string `json:"status"`
+}
+
+// Live reports that the process is up and serving requests
+func Live(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"status": StatusOK})
+}
+
+// Ready runs the checks, answering 503 when a required one fails
+func (c *Checker) Ready(w http.ResponseWriter, r *http.Request) {
+	report := c.Check(r.Context())
+	status := http.StatusOK
+	if report.Status == StatusUnavailable {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, report)
+}
+
+// Check returns a report no older than CacheFor
+func (c *Checker) Check(ctx context.Context) *Report {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cached != nil && time.Since(c.at) < c.CacheFor {
+		return c.cached
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, checkTimeout)
+	defer cancel()
+	now := time.Now()
+	report := &Report{Status: StatusOK, CheckedAt: now.UTC(), Checks: map[string]Check{
+		CheckDatabase:   c.database(ctx),
+		CheckMigrations: c.migrations(ctx),
+		CheckSFTP:       c.sftp(),
+		CheckIngestion:  c.ingestion(ctx, now),
+		CheckFreshness:  c.freshness(ctx, now),
+	}}
+	for name, check := range report.Checks {
+		check.Required = c.Required[name]
+		report.Checks[name] = check
+		if check.Status != StatusFailing {
+			continue
+		}
+		if check.Required {
+			report.Status = StatusUnavailable
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	c.cached, c.at = report, now
+	return report
+}
+
+func (c *Checker) database(ctx context.Context) Check {
+	start := time.Now()
+	if err := c.DB.PingContext(ctx); err != nil {
+		return failing(err)
+	}
+	ms := time.Since(start).Milliseconds()
+	return Check{Status: StatusOK, LatencyMS: &ms}
+}
+
+// migrations fails when the schema is behind this build. A newer schema is
+// fine, as during a rolling deploy.
+func (c *Checker) migrations(ctx context.Context) Check {
+	v, err := migrate.Current(ctx, c.DB)
+	if err != nil {
+		return failing(err)
+	}
+	check := Check{Status: StatusOK, Version: &v, Expected: &c.Migration}
+	if v < c.Migration {
+		check.Status, check.Error = StatusFailing, "schema is behind this build"
+	}
+	return check
+}
+
+func (c *Checker) sftp() Check {
+	if c.SFTP == nil {
+		return Check{Status: StatusDisabled}
+	}
+	connected, since, err := c.SFTP.State()
+	check := Check{Status: StatusOK, Connected: &connected}
+	if !since.IsZero() {
+		check.Since = &since
+	}
+	if !connected {
+		check.Status, check.Error = StatusFailing, "not connected"
+		if err != nil {
+			check.Error = err.Error()
+		}
+	}
+	return check
+}
+
+// ingestion reads the last ingested file from the audit log, so every
+// instance agrees however many ingest
+func (c *Checker) ingestion(ctx context.Context, now time.Time) Check {
+	if c.SFTP == nil {
+		return Check{Status: StatusDisabled}
+	}
+	var last sql.NullTime
+	err := c.DB.QueryRowContext(ctx, `
+		SELECT created_at FROM audit_log
+		WHERE principal = 'ingestor' AND action = 'file.ingested'
+		ORDER BY id DESC LIMIT 1`).Scan(&last)
+	if err != nil && err != sql.ErrNoRows {
+		return failing(err)
+	}
+	if !last.Valid {
+		return Check{Status: StatusFailing, Error: "no file has been ingested"}
+	}
+	age := int64(now.Sub(last.Time).Seconds())
+	check := Check{Status: StatusOK, LastSuccess: &last.Time, AgeSeconds: &age}
+	if now.Sub(last.Time) > c.MaxIngestAge {
+		check.Status, check.Error = StatusFailing, "last ingestion is older than "+c.MaxIngestAge.String()
+	}
+	return check
+}
+
+// freshness fails when any custodian's latest positions are older than
+// MaxDataAge
+func (c *Checker) freshness(ctx context.Context, now time.Time) Check {
+	rows, err := c.DB.QueryContext(ctx, `
+		SELECT COALESCE(source_system, ''), MAX(date) FROM positions
+		GROUP BY 1 ORDER BY 1`)
+	if err != nil {
+		return failing(err)
+	}
+	defer rows.Close()
+
+	check := Check{Status: StatusOK, Custodians: []Custodian{}}
+	for rows.Next() {
+		var source string
+		var latest time.Time
+		if err := rows.Scan(&source, &latest); err != nil {
+			return failing(err)
+		}
+		// A date's positions are complete at the end of that day
+		age := now.Sub(latest.AddDate(0, 0, 1))
+		cust := Custodian{Source: source, LatestDate: latest.Format("2006-01-02"), AgeHours: int64(max(age, 0).Hours()), Status: StatusOK}
+		if age > c.MaxDataAge {
+			cust.Status, check.Status = StatusFailing, StatusFailing
+			check.Error = "positions older than " + c.MaxDataAge.String()
+		}
+		check.Custodians = append(check.Custodians, cust)
+	}
+	if err := rows.Err(); err != nil {
+		return failing(err)
+	}
+	return check
+}
+
+func failing(err error) Check {
+	return Check{Status: StatusFailing, Error: err.Error()}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		return
+	}
+}
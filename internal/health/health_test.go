@@ -0,0 +1,95 @@
+package health
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	_ "github.com/jackc/pgx/v5/stdlib" // PG driver
+)
+
+func TestParseRequired(t *testing.T) {
+	required, err := ParseRequired("database, ingestion")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !required[CheckDatabase] || !required[CheckIngestion] || required[CheckMigrations] {
+		t.Errorf("Unexpected required checks %v", required)
+	}
+	if _, err := ParseRequired("database,disk"); !errors.Is(err, ErrUnknownCheck) {
+		t.Errorf("Expected ErrUnknownCheck, got %v", err)
+	}
+}
+
+func TestSessionObserve(t *testing.T) {
+	s := NewSession()
+	s.Observe(nil)
+	_, since, _ := s.State()
+	s.Observe(nil)
+	if _, again, _ := s.State(); !again.Equal(since) {
+		t.Error("Expected since to hold while the session stays connected")
+	}
+	s.Observe(errors.New("connection reset"))
+	connected, _, err := s.State()
+	if connected || err == nil {
+		t.Errorf("Expected a failed session, got connected=%v err=%v", connected, err)
+	}
+}
+
+// A database that refuses connections fails the required checks
+func TestReadyUnavailableWithoutDatabase(t *testing.T) {
+	db, err := sql.Open("pgx", "postgres://vest@127.0.0.1:1/vest?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c := NewChecker(db, 2)
+	c.SFTP = NewSession()
+
+	rec := httptest.NewRecorder()
+	c.Ready(rec, httptest.NewRequest("GET", "/readyz", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("Expected 503, got %d", rec.Code)
+	}
+	var report Report
+	if err := json.NewDecoder(rec.Body).Decode(&report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Status != StatusUnavailable {
+		t.Errorf("Expected unavailable, got %s", report.Status)
+	}
+	for _, name := range []string{CheckDatabase, CheckMigrations, CheckSFTP} {
+		if report.Checks[name].Status != StatusFailing {
+			t.Errorf("Expected %s to fail, got %+v", name, report.Checks[name])
+		}
+	}
+	if !report.Checks[CheckDatabase].Required || report.Checks[CheckSFTP].Required {
+		t.Errorf("Unexpected required flags %+v", report.Checks)
+	}
+
+	rec = httptest.NewRecorder()
+	Live(rec, httptest.NewRequest("GET", "/livez", nil))
+	if rec.Code != http.StatusOK {
+		t.Errorf("Expected liveness to pass regardless, got %d", rec.Code)
+	}
+}
+
+// Configure takes effect on the next probe, not after the cached report expires
+func TestConfigureClearsCache(t *testing.T) {
+	db, err := sql.Open("pgx", "postgres://vest@127.0.0.1:1/vest?connect_timeout=1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	c := NewChecker(db, 2)
+	if report := c.Check(t.Context()); report.Status != StatusUnavailable {
+		t.Fatalf("Expected unavailable, got %s", report.Status)
+	}
+	c.Configure(map[string]bool{}, DefaultMaxIngestAge, DefaultMaxDataAge)
+	if report := c.Check(t.Context()); report.Status != StatusDegraded {
+		t.Errorf("Expected degraded with nothing required, got %s", report.Status)
+	}
+}
@@ -0,0 +1,55 @@
+package health_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/events"
+	"github.com/AndrewCharlesHay/vest/internal/health"
+	"github.com/AndrewCharlesHay/vest/internal/migrate"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+func TestReadyReportsFreshness(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := health.NewChecker(db, migrate.NewMigrator(db, migrations).Latest())
+	c.SFTP = health.NewSession()
+	c.SFTP.Observe(nil)
+
+	today := time.Now().Format("2006-01-02")
+	if _, err := db.Exec(`
+		INSERT INTO positions (date, account_id, ticker, quantity, market_value, shares, source_system)
+		VALUES ($1, 'ACC-1', 'AAPL', 1, 100, 1, 'Fresh'), ('2020-01-02', 'ACC-1', 'MSFT', 1, 100, 1, 'Stale')`, today); err != nil {
+		t.Fatal(err)
+	}
+
+	report := c.Check(ctx)
+	if report.Status != health.StatusDegraded {
+		t.Errorf("Expected stale data and no ingestion to degrade the report, got %s", report.Status)
+	}
+	if got := report.Checks[health.CheckIngestion].Status; got != health.StatusFailing {
+		t.Errorf("Expected ingestion to fail before any file, got %s", got)
+	}
+	custodians := report.Checks[health.CheckFreshness].Custodians
+	if len(custodians) != 2 || custodians[0].Source != "Fresh" || custodians[0].Status != health.StatusOK || custodians[1].Status != health.StatusFailing {
+		t.Errorf("Unexpected custodians %+v", custodians)
+	}
+
+	if _, err := db.Exec(`DELETE FROM positions WHERE source_system = 'Stale'`); err != nil {
+		t.Fatal(err)
+	}
+	if err := audit.NewLogger(db).Change(ctx, "ingestor", events.FileIngested, nil, map[string]string{"file": "a.csv"}); err != nil {
+		t.Fatal(err)
+	}
+	c.CacheFor = 0
+	if report := c.Check(ctx); report.Status != health.StatusOK {
+		t.Errorf("Expected ok, got %s: %+v", report.Status, report.Checks)
+	}
+}
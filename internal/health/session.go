@@ -0,0 +1,37 @@
+package health
+
+import (
+	"sync"
+	"time"
+)
+
+// Session tracks whether the ingestor's SFTP session is working, as
+// reported after each connection attempt and poll
+type Session struct {
+	mu        sync.Mutex
+	connected bool
+	since     time.Time
+	err       error
+}
+
+func NewSession() *Session {
+	return &Session{}
+}
+
+// Observe records the outcome of a connection attempt or poll
+func (s *Session) Observe(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	connected := err == nil
+	if connected != s.connected || s.since.IsZero() {
+		s.since = time.Now().UTC()
+	}
+	s.connected, s.err = connected, err
+}
+
+// State returns whether the session works, since when, and the last error
+func (s *Session) State() (connected bool, since time.Time, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.connected, s.since, s.err
+}
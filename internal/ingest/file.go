@@ -0,0 +1,164 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/metrics"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrUnrecognized = errors.New("not a security master, format 1 or format 2 file")
+
+// Parsed is a file's records in the format it was recognised as. Only the
+// slice for Kind is set.
+type Parsed struct {
+	Kind       string
+	Source     string // Source system for metrics
+	Trades     []models.TradeRecord
+	Reports    []models.ReportRecord
+	Securities []models.Security
+	Rejected   []Rejection // Rows the parser skipped
+}
+
+// Parse recognises a file by its content, trying the security master, then
+// format 2 (pipe-delimited) and then format 1 (CSV)
+func Parse(f io.ReadSeeker) (*Parsed, error) {
+	rewind := func() error {
+		_, err := f.Seek(0, io.SeekStart)
+		return err
+	}
+
+	if err := rewind(); err != nil {
+		return nil, err
+	}
+	// Security master files are recognised by their header
+	if securities, rejected, err := parseSecurityMaster(f); err == nil && len(securities) > 0 {
+		return &Parsed{Kind: KindSecurityMaster, Source: sourceSecurityMaster, Securities: securities, Rejected: rejected}, nil
+	}
+
+	if err := rewind(); err != nil {
+		return nil, err
+	}
+	if reports, rejected, err := parseFormat2(f); err == nil && len(reports) > 0 {
+		source := reports[0].SourceSystem
+		if source == "" {
+			source = sourceUnknown
+		}
+		return &Parsed{Kind: KindFormat2, Source: source, Reports: reports, Rejected: rejected}, nil
+	}
+
+	if err := rewind(); err != nil {
+		return nil, err
+	}
+	if trades, rejected, err := parseFormat1(f); err == nil && len(trades) > 0 {
+		return &Parsed{Kind: KindFormat1, Source: sourceTrade, Trades: trades, Rejected: rejected}, nil
+	}
+	return nil, ErrUnrecognized
+}
+
+// Rows is the number of records parsed
+func (p *Parsed) Rows() int {
+	return len(p.Trades) + len(p.Reports) + len(p.Securities)
+}
+
+// Dates returns the position dates the file touches, or nil for the
+// security master
+func (p *Parsed) Dates() []string {
+	switch p.Kind {
+	case KindFormat1:
+		return Format1Dates(p.Trades)
+	case KindFormat2:
+		return Format2Dates(p.Reports)
+	}
+	return nil
+}
+
+// Accounts is the number of distinct accounts in a positions file
+func (p *Parsed) Accounts() int {
+	return len(p.AccountIDs())
+}
+
+// AccountIDs returns the distinct accounts in a positions file, or nil for
+// the security master
+func (p *Parsed) AccountIDs() []string {
+	switch p.Kind {
+	case KindFormat1:
+		return distinct(p.Trades, func(r models.TradeRecord) string { return r.AccountID })
+	case KindFormat2:
+		return distinct(p.Reports, func(r models.ReportRecord) string { return r.AccountID })
+	}
+	return nil
+}
+
+// Ingest loads parsed records in one transaction
+func (w *Worker) Ingest(ctx context.Context, p *Parsed) error {
+	switch p.Kind {
+	case KindSecurityMaster:
+		return w.IngestSecurities(ctx, p.Securities)
+	case KindFormat2:
+		return w.IngestFormat2(ctx, p.Reports)
+	default:
+		return w.IngestFormat1(ctx, p.Trades)
+	}
+}
+
+// IngestFile parses and ingests one file, logging it and recording metrics,
+// then calls OnIngested. Its records carry ctx's ingestion ID, or a new one.
+// modified is when the file was uploaded, for the lag metric. The file's
+// span has the parse and load phases, and the alarm evaluations that
+// OnIngested runs, beneath it.
+func (w *Worker) IngestFile(ctx context.Context, name string, f io.ReadSeeker, modified time.Time) (file *IngestedFile, err error) {
+	id := logging.IngestionID(ctx)
+	if id == "" {
+		id = logging.NewID()
+		ctx = logging.WithIngestionID(ctx, id)
+	}
+	ctx, span := tracer.Start(ctx, "ingest.file", trace.WithAttributes(attribute.String("file.name", name), attribute.String("vest.ingestion_id", id)))
+	defer func() { tracing.End(span, err) }()
+	started := time.Now()
+
+	_, parsing := tracer.Start(ctx, "ingest.parse")
+	p, err := Parse(f)
+	tracing.End(parsing, err)
+	if err != nil {
+		logger.WarnContext(ctx, "Could not parse file", "file", name, "error", err)
+		metrics.FileFailed(sourceUnknown, sourceUnknown, 0)
+		return nil, err
+	}
+	for _, r := range p.Rejected {
+		if r.Field == "" {
+			logger.WarnContext(ctx, "Rejected incomplete row", "file", name, "row", r.Row)
+		} else {
+			logger.WarnContext(ctx, "Rejected row with unparsable field", "file", name, "row", r.Row, "field", r.Field, "value", r.Value)
+		}
+	}
+	file = &IngestedFile{IngestionID: id, Name: name, Kind: p.Kind, Rows: p.Rows(), Dates: p.Dates(), Accounts: p.AccountIDs()}
+	span.SetAttributes(attribute.String("vest.format", p.Kind), attribute.String("vest.source", p.Source),
+		attribute.Int("vest.rows", file.Rows), attribute.Int("vest.rejected", len(p.Rejected)))
+
+	loadCtx, loading := tracer.Start(ctx, "ingest.load")
+	err = w.Ingest(loadCtx, p)
+	tracing.End(loading, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to ingest file", "file", name, "format", p.Kind, "source", p.Source, "rows", file.Rows, "error", err)
+		metrics.FileFailed(p.Kind, p.Source, file.Rows+len(p.Rejected))
+		return nil, err
+	}
+
+	lag := time.Since(modified)
+	logger.InfoContext(ctx, "File ingested", "file", name, "format", p.Kind, "source", p.Source,
+		"rows", file.Rows, "rejected", len(p.Rejected), "dates", file.Dates, "accounts", len(file.Accounts),
+		"duration_ms", time.Since(started).Milliseconds(), "lag_ms", lag.Milliseconds())
+	metrics.FileIngested(p.Kind, p.Source, file.Rows, len(p.Rejected), lag)
+	if w.OnIngested != nil {
+		w.OnIngested(ctx, *file)
+	}
+	return file, nil
+}
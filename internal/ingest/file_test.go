@@ -0,0 +1,73 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestParseRecognisesFormat(t *testing.T) {
+	cases := []struct {
+		name, data string
+		kind       string
+		rows       int
+		dates      []string
+	}{
+		{"format 1", "TradeDate,AccountID,Ticker,Quantity,Price,TradeType,SettlementDate\n2025-01-15,1001,AMZN,10,185.50,BUY,2025-01-17\n2025-01-15,1002,AMZN,5,185.50,BUY,2025-01-17", KindFormat1, 2, []string{"2025-01-15"}},
+		{"format 2", "ReportDate|AccountID|SecurityTicker|Shares|MarketValue|SourceSystem\n20250115|1001|GOOG|50|140.00|ReportingSystem", KindFormat2, 1, []string{"2025-01-15"}},
+		{"security master", "Ticker,CUSIP,ISIN,SEDOL,Issuer,Asset Class,Sector,Currency,Multiplier\nGOOGL,02079K305,US02079K3059,BYVY8G0,Alphabet Inc,Equity,Communication Services,USD,1", KindSecurityMaster, 1, nil},
+	}
+	for _, c := range cases {
+		p, err := Parse(strings.NewReader(c.data))
+		if err != nil {
+			t.Errorf("%s: %v", c.name, err)
+			continue
+		}
+		if p.Kind != c.kind || p.Rows() != c.rows || !reflect.DeepEqual(p.Dates(), c.dates) {
+			t.Errorf("%s: got kind %s, %d rows, dates %v", c.name, p.Kind, p.Rows(), p.Dates())
+		}
+	}
+	if p, _ := Parse(strings.NewReader(cases[0].data)); p.Accounts() != 2 || !reflect.DeepEqual(p.AccountIDs(), []string{"1001", "1002"}) {
+		t.Errorf("Expected accounts 1001 and 1002, got %v", p.AccountIDs())
+	}
+	if p, _ := Parse(strings.NewReader(cases[2].data)); p.AccountIDs() != nil {
+		t.Errorf("Expected no accounts for the security master, got %v", p.AccountIDs())
+	}
+
+	if _, err := Parse(strings.NewReader("hello\nworld")); !errors.Is(err, ErrUnrecognized) {
+		t.Errorf("Expected ErrUnrecognized, got %v", err)
+	}
+}
+
+func TestIngestFileTracesParse(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	w := &Worker{}
+	if _, err := w.IngestFile(context.Background(), "notes.txt", strings.NewReader("hello\nworld"), time.Now()); !errors.Is(err, ErrUnrecognized) {
+		t.Fatalf("Expected ErrUnrecognized, got %v", err)
+	}
+
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "ingest.parse" || spans[1].Name() != "ingest.file" {
+		t.Fatalf("Expected the parse span within the file span, got %v", spans)
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("Expected ingest.parse to be a child of ingest.file")
+	}
+	for _, s := range spans {
+		if s.Status().Code != codes.Error {
+			t.Errorf("Expected %s marked failed, got %v", s.Name(), s.Status())
+		}
+	}
+}
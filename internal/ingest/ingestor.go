@@ -3,30 +3,75 @@ package ingest
 import (
 	"context"
 	"database/sql"
-	"log"
 	"path/filepath"
 	"strings"
 	"time"
 
+	"github.com/AndrewCharlesHay/vest/internal/cache"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
 	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/tracing"
 	"github.com/pkg/sftp"
-
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
+var logger = logging.Component("ingest")
+var tracer = tracing.Tracer("ingest")
+
 type Worker struct {
 	DB         *sql.DB
 	SFTPClient *sftp.Client
 	UploadDir  string
+
+	// DrainTimeout is how long a file already being ingested may take to
+	// commit once the worker is asked to stop
+	DrainTimeout time.Duration
+
+	// OnIngested, if set, is called after each file is ingested
+	OnIngested func(ctx context.Context, file IngestedFile)
+
+	// OnPoll, if set, is called after each poll of the upload directory with
+	// its error, so a broken SFTP session shows in health checks
+	OnPoll func(err error)
+}
+
+// File kinds reported in IngestedFile
+const (
+	KindFormat1        = "format1"
+	KindFormat2        = "format2"
+	KindSecurityMaster = "security_master"
+)
+
+// Source labels for ingestion metrics. Positions files report the
+// source_system their rows are stored with.
+const (
+	sourceTrade          = "Trade"
+	sourceSecurityMaster = "security_master"
+	sourceUnknown        = "unknown"
+)
+
+// IngestedFile describes a successfully ingested upload. IngestionID matches
+// the ingestion_id of the logs written while ingesting it.
+type IngestedFile struct {
+	IngestionID string   `json:"ingestion_id"`
+	Name        string   `json:"file"`
+	Kind        string   `json:"kind"`
+	Rows        int      `json:"rows"`
+	Dates       []string `json:"dates,omitempty"`    // Position dates touched; empty for the security master
+	Accounts    []string `json:"accounts,omitempty"` // Accounts whose positions were written
 }
 
 func NewWorker(db *sql.DB, sftpClient *sftp.Client, dir string) *Worker {
 	return &Worker{
-		DB:         db,
-		SFTPClient: sftpClient,
-		UploadDir:  dir,
+		DB:           db,
+		SFTPClient:   sftpClient,
+		UploadDir:    dir,
+		DrainTimeout: 20 * time.Second,
 	}
 }
 
+// Start polls the upload directory until ctx is cancelled
 func (w *Worker) Start(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Second)
 	defer ticker.Stop()
@@ -36,20 +81,35 @@ func (w *Worker) Start(ctx context.Context) {
 		case <-ctx.Done():
 			return
 		case <-ticker.C:
-			if err := w.ProcessFiles(); err != nil {
-				log.Printf("Error processing files: %v", err)
+			err := w.ProcessFiles(ctx)
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				logger.ErrorContext(ctx, "Error processing files", "error", err)
+			}
+			if w.OnPoll != nil {
+				w.OnPoll(err)
 			}
 		}
 	}
 }
 
-func (w *Worker) ProcessFiles() error {
+// ProcessFiles ingests each file in the upload directory. Once ctx is
+// cancelled no further file is started, but the one in progress gets
+// DrainTimeout to commit rather than being abandoned mid-transaction.
+func (w *Worker) ProcessFiles(ctx context.Context) error {
 	files, err := w.SFTPClient.ReadDir(w.UploadDir)
 	if err != nil {
 		return err
 	}
+	work, cancel := w.finishing(ctx)
+	defer cancel()
 
 	for _, file := range files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		if file.IsDir() {
 			continue
 		}
@@ -62,88 +122,86 @@ func (w *Worker) ProcessFiles() error {
 			continue
 		}
 
-		log.Printf("Processing file: %s", filename)
-		f, err := w.SFTPClient.Open(filepath.Join(w.UploadDir, filename))
-		if err != nil {
-			log.Printf("Failed to open file %s: %v", filename, err)
-			continue
-		}
-		
-		// Determine format based on strict heuristic or try both?
-		// Format 1 is CSV (comma), Format 2 is Pipe.
-		// Heuristic: Read first line? Or just filename/structure.
-		// The requirements don't specify filenames. I'll peek content or just try parsing.
-		// Simpler: Format 1 has 7 columns, Format 2 has 6. 
-		// Actually Format 2 has headers with PIPE. Format 1 has commas.
-		
-		// Reset cursor after peeking if needed, but easier to just read all content.
-		// Note: csv.Reader consumes io.Reader.
-		
-		// Simplification for exercise: Try Format 2 (Pipe) first as it is distinct. If fails, try Format 1.
-		// Resetting seek is needed.
-		
-		// NOTE: pkg/sftp File supports Seek.
-		
-	// Try Pipe
-		// err is already declared above from SFTP Open
-		if _, seekErr := f.Seek(0, 0); seekErr != nil {
-			log.Printf("Failed to seek file %s: %v", filename, seekErr)
-			f.Close()
-			continue
-		}
-
-		records2, err2 := ParseFormat2(f)
-		if err2 == nil && len(records2) > 0 {
-			// It is format 2
-			err = w.IngestFormat2(records2)
-		} else {
-			// Try Format 1
-			if _, seekErr := f.Seek(0, 0); seekErr != nil {
-				log.Printf("Failed to seek file %s: %v", filename, seekErr)
-				f.Close()
-				continue
-			}
+		// Every log line for this file, and for the alarm evaluations it
+		// triggers, carries the same ingestion_id, and each file is a trace
+		id := logging.NewID()
+		fileCtx, span := tracer.Start(logging.WithIngestionID(work, id), "ingest.upload", trace.WithAttributes(
+			attribute.String("file.name", filename), attribute.Int64("file.size", file.Size()), attribute.String("vest.ingestion_id", id)))
+		logger.InfoContext(fileCtx, "Processing file", "file", filename, "size", file.Size(), "modified", file.ModTime())
+		tracing.End(span, w.processFile(fileCtx, filename, file.ModTime()))
+	}
+	return nil
+}
 
-			records1, err1 := ParseFormat1(f)
-			if err1 == nil && len(records1) > 0 {
-				err = w.IngestFormat1(records1)
-			} else {
-				log.Printf("Could not parse file %s as either format", filename)
-				f.Close()
-				continue
-			}
-		}
+// processFile ingests an uploaded file and removes it. The directory listing
+// isn't traced, as it runs every poll; a failing one shows in OnPoll.
+func (w *Worker) processFile(ctx context.Context, filename string, modified time.Time) error {
+	path := filepath.Join(w.UploadDir, filename)
+	_, span := tracer.Start(ctx, "sftp.open")
+	f, err := w.SFTPClient.Open(path)
+	tracing.End(span, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to open file", "file", filename, "error", err)
+		return err
+	}
+	// The file is read over SFTP as it is parsed
+	_, err = w.IngestFile(ctx, filename, f, modified)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	// Move or delete to avoid reprocessing endlessly in this loop
+	// For exercise, we delete
+	_, span = tracer.Start(ctx, "sftp.remove")
+	err = w.SFTPClient.Remove(path)
+	tracing.End(span, err)
+	if err != nil {
+		logger.ErrorContext(ctx, "Failed to remove file", "file", filename, "error", err)
+	}
+	return err
+}
 
-		f.Close()
-		
-		if err != nil {
-			log.Printf("Failed to ingest %s: %v", filename, err)
-		} else {
-			log.Printf("Successfully ingested %s", filename)
-			// Move or delete to avoid reprocessing endlessly in this loop
-			// For exercise, we delete
-			if err := w.SFTPClient.Remove(filepath.Join(w.UploadDir, filename)); err != nil {
-				log.Printf("Failed to remove file %s: %v", filename, err)
-			}
-		}
+// finishing returns a context for work that should complete even if ctx is
+// cancelled, ending DrainTimeout after ctx does
+func (w *Worker) finishing(ctx context.Context) (context.Context, context.CancelFunc) {
+	work, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	stop := context.AfterFunc(ctx, func() {
+		time.AfterFunc(w.DrainTimeout, cancel)
+	})
+	return work, func() {
+		stop()
+		cancel()
 	}
-	return nil
 }
 
-func (w *Worker) IngestFormat1(records []models.TradeRecord) error {
-	tx, err := w.DB.Begin()
+// accountLookup resolves the custodian account ID in $2 to its internal account
+const accountLookup = `COALESCE((SELECT a.account_id FROM account_aliases a WHERE a.alias = $2), $2)`
+
+// securityLookup selects a column of the security master row matching the
+// identifier in $3, preferring an exact ticker match over CUSIP/ISIN/SEDOL
+func securityLookup(column string) string {
+	return `SELECT s.` + column + ` FROM securities s
+			WHERE $3 IN (s.ticker, s.cusip, s.isin, s.sedol)
+			ORDER BY s.ticker = $3 DESC
+			LIMIT 1`
+}
+
+func (w *Worker) IngestFormat1(ctx context.Context, records []models.TradeRecord) error {
+	tx, err := w.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
-			log.Printf("Failed to rollback tx: %v", err)
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
 		}
 	}()
 
-	stmt, err := tx.Prepare(`
+	// Custodian account IDs resolve to the internal account, CUSIP/ISIN/SEDOL resolve
+	// to the security master ticker, and price-based market value honours the multiplier
+	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO positions (date, account_id, ticker, quantity, market_value, shares, source_system)
-		VALUES ($1, $2, $3, $4, $5, $4, 'Trade')
+		VALUES ($1, `+accountLookup+`, COALESCE((`+securityLookup("ticker")+`), $3), $4, $5 * COALESCE((`+securityLookup("multiplier")+`), 1), $4, 'Trade')
 		ON CONFLICT (date, account_id, ticker) 
 		DO UPDATE SET 
 			quantity = positions.quantity + EXCLUDED.quantity,
@@ -165,29 +223,34 @@ func (w *Worker) IngestFormat1(records []models.TradeRecord) error {
 		// Market Value needed? Format 1 has Price. MV = Qty * Price
 		mv := qty * r.Price
 
-		_, err := stmt.Exec(r.TradeDate, r.AccountID, r.Ticker, qty, mv)
+		_, err := stmt.ExecContext(ctx, r.TradeDate, r.AccountID, r.Ticker, qty, mv)
 		if err != nil {
 			return err
 		}
 	}
 
+	// Cached reads of these dates are now stale
+	if err := cache.Bump(ctx, tx, Format1Dates(records)...); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
 
-func (w *Worker) IngestFormat2(records []models.ReportRecord) error {
-	tx, err := w.DB.Begin()
+func (w *Worker) IngestFormat2(ctx context.Context, records []models.ReportRecord) error {
+	tx, err := w.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 	defer func() {
 		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
-			log.Printf("Failed to rollback tx: %v", err)
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
 		}
 	}()
 
-	stmt, err := tx.Prepare(`
+	stmt, err := tx.PrepareContext(ctx, `
 		INSERT INTO positions (date, account_id, ticker, quantity, market_value, shares, source_system)
-		VALUES ($1, $2, $3, $4, $5, $4, $6)
+		VALUES ($1, `+accountLookup+`, COALESCE((`+securityLookup("ticker")+`), $3), $4, $5, $4, $6)
 		ON CONFLICT (date, account_id, ticker) 
 		DO UPDATE SET 
 			quantity = EXCLUDED.quantity, 
@@ -208,11 +271,107 @@ func (w *Worker) IngestFormat2(records []models.ReportRecord) error {
 		parsedDate, _ := time.Parse("20060102", r.ReportDate)
 		dateStr := parsedDate.Format("2006-01-02")
 
-		_, err := stmt.Exec(dateStr, r.AccountID, r.SecurityTicker, r.Shares, r.MarketValue, r.SourceSystem)
+		_, err := stmt.ExecContext(ctx, dateStr, r.AccountID, r.SecurityTicker, r.Shares, r.MarketValue, r.SourceSystem)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Cached reads of these dates are now stale
+	if err := cache.Bump(ctx, tx, Format2Dates(records)...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// distinct returns the distinct keys among records in the order first seen
+func distinct[T any](records []T, key func(T) string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, r := range records {
+		if k := key(r); !seen[k] {
+			seen[k] = true
+			keys = append(keys, k)
+		}
+	}
+	return keys
+}
+
+// Format1Dates returns the distinct trade dates in a Format 1 file
+func Format1Dates(records []models.TradeRecord) []string {
+	seen := make(map[string]bool)
+	var dates []string
+	for _, r := range records {
+		if !seen[r.TradeDate] {
+			seen[r.TradeDate] = true
+			dates = append(dates, r.TradeDate)
+		}
+	}
+	return dates
+}
+
+// Format2Dates returns the distinct report dates in a Format 2 file as YYYY-MM-DD
+func Format2Dates(records []models.ReportRecord) []string {
+	seen := make(map[string]bool)
+	var dates []string
+	for _, r := range records {
+		parsedDate, _ := time.Parse("20060102", r.ReportDate)
+		d := parsedDate.Format("2006-01-02")
+		if !seen[d] {
+			seen[d] = true
+			dates = append(dates, d)
+		}
+	}
+	return dates
+}
+
+// IngestSecurities upserts security master records. Blank fields don't erase
+// values already on file.
+func (w *Worker) IngestSecurities(ctx context.Context, records []models.Security) error {
+	tx, err := w.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	stmt, err := tx.PrepareContext(ctx, UpsertSecuritySQL)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for _, s := range records {
+		_, err := stmt.ExecContext(ctx, s.Ticker, s.CUSIP, s.ISIN, s.SEDOL, s.Issuer, s.AssetClass, s.Sector, s.Currency, s.Multiplier)
 		if err != nil {
 			return err
 		}
 	}
 
+	if err := cache.Bump(ctx, tx, cache.ReferenceScope); err != nil {
+		return err
+	}
+
 	return tx.Commit()
 }
+
+// UpsertSecuritySQL inserts or merges a security master row. Shared with the API.
+const UpsertSecuritySQL = `
+	INSERT INTO securities (ticker, cusip, isin, sedol, issuer, asset_class, sector, currency, multiplier)
+	VALUES ($1, NULLIF($2, ''), NULLIF($3, ''), NULLIF($4, ''), NULLIF($5, ''), NULLIF($6, ''), NULLIF($7, ''), NULLIF($8, ''), $9)
+	ON CONFLICT (ticker)
+	DO UPDATE SET
+		cusip = COALESCE(EXCLUDED.cusip, securities.cusip),
+		isin = COALESCE(EXCLUDED.isin, securities.isin),
+		sedol = COALESCE(EXCLUDED.sedol, securities.sedol),
+		issuer = COALESCE(EXCLUDED.issuer, securities.issuer),
+		asset_class = COALESCE(EXCLUDED.asset_class, securities.asset_class),
+		sector = COALESCE(EXCLUDED.sector, securities.sector),
+		currency = COALESCE(EXCLUDED.currency, securities.currency),
+		multiplier = EXCLUDED.multiplier,
+		updated_at = CURRENT_TIMESTAMP
+`
@@ -0,0 +1,45 @@
+package ingest
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+func TestFormatDates(t *testing.T) {
+	trades := []models.TradeRecord{
+		{TradeDate: "2025-01-15"}, {TradeDate: "2025-01-16"}, {TradeDate: "2025-01-15"},
+	}
+	if got := Format1Dates(trades); !reflect.DeepEqual(got, []string{"2025-01-15", "2025-01-16"}) {
+		t.Errorf("Unexpected Format 1 dates: %v", got)
+	}
+
+	reports := []models.ReportRecord{
+		{ReportDate: "20250115"}, {ReportDate: "20250115"},
+	}
+	if got := Format2Dates(reports); !reflect.DeepEqual(got, []string{"2025-01-15"}) {
+		t.Errorf("Unexpected Format 2 dates: %v", got)
+	}
+}
+
+func TestFinishingOutlivesCancel(t *testing.T) {
+	w := &Worker{DrainTimeout: 50 * time.Millisecond}
+	ctx, stop := context.WithCancel(context.Background())
+	work, cancel := w.finishing(ctx)
+	defer cancel()
+
+	stop()
+	select {
+	case <-work.Done():
+		t.Fatal("work was cancelled with ctx instead of draining")
+	case <-time.After(10 * time.Millisecond):
+	}
+	select {
+	case <-work.Done():
+	case <-time.After(time.Second):
+		t.Fatal("work outlived DrainTimeout")
+	}
+}
@@ -2,6 +2,7 @@ package ingest
 
 import (
 	"encoding/csv"
+	"fmt"
 	"io"
 	"strconv"
 	"strings"
@@ -10,28 +11,46 @@ import (
 )
 
 func ParseFormat1(r io.Reader) ([]models.TradeRecord, error) {
+	records, _, err := parseFormat1(r)
+	return records, err
+}
+
+// parseFormat1 also returns the rows it rejected, either too short to use or
+// with a quantity or price that is not a number
+func parseFormat1(r io.Reader) ([]models.TradeRecord, []Rejection, error) {
 	reader := csv.NewReader(r)
 	// Skip header
 	if _, err := reader.Read(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var records []models.TradeRecord
+	var rejected []Rejection
 	for {
 		row, err := reader.Read()
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 
+		line, _ := reader.FieldPos(0)
 		if len(row) < 7 {
+			rejected = append(rejected, Rejection{Row: line})
 			continue 
 		}
 
-		qty, _ := strconv.ParseFloat(row[3], 64)
-		price, _ := strconv.ParseFloat(row[4], 64)
+		qty, bad := parseNumber(row[3], "quantity", line)
+		if bad != nil {
+			rejected = append(rejected, *bad)
+			continue
+		}
+		price, bad := parseNumber(row[4], "price", line)
+		if bad != nil {
+			rejected = append(rejected, *bad)
+			continue
+		}
 
 		records = append(records, models.TradeRecord{
 			TradeDate:      row[0],
@@ -43,14 +62,39 @@ func ParseFormat1(r io.Reader) ([]models.TradeRecord, error) {
 			SettlementDate: row[6],
 		})
 	}
-	return records, nil
+	return records, rejected, nil
+}
+
+// Rejection is a row the parser skipped. Field names the value that did not
+// parse, and is empty when the row was incomplete.
+type Rejection struct {
+	Row   int // Line in the file, or record number for Format 2
+	Field string
+	Value string
+}
+
+// parseNumber reads a numeric field, returning a rejection when it cannot be
+// parsed so the row is skipped rather than loaded as zero
+func parseNumber(value, field string, row int) (float64, *Rejection) {
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return 0, &Rejection{Row: row, Field: field, Value: value}
+	}
+	return n, nil
 }
 
 func ParseFormat2(r io.Reader) ([]models.ReportRecord, error) {
+	records, _, err := parseFormat2(r)
+	return records, err
+}
+
+// parseFormat2 also returns the records it rejected: one that is incomplete
+// at the end of the file, and any whose shares or market value is not a number
+func parseFormat2(r io.Reader) ([]models.ReportRecord, []Rejection, error) {
 	// Read full content to handle messy newlines/concatenated records
 	content, err := io.ReadAll(r)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 	
 	// Split by Pipe
@@ -98,10 +142,11 @@ func ParseFormat2(r io.Reader) ([]models.ReportRecord, error) {
 	// Now we have a flat list of fields. Group by 6.
 	const fieldsPerRecord = 6
 	if len(allFields) < fieldsPerRecord {
-		return nil, nil // Empty or just header?
+		return nil, nil, nil // Empty or just header?
 	}
 
 	var records []models.ReportRecord
+	var rejected []Rejection
 	
 	// Skip Header (First 6 fields)
 	// We assume input ALWAYS has header "REPORT_DATE|..."
@@ -113,14 +158,24 @@ func ParseFormat2(r io.Reader) ([]models.ReportRecord, error) {
 	for i := startIndex; i < len(allFields); i += fieldsPerRecord {
 		// Ensure we have enough fields remaining
 		if i+fieldsPerRecord > len(allFields) {
+			rejected = append(rejected, Rejection{Row: i / fieldsPerRecord})
 			break // Incomplete record at end
 		}
 		
 		row := allFields[i : i+fieldsPerRecord]
 		
 		// Parse
-		shares, _ := strconv.ParseFloat(row[3], 64)
-		mv, _ := strconv.ParseFloat(row[4], 64)
+		record := i / fieldsPerRecord
+		shares, bad := parseNumber(row[3], "shares", record)
+		if bad != nil {
+			rejected = append(rejected, *bad)
+			continue
+		}
+		mv, bad := parseNumber(row[4], "market_value", record)
+		if bad != nil {
+			rejected = append(rejected, *bad)
+			continue
+		}
 
 		records = append(records, models.ReportRecord{
 			ReportDate:     row[0],
@@ -132,5 +187,81 @@ func ParseFormat2(r io.Reader) ([]models.ReportRecord, error) {
 		})
 	}
 
-	return records, nil
+	return records, rejected, nil
+}
+
+// securityMasterHeader is the expected header for security master files
+var securityMasterHeader = []string{"TICKER", "CUSIP", "ISIN", "SEDOL", "ISSUER", "ASSET_CLASS", "SECTOR", "CURRENCY", "MULTIPLIER"}
+
+// ParseSecurityMaster reads a security master CSV. Unlike Format 1 the header
+// is validated, since that is how these files are told apart from trades.
+func ParseSecurityMaster(r io.Reader) ([]models.Security, error) {
+	records, _, err := parseSecurityMaster(r)
+	return records, err
+}
+
+// parseSecurityMaster also returns the rows it rejected: short, without a
+// ticker, or with a multiplier that is not a number
+func parseSecurityMaster(r io.Reader) ([]models.Security, []Rejection, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(header) < len(securityMasterHeader) {
+		return nil, nil, fmt.Errorf("not a security master file")
+	}
+	for i, col := range securityMasterHeader {
+		name := strings.ToUpper(strings.ReplaceAll(strings.TrimSpace(header[i]), " ", "_"))
+		if name != col && strings.ReplaceAll(name, "_", "") != strings.ReplaceAll(col, "_", "") {
+			return nil, nil, fmt.Errorf("not a security master file: column %d is %q", i+1, header[i])
+		}
+	}
+
+	var records []models.Security
+	var rejected []Rejection
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		line, _ := reader.FieldPos(0)
+		if len(row) < len(securityMasterHeader) || strings.TrimSpace(row[0]) == "" {
+			rejected = append(rejected, Rejection{Row: line})
+			continue
+		}
+		for i := range row {
+			row[i] = strings.TrimSpace(row[i])
+		}
+
+		// A blank or zero multiplier means 1
+		multiplier := 1.0
+		if row[8] != "" {
+			m, bad := parseNumber(row[8], "multiplier", line)
+			if bad != nil {
+				rejected = append(rejected, *bad)
+				continue
+			}
+			if m != 0 {
+				multiplier = m
+			}
+		}
+
+		records = append(records, models.Security{
+			Ticker:     row[0],
+			CUSIP:      row[1],
+			ISIN:       row[2],
+			SEDOL:      row[3],
+			Issuer:     row[4],
+			AssetClass: row[5],
+			Sector:     row[6],
+			Currency:   strings.ToUpper(row[7]),
+			Multiplier: multiplier,
+		})
+	}
+	return records, rejected, nil
 }
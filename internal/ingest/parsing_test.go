@@ -101,3 +101,107 @@ func TestParseFormat2_UserSample(t *testing.T) {
 		t.Errorf("Rec 1 Source mismatch: %v", records[1])
 	}
 }
+
+func TestParseSecurityMaster(t *testing.T) {
+	data := `Ticker,CUSIP,ISIN,SEDOL,Issuer,Asset Class,Sector,Currency,Multiplier
+GOOG,38259P706,US38259P7069,,Alphabet Inc,Equity,Communication Services,usd,
+GOOGL,02079K305,US02079K3059,BYVY8G0,Alphabet Inc,Equity,Communication Services,USD,1
+SPY 250117C00600000,,,,SPDR S&P 500 ETF,Option,,USD,100`
+
+	records, err := ParseSecurityMaster(strings.NewReader(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("Expected 3 records, got %d", len(records))
+	}
+	if records[0].Issuer != records[1].Issuer {
+		t.Errorf("Expected GOOG and GOOGL to share an issuer: %q vs %q", records[0].Issuer, records[1].Issuer)
+	}
+	if records[0].Currency != "USD" || records[0].Multiplier != 1 {
+		t.Errorf("Expected normalised currency and default multiplier, got %+v", records[0])
+	}
+	if records[2].Multiplier != 100 || records[2].AssetClass != "Option" {
+		t.Errorf("Unexpected option record: %+v", records[2])
+	}
+}
+
+func TestParseSecurityMaster_RejectsTrades(t *testing.T) {
+	csvData := `TradeDate,AccountID,Ticker,Quantity,Price,TradeType,SettlementDate
+2025-01-15,1001,AMZN,10,185.50,BUY,2025-01-17`
+
+	if _, err := ParseSecurityMaster(strings.NewReader(csvData)); err == nil {
+		t.Error("Expected trade file to be rejected")
+	}
+}
+
+func TestParseCountsRejectedRows(t *testing.T) {
+	pipeData := `ReportDate|AccountID|SecurityTicker|Shares|MarketValue|SourceSystem
+20250115|1001|GOOG|50|140.00|ReportingSystem 20250115|1002|MSFT`
+	records, rejected, err := parseFormat2(strings.NewReader(pipeData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 1 || len(rejected) != 1 {
+		t.Errorf("Expected 1 record and the truncated one rejected, got %d and %d", len(records), len(rejected))
+	}
+
+	master := "TICKER,CUSIP,ISIN,SEDOL,ISSUER,ASSET_CLASS,SECTOR,CURRENCY,MULTIPLIER\n" +
+		"AAPL,037833100,US0378331005,2046251,Apple Inc,Equity,Technology,USD,1\n" +
+		",037833100,,,,,,,\n" +
+		"MSFT,594918104\n"
+	securities, rejected, err := parseSecurityMaster(strings.NewReader(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(securities) != 1 || len(rejected) != 2 {
+		t.Errorf("Expected 1 security and 2 rows rejected, got %d and %d", len(securities), len(rejected))
+	}
+}
+
+func TestParseRejectsUnparsableNumbers(t *testing.T) {
+	csvData := `TradeDate,AccountID,Ticker,Quantity,Price,TradeType,SettlementDate
+2025-01-15,1001,AAPL,100,185.50,BUY,2025-01-17
+2025-01-15,1001,MSFT,1O0,410.00,BUY,2025-01-17
+2025-01-15,1002,GOOG,50,,SELL,2025-01-17`
+	trades, rejected, err := parseFormat1(strings.NewReader(csvData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trades) != 1 || trades[0].Ticker != "AAPL" || len(rejected) != 2 {
+		t.Errorf("Expected only AAPL loaded and 2 rows rejected, got %+v and %+v", trades, rejected)
+	}
+	if want := (Rejection{Row: 3, Field: "quantity", Value: "1O0"}); rejected[0] != want {
+		t.Errorf("Expected %+v, got %+v", want, rejected[0])
+	}
+	if rejected[1].Field != "price" || rejected[1].Row != 4 {
+		t.Errorf("Expected the empty price on line 4, got %+v", rejected[1])
+	}
+
+	pipeData := `ReportDate|AccountID|SecurityTicker|Shares|MarketValue|SourceSystem
+20250115|1001|GOOG|n/a|140.00|ReportingSystem 20250115|1002|MSFT|25|9,000.00|ReportingSystem 20250115|1003|AAPL|10|1855.00|ReportingSystem`
+	reports, rejected, err := parseFormat2(strings.NewReader(pipeData))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 || reports[0].SecurityTicker != "AAPL" || len(rejected) != 2 {
+		t.Errorf("Expected only AAPL loaded and 2 records rejected, got %+v and %+v", reports, rejected)
+	}
+	if rejected[0].Field != "shares" || rejected[1].Field != "market_value" {
+		t.Errorf("Expected shares and market value rejected, got %+v", rejected)
+	}
+
+	master := "TICKER,CUSIP,ISIN,SEDOL,ISSUER,ASSET_CLASS,SECTOR,CURRENCY,MULTIPLIER\n" +
+		"AAPL,037833100,US0378331005,2046251,Apple Inc,Equity,Technology,USD,\n" +
+		"ESH5,,,,CME,Future,Index,USD,fifty\n"
+	securities, rejected, err := parseSecurityMaster(strings.NewReader(master))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(securities) != 1 || securities[0].Multiplier != 1 {
+		t.Errorf("Expected only AAPL loaded with a blank multiplier of 1, got %+v", securities)
+	}
+	if len(rejected) != 1 || rejected[0].Field != "multiplier" || rejected[0].Value != "fifty" {
+		t.Errorf("Expected the non-numeric multiplier rejected, got %+v", rejected)
+	}
+}
@@ -0,0 +1,180 @@
+// Package logging configures structured JSON logs. Records logged with a
+// context carry its request_id and ingestion_id, so one upload can be
+// followed from SFTP to the alarms it raised, and the trace_id and span_id
+// of its span, so a slow request's logs sit beside its trace. Each package
+// logs through a component logger.
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+var ErrInvalidLevel = errors.New("log level must be debug, info, warn or error")
+
+// level is shared by every handler Setup installs, so it can change at runtime
+var level = new(slog.LevelVar)
+
+// Setup sends slog and the log package through a JSON handler writing to w,
+// or a text handler when format is "text". Records below lvl are dropped.
+func Setup(w io.Writer, format, lvl string) error {
+	if err := SetLevel(lvl); err != nil {
+		return err
+	}
+	opts := &slog.HandlerOptions{Level: level}
+	var h slog.Handler
+	switch format {
+	case "", "json":
+		h = slog.NewJSONHandler(w, opts)
+	case "text":
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return fmt.Errorf("log format must be json or text, got %q", format)
+	}
+	slog.SetDefault(slog.New(contextHandler{h}))
+	return nil
+}
+
+// SetLevel changes the minimum level logged. An empty level means info.
+func SetLevel(lvl string) error {
+	l, err := ParseLevel(lvl)
+	if err != nil {
+		return err
+	}
+	level.Set(l)
+	return nil
+}
+
+// ParseLevel reads a level name such as "debug" or "WARN". An empty level
+// means info.
+func ParseLevel(lvl string) (slog.Level, error) {
+	if lvl == "" {
+		lvl = "info"
+	}
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(strings.ToLower(lvl))); err != nil {
+		return 0, fmt.Errorf("%w, got %q", ErrInvalidLevel, lvl)
+	}
+	return l, nil
+}
+
+// ValidFormat reports whether format names a handler Setup supports
+func ValidFormat(format string) bool {
+	return format == "" || format == "json" || format == "text"
+}
+
+// Component returns a logger tagging records with component. It follows
+// the default logger at the time of each record, so package-level component
+// loggers pick up Setup.
+func Component(name string) *slog.Logger {
+	return slog.New(lazyHandler{wrap: func(h slog.Handler) slog.Handler {
+		return h.WithAttrs([]slog.Attr{slog.String("component", name)})
+	}})
+}
+
+type requestIDKey struct{}
+type ingestionIDKey struct{}
+
+// WithRequestID returns a context whose records carry request_id id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID returns the request ID in ctx, or ""
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithIngestionID returns a context whose records carry ingestion_id id
+func WithIngestionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, ingestionIDKey{}, id)
+}
+
+// IngestionID returns the ingestion ID in ctx, or ""
+func IngestionID(ctx context.Context) string {
+	id, _ := ctx.Value(ingestionIDKey{}).(string)
+	return id
+}
+
+// NewID returns a random identifier for a request or ingestion
+func NewID() string {
+	return rand.Text()
+}
+
+// validRequestID limits caller-supplied IDs to what is safe to log and echo
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._:-]{1,128}$`)
+
+// AcceptRequestID returns a caller's request ID if it is safe to log, or a
+// new one
+func AcceptRequestID(id string) string {
+	if validRequestID.MatchString(id) {
+		return id
+	}
+	return NewID()
+}
+
+// contextHandler adds the correlation and trace IDs in a record's context
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ctx != nil {
+		if id := RequestID(ctx); id != "" {
+			r.AddAttrs(slog.String("request_id", id))
+		}
+		if id := IngestionID(ctx); id != "" {
+			r.AddAttrs(slog.String("ingestion_id", id))
+		}
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			r.AddAttrs(slog.String("trace_id", sc.TraceID().String()), slog.String("span_id", sc.SpanID().String()))
+		}
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// lazyHandler applies wrap to the default handler when it is used
+type lazyHandler struct {
+	wrap func(slog.Handler) slog.Handler
+}
+
+func (h lazyHandler) handler() slog.Handler {
+	return h.wrap(slog.Default().Handler())
+}
+
+func (h lazyHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return slog.Default().Handler().Enabled(ctx, l)
+}
+
+func (h lazyHandler) Handle(ctx context.Context, r slog.Record) error {
+	return h.handler().Handle(ctx, r)
+}
+
+func (h lazyHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return lazyHandler{wrap: func(base slog.Handler) slog.Handler {
+		return h.wrap(base).WithAttrs(attrs)
+	}}
+}
+
+func (h lazyHandler) WithGroup(name string) slog.Handler {
+	return lazyHandler{wrap: func(base slog.Handler) slog.Handler {
+		return h.wrap(base).WithGroup(name)
+	}}
+}
@@ -0,0 +1,81 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestComponentLoggerCarriesContextIDs(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	// Created before Setup, as package-level loggers are
+	logger := Component("ingest")
+
+	var buf bytes.Buffer
+	if err := Setup(&buf, "json", "info"); err != nil {
+		t.Fatal(err)
+	}
+	ctx := WithIngestionID(WithRequestID(context.Background(), "req-1"), "ing-1")
+	logger.InfoContext(ctx, "File ingested", "rows", 3)
+	logger.Debug("Dropped below the level")
+	log.Printf("From the log package")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 records, got %d: %s", len(lines), buf.String())
+	}
+	var rec map[string]any
+	if err := json.Unmarshal([]byte(lines[0]), &rec); err != nil {
+		t.Fatal(err)
+	}
+	for key, want := range map[string]any{
+		"level": "INFO", "msg": "File ingested", "component": "ingest",
+		"request_id": "req-1", "ingestion_id": "ing-1", "rows": float64(3),
+	} {
+		if rec[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, rec[key])
+		}
+	}
+	if !strings.Contains(lines[1], `"msg":"From the log package"`) {
+		t.Errorf("Expected log package output as JSON, got %s", lines[1])
+	}
+}
+
+func TestRecordsCarryTraceIDs(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	if err := Setup(&buf, "json", "info"); err != nil {
+		t.Fatal(err)
+	}
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID: trace.TraceID{1, 2, 3},
+		SpanID:  trace.SpanID{4, 5, 6},
+	})
+	Component("api").InfoContext(trace.ContextWithSpanContext(context.Background(), sc), "Slow query")
+	Component("api").InfoContext(context.Background(), "Untraced")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !strings.Contains(lines[0], `"trace_id":"`+sc.TraceID().String()+`"`) || !strings.Contains(lines[0], `"span_id":"`+sc.SpanID().String()+`"`) {
+		t.Errorf("Expected the span's IDs, got %s", lines[0])
+	}
+	if strings.Contains(lines[1], "trace_id") {
+		t.Errorf("Expected no trace_id without a span, got %s", lines[1])
+	}
+}
+
+func TestSetLevel(t *testing.T) {
+	defer level.Set(slog.LevelInfo)
+	if err := SetLevel("DEBUG"); err != nil || level.Level() != slog.LevelDebug {
+		t.Errorf("Expected debug, got %v (%v)", level.Level(), err)
+	}
+	if err := SetLevel("loud"); !errors.Is(err, ErrInvalidLevel) {
+		t.Errorf("Expected ErrInvalidLevel, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// alarmQueryTimeout bounds the count query run on each scrape
+const alarmQueryTimeout = 5 * time.Second
+
+var alarmsDesc = prometheus.NewDesc(
+	"vest_alarms_open",
+	"Compliance alarms not yet resolved or cleared, by status and severity.",
+	[]string{"status", "severity"}, nil,
+)
+
+// AlarmCollector counts open and acknowledged alarms when scraped, so every
+// server instance reports the same totals
+type AlarmCollector struct {
+	DB *sql.DB
+}
+
+func NewAlarmCollector(db *sql.DB) *AlarmCollector {
+	return &AlarmCollector{DB: db}
+}
+
+func (c *AlarmCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- alarmsDesc
+}
+
+func (c *AlarmCollector) Collect(ch chan<- prometheus.Metric) {
+	counts, err := c.counts()
+	if err != nil {
+		ch <- prometheus.NewInvalidMetric(alarmsDesc, err)
+		return
+	}
+	for key, n := range counts {
+		ch <- prometheus.MustNewConstMetric(alarmsDesc, prometheus.GaugeValue, n, key[0], key[1])
+	}
+}
+
+// counts returns alarms per status and severity, with zeros for the known
+// combinations so alerts see 0 rather than a missing series
+func (c *AlarmCollector) counts() (map[[2]string]float64, error) {
+	counts := make(map[[2]string]float64)
+	for _, status := range []string{models.AlarmOpen, models.AlarmAcknowledged} {
+		for _, severity := range []string{compliance.SeverityWarning, compliance.SeverityCritical} {
+			counts[[2]string{status, severity}] = 0
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), alarmQueryTimeout)
+	defer cancel()
+	rows, err := c.DB.QueryContext(ctx, `
+		SELECT status, severity, COUNT(*) FROM alarms
+		WHERE status IN ($1, $2)
+		GROUP BY status, severity`, models.AlarmOpen, models.AlarmAcknowledged)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var status, severity string
+		var n float64
+		if err := rows.Scan(&status, &severity, &n); err != nil {
+			return nil, err
+		}
+		counts[[2]string{status, severity}] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,107 @@
+// Package metrics exposes Prometheus metrics at /metrics: HTTP traffic,
+// database pool stats, ingestion outcomes and open alarms. No label carries
+// an account ID, so the endpoint reveals nothing about client holdings.
+package metrics
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Ingestion outcomes, the result label of the ingest counters
+const (
+	ResultIngested = "ingested"
+	ResultFailed   = "failed"
+	RowsAccepted   = "accepted"
+	RowsRejected   = "rejected"
+)
+
+// Registry holds every vest metric plus the Go runtime and process collectors
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vest_http_requests_total",
+		Help: "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vest_http_request_duration_seconds",
+		Help:    "HTTP request latency by method, route pattern and status.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	ingestFiles = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vest_ingest_files_total",
+		Help: "Uploaded files processed by format, source system and result (ingested or failed).",
+	}, []string{"format", "source", "result"})
+
+	ingestRows = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "vest_ingest_rows_total",
+		Help: "Rows read from uploaded files by format, source system and result (accepted or rejected).",
+	}, []string{"format", "source", "result"})
+
+	// Buckets run from a second to about 18 hours
+	ingestLag = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "vest_ingest_lag_seconds",
+		Help:    "Time from an uploaded file's modification to its ingestion committing.",
+		Buckets: prometheus.ExponentialBuckets(1, 4, 9),
+	}, []string{"format", "source"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, ingestFiles, ingestRows, ingestLag,
+	)
+}
+
+// RegisterDB adds connection pool stats and open alarm counts for db. Call
+// it once.
+func RegisterDB(db *sql.DB) error {
+	return errors.Join(
+		Registry.Register(collectors.NewDBStatsCollector(db, "vest")),
+		Registry.Register(NewAlarmCollector(db)),
+	)
+}
+
+// Handler serves the registry in the Prometheus exposition format. A
+// collector that fails is logged and left out rather than failing the scrape.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{
+		ErrorLog:      log.Default(),
+		ErrorHandling: promhttp.ContinueOnError,
+	})
+}
+
+// ObserveRequest counts a finished HTTP request and its latency
+func ObserveRequest(method, route string, status int, d time.Duration) {
+	code := strconv.Itoa(status)
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route, code).Observe(d.Seconds())
+}
+
+// FileIngested records a committed file: its rows, those the parser
+// rejected, and how long after the upload it committed
+func FileIngested(format, source string, accepted, rejected int, lag time.Duration) {
+	ingestFiles.WithLabelValues(format, source, ResultIngested).Inc()
+	ingestRows.WithLabelValues(format, source, RowsAccepted).Add(float64(accepted))
+	ingestRows.WithLabelValues(format, source, RowsRejected).Add(float64(rejected))
+	ingestLag.WithLabelValues(format, source).Observe(lag.Seconds())
+}
+
+// FileFailed records a file that could not be parsed or committed. Every row
+// read from it counts as rejected.
+func FileFailed(format, source string, rows int) {
+	ingestFiles.WithLabelValues(format, source, ResultFailed).Inc()
+	ingestRows.WithLabelValues(format, source, RowsRejected).Add(float64(rows))
+}
@@ -0,0 +1,38 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestIngestCounters(t *testing.T) {
+	FileIngested("format2", "CustodianA", 3, 1, 90*time.Second)
+	FileFailed("format2", "CustodianA", 2)
+
+	for _, c := range []struct {
+		result string
+		want   float64
+	}{{ResultIngested, 1}, {ResultFailed, 1}} {
+		if got := testutil.ToFloat64(ingestFiles.WithLabelValues("format2", "CustodianA", c.result)); got != c.want {
+			t.Errorf("Expected %v %s files, got %v", c.want, c.result, got)
+		}
+	}
+	if got := testutil.ToFloat64(ingestRows.WithLabelValues("format2", "CustodianA", RowsAccepted)); got != 3 {
+		t.Errorf("Expected 3 accepted rows, got %v", got)
+	}
+	// One rejected by the parser plus both rows of the failed file
+	if got := testutil.ToFloat64(ingestRows.WithLabelValues("format2", "CustodianA", RowsRejected)); got != 3 {
+		t.Errorf("Expected 3 rejected rows, got %v", got)
+	}
+	if n := testutil.CollectAndCount(ingestLag); n != 1 {
+		t.Errorf("Expected one lag series, got %d", n)
+	}
+}
+
+func TestRegistryGathers(t *testing.T) {
+	if _, err := Registry.Gather(); err != nil {
+		t.Fatalf("Gather failed: %v", err)
+	}
+}
@@ -0,0 +1,33 @@
+package metrics_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/metrics"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestAlarmCollector(t *testing.T) {
+	db := testdb.Open(t)
+	if _, err := db.Exec(`
+		INSERT INTO alarms (account_id, rule_id, ticker, status, severity, observed_value, threshold, first_detected_date, last_seen_date)
+		VALUES ('ACC-1', 'concentration', 'AAPL', 'open', 'critical', 0.5, 0.2, '2025-01-15', '2025-01-15'),
+		       ('ACC-2', 'concentration', 'AAPL', 'open', 'critical', 0.5, 0.2, '2025-01-15', '2025-01-15'),
+		       ('ACC-1', 'concentration', 'MSFT', 'resolved', 'warning', 0.3, 0.2, '2025-01-15', '2025-01-15')`); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `
+# HELP vest_alarms_open Compliance alarms not yet resolved or cleared, by status and severity.
+# TYPE vest_alarms_open gauge
+vest_alarms_open{severity="critical",status="acknowledged"} 0
+vest_alarms_open{severity="critical",status="open"} 2
+vest_alarms_open{severity="warning",status="acknowledged"} 0
+vest_alarms_open{severity="warning",status="open"} 0
+`
+	if err := testutil.CollectAndCompare(metrics.NewAlarmCollector(db), strings.NewReader(want)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+// maxAuditBody is the largest request body copied into the audit log
+const maxAuditBody = 64 << 10
+
+// redactedFields are top-level body fields never written to the audit log
+var redactedFields = []string{"secret", "password", "token", "api_key"}
+
+// Audit records each authenticated request: who called which route with what
+// parameters, the accounts the handlers noted returning, and the status.
+// Writes are recorded as changes. It runs after Authenticate.
+func Audit(l *audit.Logger, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := auth.PrincipalFrom(r.Context())
+		if l == nil || p == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		params := requestParams(r)
+		ctx := audit.WithNotes(r.Context())
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		kind := audit.KindAccess
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.URL.Path != "/graphql" {
+			kind = audit.KindChange
+		}
+		l.Record(models.AuditEntry{
+			Kind:      kind,
+			Principal: p.Label(),
+			Method:    r.Method,
+			Route:     r.URL.Path,
+			Params:    params,
+			Accounts:  audit.Noted(ctx),
+			Status:    sw.status,
+		})
+	})
+}
+
+// requestParams captures the query string and, for requests with a body, the
+// JSON body with secrets removed. The body is restored for the handler.
+func requestParams(r *http.Request) json.RawMessage {
+	params := map[string]any{}
+	if q := r.URL.Query(); len(q) > 0 {
+		q.Del("api_key")
+		params["query"] = q
+	}
+	if r.Body != nil && r.Body != http.NoBody {
+		buf, err := io.ReadAll(io.LimitReader(r.Body, maxAuditBody+1))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), r.Body), r.Body}
+		var body any
+		switch {
+		case err != nil || len(buf) > maxAuditBody:
+			params["body_truncated"] = true
+		case len(buf) == 0:
+		case json.Unmarshal(buf, &body) == nil:
+			if fields, ok := body.(map[string]any); ok {
+				for _, field := range redactedFields {
+					if _, ok := fields[field]; ok {
+						fields[field] = "[redacted]"
+					}
+				}
+			}
+			params["body"] = body
+		default:
+			params["body_bytes"] = len(buf)
+		}
+	}
+	data, _ := json.Marshal(params)
+	return data
+}
+
+// statusWriter remembers the response status and size, passing flushes
+// through for the event stream
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+	wrote  bool
+	bytes  int
+}
+
+func (sw *statusWriter) WriteHeader(status int) {
+	if !sw.wrote {
+		sw.status, sw.wrote = status, true
+	}
+	sw.ResponseWriter.WriteHeader(status)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	sw.wrote = true
+	n, err := sw.ResponseWriter.Write(b)
+	sw.bytes += n
+	return n, err
+}
+
+func (sw *statusWriter) Flush() {
+	sw.wrote = true
+	if f, ok := sw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
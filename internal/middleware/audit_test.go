@@ -0,0 +1,62 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRequestParams(t *testing.T) {
+	req := httptest.NewRequest("POST", "/webhooks?api_key=leak&verbose=1", strings.NewReader(`{"url":"https://example.com/hook","secret":"s3cret"}`))
+	var params struct {
+		Query map[string][]string `json:"query"`
+		Body  map[string]string   `json:"body"`
+	}
+	if err := json.Unmarshal(requestParams(req), &params); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := params.Query["api_key"]; ok || params.Query["verbose"][0] != "1" {
+		t.Errorf("Expected the query without credentials, got %v", params.Query)
+	}
+	if params.Body["secret"] != "[redacted]" || params.Body["url"] != "https://example.com/hook" {
+		t.Errorf("Expected the body with secrets redacted, got %v", params.Body)
+	}
+
+	body, _ := io.ReadAll(req.Body)
+	if !strings.Contains(string(body), "s3cret") {
+		t.Errorf("Expected the handler to still receive the full body, got %s", body)
+	}
+
+	req = httptest.NewRequest("PUT", "/securities/AAPL", strings.NewReader(strings.Repeat("x", maxAuditBody+1)))
+	if got := string(requestParams(req)); got != `{"body_truncated":true}` {
+		t.Errorf("Expected oversized bodies to be left out, got %s", got)
+	}
+	if body, _ := io.ReadAll(req.Body); len(body) != maxAuditBody+1 {
+		t.Errorf("Expected the handler to receive all %d bytes, got %d", maxAuditBody+1, len(body))
+	}
+}
+
+func TestStatusWriter(t *testing.T) {
+	rec := httptest.NewRecorder()
+	sw := &statusWriter{ResponseWriter: rec, status: http.StatusOK}
+	sw.WriteHeader(http.StatusNotFound)
+	sw.WriteHeader(http.StatusInternalServerError) // Superfluous; the first status stands
+	if sw.status != http.StatusNotFound {
+		t.Errorf("Expected 404, got %d", sw.status)
+	}
+
+	rec = httptest.NewRecorder()
+	sw = &statusWriter{ResponseWriter: rec, status: http.StatusOK}
+	var w http.ResponseWriter = sw
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("Expected statusWriter to support flushing for /events")
+	}
+	flusher.Flush()
+	if !rec.Flushed {
+		t.Error("Expected the flush to reach the underlying writer")
+	}
+}
@@ -1,35 +1,64 @@
 package middleware
 
 import (
-	"log"
+	"errors"
 	"net/http"
-	"os"
+	"strings"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
 )
 
-func APIKeyAuth(next http.Handler) http.Handler {
+// RequiredScope returns the scope a request needs: admin for key, webhook and
+// entitlement management and the audit log, read for reads (GraphQL is
+// query-only), ingest for other writes
+func RequiredScope(r *http.Request) string {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/keys"), strings.HasPrefix(r.URL.Path, "/webhooks"),
+		strings.HasPrefix(r.URL.Path, "/entitlements"), strings.HasPrefix(r.URL.Path, "/audit"):
+		return auth.ScopeAdmin
+	case r.Method == http.MethodGet || r.Method == http.MethodHead || r.URL.Path == "/graphql":
+		return auth.ScopeRead
+	default:
+		return auth.ScopeIngest
+	}
+}
+
+// APIKeyAuth requires an X-API-Key header holding a key with the scope the
+// request needs, and attaches the caller to the request context
+func APIKeyAuth(keys *auth.KeyStore, next http.Handler) http.Handler {
+	return Authenticate(&auth.Authenticator{Keys: keys}, next)
+}
+
+// Authenticate accepts an X-API-Key header or, when OIDC is configured, an
+// Authorization: Bearer token, and checks the caller holds the scope the
+// request needs
+func Authenticate(a *auth.Authenticator, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		apiKey := os.Getenv("API_KEY")
-		if apiKey == "" {
-			// If not configured, warn but allow? Or deny?
-			// Secure default: Deny unless configured? 
-			// For this exercise, assume if not set, we might be in dev mode or it's a misconfig.
-			// Let's log warning and deny to be safe.
-			log.Println("Warning: API_KEY env var not set. Denying request.")
-			http.Error(w, "Unauthorized: API Key not configured", http.StatusUnauthorized)
+		bearer := r.Header.Get("Authorization")
+		principal, err := a.Authenticate(r.Context(), r.Header.Get("X-API-Key"), bearer)
+		if err != nil {
+			if !errors.Is(err, auth.ErrUnauthenticated) {
+				logger.ErrorContext(r.Context(), "Authentication failed", "error", err)
+				http.Error(w, "authentication unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			if bearer != "" {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+			}
+			// Keys in the query string end up in access logs, so they are no longer accepted
+			if r.URL.Query().Has("api_key") {
+				http.Error(w, "Unauthorized: send the key in the X-API-Key header", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		clientKey := r.Header.Get("X-API-Key")
-		if clientKey == "" {
-			// Check query param as fallback? Requirement usually header.
-			clientKey = r.URL.Query().Get("api_key")
-		}
-
-		if clientKey != apiKey {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		if scope := RequiredScope(r); !principal.Can(scope) {
+			http.Error(w, "Forbidden: requires "+scope+" scope", http.StatusForbidden)
 			return
 		}
 
-		next.ServeHTTP(w, r)
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
 	})
 }
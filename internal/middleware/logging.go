@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var logger = logging.Component("http")
+
+// RequestID takes the caller's X-Request-ID, or generates one, and attaches
+// it to the request context for logging and the request's span. The ID is
+// echoed in the response.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := logging.AcceptRequestID(r.Header.Get("X-Request-ID"))
+		trace.SpanFromContext(r.Context()).SetAttributes(attribute.String("request_id", id))
+		w.Header().Set("X-Request-ID", id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// quietPaths are polled by probes and scrapers, so their access logs are debug
+var quietPaths = map[string]bool{"/health": true, "/livez": true, "/readyz": true, "/metrics": true}
+
+// AccessLog logs each request once it finishes, with its route, status,
+// size, duration and how many accounts it returned data for. It runs inside
+// RequestID.
+func AccessLog(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx := audit.WithNotes(r.Context())
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+
+		level := slog.LevelInfo
+		if quietPaths[r.URL.Path] {
+			level = slog.LevelDebug
+		}
+		logger.Log(ctx, level, "Request completed",
+			"method", r.Method,
+			"route", routeOf(mux, r),
+			"path", r.URL.Path,
+			"status", sw.status,
+			"bytes", sw.bytes,
+			"duration_ms", time.Since(start).Milliseconds(),
+			"accounts", len(audit.Noted(ctx)),
+			"remote_addr", r.RemoteAddr,
+		)
+	})
+}
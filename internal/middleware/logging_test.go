@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+)
+
+func TestRequestIDAndAccessLog(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+	var buf bytes.Buffer
+	if err := logging.Setup(&buf, "json", "info"); err != nil {
+		t.Fatal(err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		audit.Note(r.Context(), r.PathValue("id"))
+		if _, err := w.Write([]byte("ok")); err != nil {
+			t.Error(err)
+		}
+	})
+	handler := RequestID(AccessLog(mux, mux))
+
+	req := httptest.NewRequest("GET", "/accounts/ACC-1", nil)
+	req.Header.Set("X-Request-ID", "trace-123")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Request-ID"); got != "trace-123" {
+		t.Errorf("Expected the caller's request ID echoed, got %q", got)
+	}
+
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one JSON access log line, got %s", buf.String())
+	}
+	for key, want := range map[string]any{
+		"msg": "Request completed", "component": "http", "request_id": "trace-123",
+		"route": "/accounts/{id}", "status": float64(200), "bytes": float64(2), "accounts": float64(1),
+	} {
+		if entry[key] != want {
+			t.Errorf("Expected %s=%v, got %v", key, want, entry[key])
+		}
+	}
+
+	req = httptest.NewRequest("GET", "/accounts/ACC-1", nil)
+	req.Header.Set("X-Request-ID", "bad id\nwith newline")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if got := rec.Header().Get("X-Request-ID"); got == "" || got == req.Header.Get("X-Request-ID") {
+		t.Errorf("Expected an unsafe request ID to be replaced, got %q", got)
+	}
+}
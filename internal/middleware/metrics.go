@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/metrics"
+)
+
+// unmatchedRoute labels requests no route pattern matched, keeping arbitrary
+// paths out of the metric labels
+const unmatchedRoute = "unmatched"
+
+// Metrics counts each request and its latency by method, the mux pattern it
+// matched and the response status. It wraps authentication, audit and rate
+// limiting, so rejected and unauthenticated requests are counted too.
+func Metrics(mux *http.ServeMux, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(sw, r)
+		metrics.ObserveRequest(r.Method, routeOf(mux, r), sw.status, time.Since(start))
+	})
+}
+
+// routeOf returns the pattern r matches without its method, such as
+// /accounts/{id}/positions
+func routeOf(mux *http.ServeMux, r *http.Request) string {
+	_, pattern := mux.Handler(r)
+	if _, path, ok := strings.Cut(pattern, " "); ok {
+		pattern = path
+	}
+	if pattern == "" {
+		return unmatchedRoute
+	}
+	return pattern
+}
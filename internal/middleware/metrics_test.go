@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/metrics"
+)
+
+func TestMetricsLabelsRoutePattern(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "not found", http.StatusNotFound)
+	})
+	handler := Metrics(mux, mux)
+	for _, path := range []string{"/accounts/ACC-1", "/accounts/ACC-2", "/no/such/route"} {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body := rec.Body.String()
+	for _, want := range []string{
+		`vest_http_requests_total{method="GET",route="/accounts/{id}",status="404"} 2`,
+		`vest_http_requests_total{method="GET",route="unmatched",status="404"} 1`,
+		`vest_http_request_duration_seconds_count{method="GET",route="/accounts/{id}",status="404"} 2`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("Expected %s in metrics output", want)
+		}
+	}
+	if strings.Contains(body, "ACC-1") {
+		t.Error("Account IDs from the path leaked into metric labels")
+	}
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+)
+
+// RateLimit charges each request to the authenticated caller's buckets and
+// rejects it with 429 once one is empty. It runs after Authenticate; a store
+// failure lets the request through rather than taking the API down.
+func RateLimit(l *ratelimit.Limiter, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p := auth.PrincipalFrom(r.Context())
+		if l == nil || p == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		d, err := l.Allow(r.Context(), p.Label(), r.URL.Path)
+		if err != nil {
+			logger.ErrorContext(r.Context(), "Rate limit check failed", "error", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if d.Limit > 0 {
+			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(d.Limit))
+			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(d.Remaining))
+			w.Header().Set("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(d.Reset)))
+		}
+		if !d.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(d.RetryAfter)))
+			http.Error(w, "Too Many Requests: rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ceilSeconds rounds up so clients never retry before a token is available
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,59 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+)
+
+func TestRateLimit(t *testing.T) {
+	rules, err := ratelimit.ParseRules("*=1/m:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := RateLimit(ratelimit.NewLimiter(rules, ratelimit.NewMemoryStore()), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := func(keyID int64) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/positions?date=2025-01-15", nil)
+		req = req.WithContext(auth.WithPrincipal(req.Context(), &auth.Principal{KeyID: keyID, Scopes: []string{auth.ScopeRead}}))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	rec := request(1)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 OK, got %d", rec.Code)
+	}
+	if rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != "1" || rec.Header().Get("X-RateLimit-Reset") != "60" {
+		t.Errorf("Unexpected rate limit headers %v", rec.Header())
+	}
+
+	request(1)
+	rec = request(1)
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("Expected 429 once the burst is spent, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") != "60" || rec.Header().Get("X-RateLimit-Remaining") != "0" {
+		t.Errorf("Unexpected headers on 429 %v", rec.Header())
+	}
+
+	if rec := request(2); rec.Code != http.StatusOK {
+		t.Errorf("Expected another key to have its own bucket, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitDisabled(t *testing.T) {
+	handler := RateLimit(nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest("GET", "/positions", nil))
+	if rec.Code != http.StatusOK || rec.Header().Get("X-RateLimit-Limit") != "" {
+		t.Errorf("Expected requests to pass untouched without a limiter, got %d %v", rec.Code, rec.Header())
+	}
+}
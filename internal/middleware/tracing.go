@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Tracing starts a span for each request, continuing the caller's trace when
+// it sends a traceparent header. Spans are named by method and route pattern,
+// such as GET /positions. It runs outermost, so authentication, rate limiting
+// and encoding all fall within the span; probes and scrapes are not traced.
+func Tracing(mux *http.ServeMux, next http.Handler) http.Handler {
+	route := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		trace.SpanFromContext(r.Context()).SetAttributes(semconv.HTTPRoute(routeOf(mux, r)))
+		next.ServeHTTP(w, r)
+	})
+	return otelhttp.NewHandler(route, "http",
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method + " " + routeOf(mux, r)
+		}),
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !quietPaths[r.URL.Path]
+		}),
+	)
+}
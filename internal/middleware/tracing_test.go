@@ -0,0 +1,50 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestTracingNamesSpansByRoute(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	var traced trace.SpanContext
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /accounts/{id}", func(w http.ResponseWriter, r *http.Request) {
+		traced = trace.SpanContextFromContext(r.Context())
+	})
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {})
+	handler := Tracing(mux, RequestID(mux))
+
+	req := httptest.NewRequest("GET", "/accounts/ACC-1", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/health", nil))
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("Expected one span, with /health untraced, got %d", len(spans))
+	}
+	if spans[0].Name() != "GET /accounts/{id}" {
+		t.Errorf("Expected the span named by route, got %q", spans[0].Name())
+	}
+	attrs := make(map[string]string)
+	for _, kv := range spans[0].Attributes() {
+		attrs[string(kv.Key)] = kv.Value.Emit()
+	}
+	if attrs["http.route"] != "/accounts/{id}" || attrs["request_id"] != "req-1" {
+		t.Errorf("Expected the route and request ID as attributes, got %v", attrs)
+	}
+	if traced.TraceID() != spans[0].SpanContext().TraceID() {
+		t.Error("Expected the handler's context to carry the request span")
+	}
+}
@@ -0,0 +1,59 @@
+package migrate
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_add_index.up.sql":     {Data: []byte("CREATE INDEX ...")},
+		"m/0010_add_index.down.sql":   {Data: []byte("DROP INDEX ...")},
+		"m/0002_add_table.up.sql":     {Data: []byte("CREATE TABLE ...")},
+		"m/0002_add_table.down.sql":   {Data: []byte("DROP TABLE ...")},
+		"m/0001_baseline.up.sql":      {Data: []byte("CREATE TABLE ...")},
+		"m/0001_baseline.down.sql":    {Data: []byte("DROP TABLE ...")},
+		"other/0003_ignored.up.sql":   {Data: []byte("")},
+		"other/0003_ignored.down.sql": {Data: []byte("")},
+	}
+	migrations, err := Load(fsys, "m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 || migrations[0].Version != 1 || migrations[1].Version != 2 || migrations[2].Version != 10 {
+		t.Fatalf("expected versions 1, 2, 10 in order, got %+v", migrations)
+	}
+	if migrations[2].Name != "add_index" || migrations[2].Up != "CREATE INDEX ..." || migrations[2].Down != "DROP INDEX ..." {
+		t.Errorf("unexpected migration %+v", migrations[2])
+	}
+
+	invalid := map[string]fstest.MapFS{
+		"missing down": {"m/0001_baseline.up.sql": {Data: []byte("x")}},
+		"bad name":     {"m/baseline.sql": {Data: []byte("x")}},
+		"name clash": {
+			"m/0001_a.up.sql": {Data: []byte("x")}, "m/0001_a.down.sql": {Data: []byte("x")},
+			"m/0001_b.up.sql": {Data: []byte("x")}, "m/0001_b.down.sql": {Data: []byte("x")},
+		},
+	}
+	for name, fsys := range invalid {
+		if _, err := Load(fsys, "m"); !errors.Is(err, ErrInvalidMigration) {
+			t.Errorf("%s: expected ErrInvalidMigration, got %v", name, err)
+		}
+	}
+}
+
+func TestEmbedded(t *testing.T) {
+	migrations, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 || migrations[0].Name != "baseline" {
+		t.Fatalf("expected the baseline first, got %+v", migrations)
+	}
+	for i, m := range migrations {
+		if m.Version != int64(i+1) {
+			t.Errorf("expected consecutive versions, %04d_%s is at position %d", m.Version, m.Name, i+1)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	schema "github.com/AndrewCharlesHay/vest/db"
+	"github.com/AndrewCharlesHay/vest/internal/migrate"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+// describe lists every column, constraint, index and trigger in the current
+// schema as comparable lines
+func describe(t *testing.T, db *sql.DB) []string {
+	t.Helper()
+	rows, err := db.Query(`
+		SELECT 'column ' || table_name || '.' || column_name || ' ' || data_type
+			|| COALESCE('(' || character_maximum_length || ')', '')
+			|| COALESCE('(' || numeric_precision || ',' || numeric_scale || ')', '')
+			|| ' null=' || is_nullable || ' default=' || COALESCE(column_default, '')
+		FROM information_schema.columns
+		WHERE table_schema = current_schema() AND table_name <> 'schema_migrations'
+		UNION ALL
+		SELECT 'constraint ' || conrelid::regclass || ' ' || pg_get_constraintdef(oid)
+		FROM pg_constraint
+		WHERE connamespace = current_schema()::regnamespace AND conrelid::regclass::text <> 'schema_migrations'
+		UNION ALL
+		SELECT 'index ' || replace(indexdef, current_schema() || '.', '')
+		FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'
+		UNION ALL
+		SELECT 'trigger ' || t.tgname || ' ' || replace(pg_get_triggerdef(t.oid), current_schema() || '.', '')
+		FROM pg_trigger t JOIN pg_class c ON c.oid = t.tgrelid
+		WHERE NOT t.tgisinternal AND c.relnamespace = current_schema()::regnamespace
+		UNION ALL
+		SELECT 'function ' || proname || ' ' || md5(btrim(regexp_replace(prosrc, '\s+', ' ', 'g')))
+		FROM pg_proc
+		WHERE pronamespace = current_schema()::regnamespace
+		ORDER BY 1
+	`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+	var lines []string
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// TestMigrationsMatchSchema keeps db/schema.sql the source of truth: every
+// migration applied in order must produce exactly the schema it describes
+func TestMigrationsMatchSchema(t *testing.T) {
+	migrated := testdb.Open(t)
+	declared := testdb.OpenEmpty(t)
+	if _, err := declared.Exec(schema.Schema); err != nil {
+		t.Fatalf("db/schema.sql: %v", err)
+	}
+
+	got, want := describe(t, migrated), describe(t, declared)
+	if len(want) == 0 {
+		t.Fatal("db/schema.sql created nothing")
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		seen := make(map[string]bool)
+		for _, l := range got {
+			seen[l] = true
+		}
+		for _, l := range want {
+			if !seen[l] {
+				t.Errorf("missing after migrations: %s", l)
+			}
+			delete(seen, l)
+		}
+		for l := range seen {
+			t.Errorf("not in db/schema.sql: %s", l)
+		}
+	}
+}
+
+func TestUpDownUp(t *testing.T) {
+	db := testdb.OpenEmpty(t)
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := migrate.NewMigrator(db, migrations)
+	ctx := context.Background()
+
+	// Concurrent tasks: one applies everything, the other finds nothing to do
+	var wg sync.WaitGroup
+	counts := make([]int, 2)
+	for i := range counts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			applied, err := m.Up(ctx)
+			if err != nil {
+				t.Errorf("up: %v", err)
+			}
+			counts[i] = len(applied)
+		}(i)
+	}
+	wg.Wait()
+	if counts[0]+counts[1] != len(migrations) {
+		t.Fatalf("expected %d migrations applied once, got %v", len(migrations), counts)
+	}
+
+	reverted, err := m.Down(ctx, len(migrations))
+	if err != nil || len(reverted) != len(migrations) {
+		t.Fatalf("down: reverted %d, %v", len(reverted), err)
+	}
+	if left := describe(t, db); len(left) != 0 {
+		t.Errorf("expected an empty schema after reverting everything, got %v", left)
+	}
+	if applied, err := m.Up(ctx); err != nil || len(applied) != len(migrations) {
+		t.Fatalf("up again: applied %d, %v", len(applied), err)
+	}
+
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("%04d_%s not applied", s.Version, s.Name)
+		}
+	}
+
+	// A build that knows fewer migrations still starts, but will not revert
+	older := migrate.NewMigrator(db, migrations[:1])
+	if _, err := older.Up(ctx); err != nil {
+		t.Errorf("older build up: %v", err)
+	}
+	if _, err := older.Down(ctx, 1); !errors.Is(err, migrate.ErrUnknownVersion) {
+		t.Errorf("expected ErrUnknownVersion from an older build, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 )
 
@@ -47,13 +48,243 @@ type BlotterResponse struct {
 // PositionResponse represents the % of funds by ticker
 type PositionResponse struct {
 	AccountID   string             `json:"account_id"`
-	Allocations map[string]float64 `json:"allocations"` // Ticker -> Percentage
+	GroupID     string             `json:"group_id,omitempty"` // Set when aggregated over an account group
+	Exposure    string             `json:"exposure"`
+	GroupBy     string             `json:"group_by,omitempty"` // Security attribute allocations are keyed by
+	Total       float64            `json:"total"`              // Denominator used for allocations
+	Allocations map[string]float64 `json:"allocations"`        // Ticker (or group) -> Percentage
+	Warning     string             `json:"warning,omitempty"`  // Zero or negative total
+}
+
+// Violation is a single compliance rule breach within an account
+type Violation struct {
+	RuleID        string  `json:"rule_id"`
+	Ticker        string  `json:"ticker"`
+	ObservedValue float64 `json:"observed_value"` // % of portfolio
+	Threshold     float64 `json:"threshold"`
+	Severity      string  `json:"severity"`
+	MarketValue   float64 `json:"market_value"`
+	AccountTotal  float64 `json:"account_total"`
+	Message       string  `json:"message"`
 }
 
 // AlarmResponse represents the alarm compliance check
 type AlarmResponse struct {
-	Date          string `json:"date"`
-	AccountID     string `json:"account_id"`
-	HasViolation  bool   `json:"has_violation"`
-	ViolationInfo string `json:"violation_info,omitempty"`
+	Date          string      `json:"date"`
+	AccountID     string      `json:"account_id"`
+	GroupID       string      `json:"group_id,omitempty"` // Set when aggregated over an account group
+	Exposure      string      `json:"exposure"`
+	HasViolation  bool        `json:"has_violation"`
+	ViolationInfo string      `json:"violation_info,omitempty"` // Human-readable summary
+	Violations    []Violation `json:"violations"`
+	Warning       string      `json:"warning,omitempty"` // Set when the account could not be evaluated
+}
+
+// Alarm statuses
+const (
+	AlarmOpen         = "open"
+	AlarmAcknowledged = "acknowledged"
+	AlarmResolved     = "resolved"
+	AlarmAutoCleared  = "auto_cleared"
+)
+
+// Alarm is a persisted compliance breach and its lifecycle state
+type Alarm struct {
+	ID                int64        `json:"id"`
+	AccountID         string       `json:"account_id"`
+	RuleID            string       `json:"rule_id"`
+	Ticker            string       `json:"ticker"`
+	Status            string       `json:"status"`
+	Severity          string       `json:"severity"`
+	ObservedValue     float64      `json:"observed_value"`
+	Threshold         float64      `json:"threshold"`
+	FirstDetectedDate string       `json:"first_detected_date"`
+	LastSeenDate      string       `json:"last_seen_date"`
+	Assignee          string       `json:"assignee,omitempty"`
+	CreatedAt         time.Time    `json:"created_at"`
+	UpdatedAt         time.Time    `json:"updated_at"`
+	ClosedAt          *time.Time   `json:"closed_at,omitempty"`
+	Events            []AlarmEvent `json:"events,omitempty"`
+}
+
+// AlarmEvent is one entry in an alarm's audit trail
+type AlarmEvent struct {
+	ID         int64     `json:"id"`
+	AlarmID    int64     `json:"alarm_id"`
+	Action     string    `json:"action"` // detected, acknowledged, resolved, auto_cleared, assigned, comment
+	FromStatus string    `json:"from_status,omitempty"`
+	ToStatus   string    `json:"to_status,omitempty"`
+	Actor      string    `json:"actor"`
+	Comment    string    `json:"comment,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// WebhookSubscription is a registered receiver of compliance events
+type WebhookSubscription struct {
+	ID        int64     `json:"id"`
+	Name      string    `json:"name"`
+	URL       string    `json:"url"`
+	Secret    string    `json:"secret,omitempty"` // Only returned on creation
+	Events    []string  `json:"events"`           // Empty means all events
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// WebhookDelivery is one event sent (or to be sent) to a subscription
+type WebhookDelivery struct {
+	ID             int64      `json:"id"`
+	SubscriptionID int64      `json:"subscription_id"`
+	Event          string     `json:"event"`
+	Status         string     `json:"status"` // pending, delivered, failed
+	Attempts       int        `json:"attempts"`
+	ResponseCode   int        `json:"response_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty"`
+}
+
+// APIKey is a named credential. Only a hash of the key is stored.
+type APIKey struct {
+	ID         int64      `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`        // Leading characters of the key, for identification
+	Key        string     `json:"key,omitempty"` // Only returned when issued
+	Scopes     []string   `json:"scopes"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+}
+
+// Entitlement lets a principal read one account, every account in a group
+// (and its descendants), or every account
+type Entitlement struct {
+	ID          int64     `json:"id"`
+	Principal   string    `json:"principal"` // key:<id> or user:<OIDC subject>
+	AccountID   string    `json:"account_id,omitempty"`
+	GroupID     string    `json:"group_id,omitempty"`
+	AllAccounts bool      `json:"all_accounts,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// Event is a change notification streamed to dashboards over /events
+type Event struct {
+	ID        int64           `json:"id"`
+	Type      string          `json:"type"`
+	Data      json.RawMessage `json:"data"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// AccountHistoryPoint is one day of an account's aggregate value
+type AccountHistoryPoint struct {
+	Date             string   `json:"date"`
+	TotalMarketValue float64  `json:"total_market_value"`
+	LongMarketValue  float64  `json:"long_market_value"`
+	ShortMarketValue float64  `json:"short_market_value"`
+	Holdings         int      `json:"holdings"`
+	Change           *float64 `json:"change"`     // vs previous date with data; null for the first
+	ChangePct        *float64 `json:"change_pct"` // null when previous total is 0
+}
+
+// TickerHistoryPoint is one day of a single holding within an account
+type TickerHistoryPoint struct {
+	Date           string   `json:"date"`
+	Quantity       float64  `json:"quantity"`
+	MarketValue    float64  `json:"market_value"`
+	AccountPercent float64  `json:"account_percent"`
+	Change         *float64 `json:"change"`
+}
+
+// AccountHistoryResponse is the time series for an account (or one of its tickers)
+type AccountHistoryResponse struct {
+	AccountID string                `json:"account_id"`
+	Ticker    string                `json:"ticker,omitempty"`
+	From      string                `json:"from,omitempty"`
+	To        string                `json:"to,omitempty"`
+	Points    []AccountHistoryPoint `json:"points,omitempty"`
+	Tickers   []TickerHistoryPoint  `json:"ticker_points,omitempty"`
+}
+
+// Security is a security master record keyed by ticker
+type Security struct {
+	Ticker     string    `json:"ticker"`
+	CUSIP      string    `json:"cusip,omitempty"`
+	ISIN       string    `json:"isin,omitempty"`
+	SEDOL      string    `json:"sedol,omitempty"`
+	Issuer     string    `json:"issuer,omitempty"`
+	AssetClass string    `json:"asset_class,omitempty"`
+	Sector     string    `json:"sector,omitempty"`
+	Currency   string    `json:"currency,omitempty"`
+	Multiplier float64   `json:"multiplier"`
+	UpdatedAt  time.Time `json:"updated_at,omitempty"`
+}
+
+// Account is an account master record. Aliases are custodian account IDs
+// that ingestion resolves to AccountID.
+type Account struct {
+	AccountID    string         `json:"account_id"`
+	Name         string         `json:"name"`
+	Type         string         `json:"type,omitempty"` // e.g. individual, joint, ira, trust
+	BaseCurrency string         `json:"base_currency,omitempty"`
+	Status       string         `json:"status"` // active, closed
+	Aliases      []AccountAlias `json:"aliases"`
+	Groups       []string       `json:"groups,omitempty"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+// AccountAlias is a custodian's identifier for an account
+type AccountAlias struct {
+	Alias     string `json:"alias"`
+	Custodian string `json:"custodian,omitempty"`
+}
+
+// AccountGroup is a node in the account hierarchy (household, strategy, firm)
+type AccountGroup struct {
+	GroupID  string   `json:"group_id"`
+	Name     string   `json:"name"`
+	Type     string   `json:"type"`
+	ParentID string   `json:"parent_id,omitempty"`
+	Accounts []string `json:"accounts"`           // Direct members
+	Children []string `json:"children,omitempty"` // Direct child groups
+	Resolved []string `json:"resolved_accounts,omitempty"`
+}
+
+// PositionDiff compares one account/ticker between two dates
+type PositionDiff struct {
+	AccountID        string  `json:"account_id"`
+	Ticker           string  `json:"ticker"`
+	FromQuantity     float64 `json:"from_quantity"`
+	ToQuantity       float64 `json:"to_quantity"`
+	QuantityDelta    float64 `json:"quantity_delta"`
+	FromMarketValue  float64 `json:"from_market_value"`
+	ToMarketValue    float64 `json:"to_market_value"`
+	MarketValueDelta float64 `json:"market_value_delta"`
+	Change           string  `json:"change"` // opened, closed, increased, decreased, unchanged, reversed
+}
+
+// PositionDiffResponse is the set of changes between two dates
+type PositionDiffResponse struct {
+	From    string         `json:"from"`
+	To      string         `json:"to"`
+	Summary map[string]int `json:"summary"` // Change -> count
+	Changes []PositionDiff `json:"changes"`
+}
+
+// AuditEntry is one record in the hash-chained audit log. Access entries
+// record a request; change entries record a mutation.
+type AuditEntry struct {
+	ID        int64           `json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Kind      string          `json:"kind"`             // access or change
+	Principal string          `json:"principal"`        // key:<id>, user:<sub>, bootstrap or a system actor such as ingestor
+	Method    string          `json:"method,omitempty"` // HTTP method, or GRPC
+	Route     string          `json:"route,omitempty"`  // Request path or gRPC method
+	Params    json.RawMessage `json:"params"`           // Query parameters and request body
+	Accounts  []string        `json:"accounts"`         // Accounts whose data was returned or changed
+	Status    int             `json:"status,omitempty"` // HTTP status, or gRPC code for GRPC
+	Action    string          `json:"action,omitempty"` // What changed, e.g. file.ingested
+	Detail    json.RawMessage `json:"detail"`
+	PrevHash  string          `json:"prev_hash"`
+	Hash      string          `json:"hash"`
 }
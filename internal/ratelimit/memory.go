@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// pruneEvery is how many takes pass between sweeps for full, idle buckets
+const pruneEvery = 1024
+
+// MemoryStore keeps buckets in process. Each server instance counts
+// separately; use PostgresStore to share limits.
+type MemoryStore struct {
+	Now func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	takes   int
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // When the bucket will have refilled, so it can be dropped
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{Now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+func (m *MemoryStore) Take(_ context.Context, buckets []Bucket) ([]float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.Now()
+	m.takes++
+	if m.takes%pruneEvery == 0 {
+		for k, b := range m.buckets {
+			if now.After(b.full) {
+				delete(m.buckets, k)
+			}
+		}
+	}
+
+	held := make([]*bucket, len(buckets))
+	tokens := make([]float64, len(buckets))
+	for i, spec := range buckets {
+		b, ok := m.buckets[spec.Key]
+		if !ok {
+			b = &bucket{tokens: float64(spec.Burst), updated: now}
+			m.buckets[spec.Key] = b
+		}
+		elapsed := math.Max(0, now.Sub(b.updated).Seconds())
+		b.tokens = math.Min(float64(spec.Burst), b.tokens+elapsed*spec.Rate)
+		b.updated = now
+		held[i], tokens[i] = b, b.tokens
+	}
+
+	take := allWhole(tokens)
+	for i, b := range held {
+		if take {
+			b.tokens--
+		}
+		b.full = now.Add(seconds((float64(buckets[i].Burst) - b.tokens) / buckets[i].Rate))
+	}
+	return tokens, nil
+}
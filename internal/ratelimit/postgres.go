@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+)
+
+var logger = logging.Component("ratelimit")
+
+// PostgresStore keeps buckets in the rate_limit_buckets table so every server
+// instance draws from the same counters. Times come from the database clock.
+type PostgresStore struct {
+	DB            *sql.DB
+	PruneInterval time.Duration // How often full buckets are deleted
+}
+
+func NewPostgresStore(db *sql.DB) *PostgresStore {
+	return &PostgresStore{DB: db, PruneInterval: 10 * time.Minute}
+}
+
+// Take checks and takes from every bucket in one transaction, whatever the
+// number of rules
+func (p *PostgresStore) Take(ctx context.Context, buckets []Bucket) ([]float64, error) {
+	keys := make([]string, len(buckets))
+	bursts := make([]float64, len(buckets))
+	rates := make([]float64, len(buckets))
+	index := make(map[string]int, len(buckets))
+	for i, b := range buckets {
+		keys[i], bursts[i], rates[i] = b.Key, float64(b.Burst), b.Rate
+		index[b.Key] = i
+	}
+
+	tx, err := p.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+			logger.ErrorContext(ctx, "Failed to rollback tx", "error", err)
+		}
+	}()
+
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (key, tokens, updated_at, full_at)
+		SELECT key, burst, now(), now() FROM unnest($1::text[], $2::float8[]) AS b(key, burst)
+		ON CONFLICT (key) DO NOTHING`, keys, bursts); err != nil {
+		return nil, err
+	}
+	// The row locks serialize concurrent requests for the same buckets; taking
+	// them in key order keeps two requests from deadlocking
+	rows, err := tx.QueryContext(ctx, `
+		SELECT r.key, LEAST(b.burst, r.tokens + GREATEST(0, EXTRACT(EPOCH FROM now() - r.updated_at)) * b.rate)
+		FROM rate_limit_buckets r
+		JOIN unnest($1::text[], $2::float8[], $3::float8[]) AS b(key, burst, rate) ON b.key = r.key
+		ORDER BY r.key
+		FOR UPDATE OF r`, keys, bursts, rates)
+	if err != nil {
+		return nil, err
+	}
+	tokens := make([]float64, len(buckets))
+	for rows.Next() {
+		var key string
+		var t float64
+		if err := rows.Scan(&key, &t); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		tokens[index[key]] = t
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// A refused request leaves the buckets as they were, which refill the same
+	if !allWhole(tokens) {
+		return tokens, tx.Commit()
+	}
+	left := make([]float64, len(tokens))
+	for i, t := range tokens {
+		left[i] = t - 1
+	}
+	if _, err := tx.ExecContext(ctx, `
+		UPDATE rate_limit_buckets r
+		SET tokens = b.tokens, updated_at = now(), full_at = now() + make_interval(secs => (b.burst - b.tokens) / b.rate)
+		FROM unnest($1::text[], $2::float8[], $3::float8[], $4::float8[]) AS b(key, tokens, burst, rate)
+		WHERE r.key = b.key`, keys, left, bursts, rates); err != nil {
+		return nil, err
+	}
+	return tokens, tx.Commit()
+}
+
+// Start deletes buckets that have refilled, which behave the same as missing
+// ones, every PruneInterval until ctx is cancelled
+func (p *PostgresStore) Start(ctx context.Context) {
+	ticker := time.NewTicker(p.PruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := p.DB.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE full_at < now()`); err != nil {
+				logger.ErrorContext(ctx, "Failed to prune rate limit buckets", "error", err)
+			}
+		}
+	}
+}
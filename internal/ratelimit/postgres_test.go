@@ -0,0 +1,80 @@
+package ratelimit_test
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+// Two limiters on one table stand in for two server instances
+func TestPostgresStoreSharesBuckets(t *testing.T) {
+	db := testdb.Open(t)
+	rules, err := ratelimit.ParseRules("*=1/m:5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	instances := []*ratelimit.Limiter{
+		ratelimit.NewLimiter(rules, ratelimit.NewPostgresStore(db)),
+		ratelimit.NewLimiter(rules, ratelimit.NewPostgresStore(db)),
+	}
+
+	var allowed atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(l *ratelimit.Limiter) {
+			defer wg.Done()
+			d, err := l.Allow(context.Background(), "key:1", "/positions")
+			if err != nil {
+				t.Errorf("allow: %v", err)
+				return
+			}
+			if d.Allowed {
+				allowed.Add(1)
+			}
+		}(instances[i%2])
+	}
+	wg.Wait()
+	if n := allowed.Load(); n != 5 {
+		t.Errorf("expected exactly the burst of 5 across instances, got %d", n)
+	}
+	if d, err := instances[0].Allow(context.Background(), "key:2", "/positions"); err != nil || !d.Allowed {
+		t.Errorf("another caller must have its own bucket, got %+v, %v", d, err)
+	}
+}
+
+func TestPostgresStoreTakesOnlyWhenAllAllow(t *testing.T) {
+	db := testdb.Open(t)
+	rules, err := ratelimit.ParseRules("*=1/m, *=3/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l := ratelimit.NewLimiter(rules, ratelimit.NewPostgresStore(db))
+
+	for i := 0; i < 4; i++ {
+		d, err := l.Allow(context.Background(), "key:1", "/positions")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if d.Allowed != (i == 0) {
+			t.Fatalf("request %d: unexpected decision %+v", i+1, d)
+		}
+	}
+
+	var tokens float64
+	var fullAt time.Time
+	if err := db.QueryRow(`SELECT tokens, full_at FROM rate_limit_buckets WHERE key = 'key:1|*=3/d:3'`).Scan(&tokens, &fullAt); err != nil {
+		t.Fatal(err)
+	}
+	if tokens < 1.99 || tokens > 2.01 {
+		t.Errorf("denied requests must not use the daily quota, bucket holds %v", tokens)
+	}
+	if !fullAt.After(time.Now()) {
+		t.Errorf("expected the daily bucket to refill in the future, got %v", fullAt)
+	}
+}
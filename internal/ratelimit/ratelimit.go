@@ -0,0 +1,232 @@
+// Package ratelimit applies token-bucket limits per caller and route. Each
+// rule is its own bucket, so a short burst limit and a daily quota can apply
+// to the same request.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrInvalidRule = errors.New("invalid rate limit rule")
+
+// Rule allows Count requests per Period, refilled continuously, with up to
+// Burst at once. Route is a path prefix or "*" for every route. A rule with
+// a Principal replaces the general rule of the same Route and Period for
+// that caller.
+type Rule struct {
+	Principal string // key:<id>, user:<subject> or empty for everyone
+	Route     string
+	Count     int
+	Period    time.Duration
+	Burst     int
+}
+
+// Rate is the refill rate in requests per second
+func (r Rule) Rate() float64 {
+	return float64(r.Count) / r.Period.Seconds()
+}
+
+func (r Rule) matches(path string) bool {
+	return r.Route == "*" || path == r.Route || strings.HasPrefix(path, strings.TrimSuffix(r.Route, "/")+"/")
+}
+
+func (r Rule) String() string {
+	s := fmt.Sprintf("%s=%d/%s:%d", r.Route, r.Count, periodUnit(r.Period), r.Burst)
+	if r.Principal != "" {
+		s = r.Principal + "@" + s
+	}
+	return s
+}
+
+var periods = map[string]time.Duration{"s": time.Second, "m": time.Minute, "h": time.Hour, "d": 24 * time.Hour}
+
+func periodUnit(d time.Duration) string {
+	for unit, p := range periods {
+		if p == d {
+			return unit
+		}
+	}
+	return d.String()
+}
+
+// ParseRules reads rules such as
+//
+//	*=20/s:40, /positions=5/s, *=50000/d, key:12@*=200/s
+//
+// where each is [principal@]route=count/period[:burst] and period is s, m, h
+// or d. Burst defaults to count.
+func ParseRules(s string) ([]Rule, error) {
+	var rules []Rule
+	for _, item := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ';' }) {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		var r Rule
+		spec := item
+		if principal, rest, ok := strings.Cut(spec, "@"); ok {
+			r.Principal, spec = principal, rest
+		}
+		route, limit, ok := strings.Cut(spec, "=")
+		if !ok || route == "" || (route != "*" && !strings.HasPrefix(route, "/")) {
+			return nil, fmt.Errorf("%w %q: want [principal@]route=count/period[:burst]", ErrInvalidRule, item)
+		}
+		r.Route = route
+		limit, burst, hasBurst := strings.Cut(limit, ":")
+		count, unit, ok := strings.Cut(limit, "/")
+		var err error
+		if r.Count, err = strconv.Atoi(count); !ok || err != nil || r.Count <= 0 {
+			return nil, fmt.Errorf("%w %q: count must be a positive integer", ErrInvalidRule, item)
+		}
+		if r.Period, ok = periods[unit]; !ok {
+			return nil, fmt.Errorf("%w %q: period must be s, m, h or d", ErrInvalidRule, item)
+		}
+		r.Burst = r.Count
+		if hasBurst {
+			if r.Burst, err = strconv.Atoi(burst); err != nil || r.Burst <= 0 {
+				return nil, fmt.Errorf("%w %q: burst must be a positive integer", ErrInvalidRule, item)
+			}
+		}
+		rules = append(rules, r)
+	}
+	return rules, nil
+}
+
+// Bucket names a token bucket and how it refills
+type Bucket struct {
+	Key   string
+	Rate  float64
+	Burst int
+}
+
+// Store keeps bucket state. Take refills each bucket at its rate up to its
+// burst and returns the tokens each held before the request. Only when every
+// bucket holds a whole token does it remove one from each, so a request one
+// rule rejects uses up none of another's quota.
+type Store interface {
+	Take(ctx context.Context, buckets []Bucket) ([]float64, error)
+}
+
+// allWhole reports whether every bucket can give a token
+func allWhole(tokens []float64) bool {
+	for _, t := range tokens {
+		if t < 1 {
+			return false
+		}
+	}
+	return true
+}
+
+// Decision is the outcome for the tightest rule that applied
+type Decision struct {
+	Allowed    bool
+	Limit      int           // Burst of the rule
+	Remaining  int           // Whole requests left right now
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next request would be allowed; zero when allowed
+}
+
+// Limiter checks requests against its rules
+type Limiter struct {
+	Rules []Rule
+	Store Store
+
+	mu sync.RWMutex
+}
+
+func NewLimiter(rules []Rule, store Store) *Limiter {
+	return &Limiter{Rules: rules, Store: store}
+}
+
+// SetRules replaces the rules for requests that follow. Buckets are keyed by
+// rule, so a changed rule starts with a full bucket.
+func (l *Limiter) SetRules(rules []Rule) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.Rules = rules
+}
+
+// applicable returns the rules for a caller and path, with caller-specific
+// rules replacing general ones of the same route and period
+func (l *Limiter) applicable(principal, path string) []Rule {
+	type slot struct {
+		route  string
+		period time.Duration
+	}
+	chosen := make(map[slot]int)
+	var out []Rule
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	for _, r := range l.Rules {
+		if !r.matches(path) || (r.Principal != "" && r.Principal != principal) {
+			continue
+		}
+		s := slot{r.Route, r.Period}
+		if i, ok := chosen[s]; ok {
+			if r.Principal != "" {
+				out[i] = r
+			}
+			continue
+		}
+		chosen[s] = len(out)
+		out = append(out, r)
+	}
+	return out
+}
+
+// Allow takes a token from every bucket that applies, if each has one, and
+// reports the tightest. Requests no rule matches are always allowed.
+func (l *Limiter) Allow(ctx context.Context, principal, path string) (Decision, error) {
+	rules := l.applicable(principal, path)
+	if len(rules) == 0 {
+		return Decision{Allowed: true}, nil
+	}
+	buckets := make([]Bucket, len(rules))
+	for i, r := range rules {
+		buckets[i] = Bucket{Key: principal + "|" + r.String(), Rate: r.Rate(), Burst: r.Burst}
+	}
+	tokens, err := l.Store.Take(ctx, buckets)
+	if err != nil {
+		return Decision{Allowed: true}, err
+	}
+	taken := allWhole(tokens)
+
+	d := Decision{Allowed: true, Remaining: math.MaxInt}
+	for i, r := range rules {
+		rd := decide(r, tokens[i], taken)
+		if !rd.Allowed && (d.Allowed || rd.RetryAfter > d.RetryAfter) {
+			d = rd
+		} else if d.Allowed && (rd.Allowed && rd.Remaining < d.Remaining) {
+			d = rd
+		}
+	}
+	return d, nil
+}
+
+// decide reports a rule's bucket, which held tokens before the request and
+// gave one up if taken
+func decide(r Rule, tokens float64, taken bool) Decision {
+	d := Decision{Limit: r.Burst, Allowed: tokens >= 1}
+	left := tokens
+	if d.Allowed {
+		if taken {
+			left--
+		}
+	} else {
+		d.RetryAfter = seconds((1 - tokens) / r.Rate())
+	}
+	d.Remaining = int(math.Max(0, math.Floor(left)))
+	d.Reset = seconds((float64(r.Burst) - left) / r.Rate())
+	return d
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,152 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("*=20/s:40, /positions=5/m; key:12@*=200/s:400,*=50000/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Rule{
+		{Route: "*", Count: 20, Period: time.Second, Burst: 40},
+		{Route: "/positions", Count: 5, Period: time.Minute, Burst: 5},
+		{Principal: "key:12", Route: "*", Count: 200, Period: time.Second, Burst: 400},
+		{Route: "*", Count: 50000, Period: 24 * time.Hour, Burst: 50000},
+	}
+	if len(rules) != len(want) {
+		t.Fatalf("expected %d rules, got %+v", len(want), rules)
+	}
+	for i := range want {
+		if rules[i] != want[i] {
+			t.Errorf("rule %d: expected %+v, got %+v", i, want[i], rules[i])
+		}
+	}
+
+	for _, bad := range []string{"positions=5/s", "*=5", "*=0/s", "*=5/w", "*=5/s:x", "*"} {
+		if _, err := ParseRules(bad); !errors.Is(err, ErrInvalidRule) {
+			t.Errorf("%q: expected ErrInvalidRule, got %v", bad, err)
+		}
+	}
+}
+
+func TestMemoryStoreRefills(t *testing.T) {
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.Now = func() time.Time { return now }
+	rules, _ := ParseRules("*=2/s:3")
+	l := NewLimiter(rules, store)
+
+	for i := 0; i < 3; i++ {
+		d, err := l.Allow(context.Background(), "key:1", "/blotter")
+		if err != nil || !d.Allowed {
+			t.Fatalf("request %d within burst denied: %+v, %v", i+1, d, err)
+		}
+		if d.Limit != 3 || d.Remaining != 2-i {
+			t.Errorf("request %d: expected limit 3, remaining %d, got %+v", i+1, 2-i, d)
+		}
+	}
+	d, _ := l.Allow(context.Background(), "key:1", "/blotter")
+	if d.Allowed || d.RetryAfter != 500*time.Millisecond {
+		t.Errorf("expected denial with a 500ms retry, got %+v", d)
+	}
+	if d, _ := l.Allow(context.Background(), "key:2", "/blotter"); !d.Allowed {
+		t.Errorf("callers must not share buckets")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); !d.Allowed {
+		t.Errorf("expected a token after refilling, got %+v", d)
+	}
+	now = now.Add(time.Hour)
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); d.Remaining != 2 {
+		t.Errorf("refill must stop at the burst, got %+v", d)
+	}
+}
+
+func TestDeniedRequestsKeepOtherQuotas(t *testing.T) {
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.Now = func() time.Time { return now }
+	rules, _ := ParseRules("*=1/m, *=3/d")
+	l := NewLimiter(rules, store)
+
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); !d.Allowed {
+		t.Fatalf("first request denied: %+v", d)
+	}
+	for i := 0; i < 5; i++ {
+		if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); d.Allowed {
+			t.Fatalf("request within the minute allowed: %+v", d)
+		} else if d.Remaining != 0 || d.RetryAfter <= 0 {
+			t.Errorf("expected the per-minute rule to report the denial, got %+v", d)
+		}
+	}
+
+	// Only the allowed requests count against the daily quota
+	for i := 0; i < 2; i++ {
+		now = now.Add(time.Minute)
+		d, _ := l.Allow(context.Background(), "key:1", "/blotter")
+		if !d.Allowed {
+			t.Fatalf("request %d after refilling denied: %+v", i+2, d)
+		}
+	}
+	now = now.Add(time.Minute)
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); d.Allowed || d.Limit != 3 {
+		t.Errorf("expected the daily rule to deny the fourth request, got %+v", d)
+	}
+}
+
+func TestApplicableRules(t *testing.T) {
+	rules, _ := ParseRules("*=20/s, /positions=5/s, *=1000/d, key:12@*=200/s, key:12@/positions=50/s")
+	l := NewLimiter(rules, NewMemoryStore())
+
+	got := l.applicable("key:7", "/positions/history")
+	if len(got) != 3 || got[0].Count != 20 || got[1].Count != 5 || got[2].Count != 1000 {
+		t.Errorf("expected the general per-second, route and daily rules, got %+v", got)
+	}
+	if got := l.applicable("key:7", "/positionsx"); len(got) != 2 {
+		t.Errorf("route rules match whole path segments, got %+v", got)
+	}
+	got = l.applicable("key:12", "/positions")
+	if len(got) != 3 || got[0].Count != 200 || got[1].Count != 50 || got[2].Count != 1000 {
+		t.Errorf("expected key:12's overrides and the shared daily quota, got %+v", got)
+	}
+}
+
+func TestAllowReportsTightestRule(t *testing.T) {
+	store := NewMemoryStore()
+	now := time.Date(2025, 1, 15, 9, 0, 0, 0, time.UTC)
+	store.Now = func() time.Time { return now }
+	rules, _ := ParseRules("*=100/s, /positions=1/m:2")
+	l := NewLimiter(rules, store)
+	d, _ := l.Allow(context.Background(), "key:1", "/positions")
+	if !d.Allowed || d.Limit != 2 || d.Remaining != 1 {
+		t.Errorf("expected the /positions bucket in the headers, got %+v", d)
+	}
+	l.Allow(context.Background(), "key:1", "/positions")
+	d, _ = l.Allow(context.Background(), "key:1", "/positions")
+	if d.Allowed || d.RetryAfter != time.Minute {
+		t.Errorf("expected a one minute retry, got %+v", d)
+	}
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); !d.Allowed {
+		t.Errorf("other routes must be unaffected, got %+v", d)
+	}
+}
+
+func TestSetRules(t *testing.T) {
+	rules, _ := ParseRules("*=1/m")
+	l := NewLimiter(rules, NewMemoryStore())
+	l.Allow(context.Background(), "key:1", "/blotter")
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); d.Allowed {
+		t.Fatalf("expected the old rule to deny, got %+v", d)
+	}
+	rules, _ = ParseRules("*=5/m")
+	l.SetRules(rules)
+	if d, _ := l.Allow(context.Background(), "key:1", "/blotter"); !d.Allowed || d.Limit != 5 {
+		t.Errorf("expected the new rule to apply, got %+v", d)
+	}
+}
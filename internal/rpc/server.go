@@ -0,0 +1,352 @@
+// Package rpc serves the read API over gRPC for internal consumers. Calls go
+// through the same query layer as the REST and GraphQL endpoints.
+package rpc
+
+//go:generate protoc -I ../../proto --go_out=vestv1 --go_opt=paths=source_relative --go-grpc_out=vestv1 --go-grpc_opt=paths=source_relative vest/v1/vest.proto
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/api"
+	"github.com/AndrewCharlesHay/vest/internal/audit"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+	"github.com/AndrewCharlesHay/vest/internal/rpc/vestv1"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+var logger = logging.Component("grpc")
+
+// Source is the query layer the service reads from; *api.Handler implements it
+type Source interface {
+	QueryBlotter(ctx context.Context, f api.Filter) ([]models.BlotterResponse, error)
+	EachBlotterRow(ctx context.Context, f api.Filter, fn func(models.BlotterResponse) error) error
+	QueryPositions(ctx context.Context, o api.PositionOptions) ([]models.PositionResponse, error)
+	QueryAlarms(ctx context.Context, o api.AlarmOptions) ([]models.AlarmResponse, error)
+}
+
+// Server implements vestv1.VestServiceServer
+type Server struct {
+	vestv1.UnimplementedVestServiceServer
+	src Source
+}
+
+// NewServer returns a gRPC server with VestService registered behind API key
+// or bearer token auth. Calls count against limits, if set, under their full
+// method name, so only "*" rules apply to them. Each call is recorded in
+// auditLog, if set, and traced, continuing the caller's trace.
+func NewServer(src Source, authn *auth.Authenticator, limits *ratelimit.Limiter, auditLog *audit.Logger) *grpc.Server {
+	a := authenticator{authn: authn, limits: limits, audit: auditLog}
+	s := grpc.NewServer(
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
+		grpc.UnaryInterceptor(a.unary),
+		grpc.StreamInterceptor(a.stream),
+	)
+	vestv1.RegisterVestServiceServer(s, &Server{src: src})
+	return s
+}
+
+func (s *Server) GetBlotter(ctx context.Context, req *vestv1.BlotterRequest) (*vestv1.BlotterResponse, error) {
+	if req.GetDate() == "" {
+		return nil, status.Error(codes.InvalidArgument, "date is required")
+	}
+	rows, err := s.src.QueryBlotter(ctx, filter(req.GetDate(), req.GetAccountId(), req.GetGroup()))
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	resp := &vestv1.BlotterResponse{Rows: make([]*vestv1.BlotterRow, len(rows))}
+	for i, b := range rows {
+		resp.Rows[i] = blotterRow(b)
+	}
+	return resp, nil
+}
+
+// StreamBlotter sends each row as it is scanned rather than buffering the date
+func (s *Server) StreamBlotter(req *vestv1.BlotterRequest, stream grpc.ServerStreamingServer[vestv1.BlotterRow]) error {
+	if req.GetDate() == "" {
+		return status.Error(codes.InvalidArgument, "date is required")
+	}
+	err := s.src.EachBlotterRow(stream.Context(), filter(req.GetDate(), req.GetAccountId(), req.GetGroup()), func(b models.BlotterResponse) error {
+		return stream.Send(blotterRow(b))
+	})
+	if err != nil {
+		return statusError(stream.Context(), err)
+	}
+	return nil
+}
+
+func (s *Server) GetPositions(ctx context.Context, req *vestv1.PositionsRequest) (*vestv1.PositionsResponse, error) {
+	if req.GetDate() == "" {
+		return nil, status.Error(codes.InvalidArgument, "date is required")
+	}
+	positions, err := s.src.QueryPositions(ctx, api.PositionOptions{
+		Filter:   filter(req.GetDate(), req.GetAccountId(), req.GetGroup()),
+		Exposure: exposure(req.GetExposure()),
+		GroupBy:  req.GetGroupBy(),
+	})
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	resp := &vestv1.PositionsResponse{Accounts: make([]*vestv1.AccountPositions, len(positions))}
+	for i, p := range positions {
+		resp.Accounts[i] = &vestv1.AccountPositions{
+			AccountId:   p.AccountID,
+			GroupId:     p.GroupID,
+			Exposure:    req.GetExposure(),
+			GroupBy:     p.GroupBy,
+			Total:       p.Total,
+			Warning:     p.Warning,
+			Allocations: allocations(p.Allocations),
+		}
+	}
+	return resp, nil
+}
+
+func (s *Server) GetAlarms(ctx context.Context, req *vestv1.AlarmsRequest) (*vestv1.AlarmsResponse, error) {
+	if req.GetDate() == "" {
+		return nil, status.Error(codes.InvalidArgument, "date is required")
+	}
+	alarms, err := s.src.QueryAlarms(ctx, api.AlarmOptions{
+		Filter:     filter(req.GetDate(), req.GetAccountId(), req.GetGroup()),
+		Exposure:   exposure(req.GetExposure()),
+		IncludeAll: req.GetIncludeAll(),
+	})
+	if err != nil {
+		return nil, statusError(ctx, err)
+	}
+	resp := &vestv1.AlarmsResponse{Alarms: make([]*vestv1.Alarm, len(alarms))}
+	for i, a := range alarms {
+		alarm := &vestv1.Alarm{
+			Date:          a.Date,
+			AccountId:     a.AccountID,
+			GroupId:       a.GroupID,
+			Exposure:      req.GetExposure(),
+			HasViolation:  a.HasViolation,
+			ViolationInfo: a.ViolationInfo,
+			Warning:       a.Warning,
+		}
+		for _, v := range a.Violations {
+			alarm.Violations = append(alarm.Violations, &vestv1.Violation{
+				RuleId:        v.RuleID,
+				Ticker:        v.Ticker,
+				ObservedValue: v.ObservedValue,
+				Threshold:     v.Threshold,
+				Severity:      v.Severity,
+				MarketValue:   v.MarketValue,
+				AccountTotal:  v.AccountTotal,
+				Message:       v.Message,
+			})
+		}
+		resp.Alarms[i] = alarm
+	}
+	return resp, nil
+}
+
+func filter(date, accountID, group string) api.Filter {
+	return api.Filter{Date: date, AccountID: accountID, Group: group}
+}
+
+func blotterRow(b models.BlotterResponse) *vestv1.BlotterRow {
+	return &vestv1.BlotterRow{
+		Date:        b.Date,
+		AccountId:   b.AccountID,
+		Ticker:      b.Ticker,
+		Quantity:    b.Quantity,
+		MarketValue: b.MarketValue,
+	}
+}
+
+// exposure maps the enum onto the compliance mode; unspecified means net
+func exposure(e vestv1.Exposure) compliance.Exposure {
+	switch e {
+	case vestv1.Exposure_EXPOSURE_GROSS:
+		return compliance.ExposureGross
+	case vestv1.Exposure_EXPOSURE_LONG:
+		return compliance.ExposureLong
+	case vestv1.Exposure_EXPOSURE_SHORT:
+		return compliance.ExposureShort
+	default:
+		return compliance.ExposureNet
+	}
+}
+
+// allocations orders the map largest first so responses are deterministic
+func allocations(m map[string]float64) []*vestv1.Allocation {
+	out := make([]*vestv1.Allocation, 0, len(m))
+	for key, pct := range m {
+		out = append(out, &vestv1.Allocation{Key: key, Percent: pct})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Percent != out[j].Percent {
+			return out[i].Percent > out[j].Percent
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out
+}
+
+// statusError maps a query layer error to a gRPC status
+func statusError(ctx context.Context, err error) error {
+	switch {
+	case errors.Is(err, api.ErrInvalidArgument):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, api.ErrNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	default:
+		logger.ErrorContext(ctx, "gRPC query failed", "error", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
+
+// authenticator applies the same checks as the HTTP middleware, reading the
+// x-api-key and authorization metadata headers. Every call is a read.
+type authenticator struct {
+	authn  *auth.Authenticator
+	limits *ratelimit.Limiter
+	audit  *audit.Logger
+}
+
+func (a authenticator) authorize(ctx context.Context, method string) (context.Context, error) {
+	var key, authorization string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-api-key"); len(v) > 0 {
+			key = v[0]
+		}
+		if v := md.Get("authorization"); len(v) > 0 {
+			authorization = v[0]
+		}
+	}
+	principal, err := a.authn.Authenticate(ctx, key, authorization)
+	if errors.Is(err, auth.ErrUnauthenticated) {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+	if err != nil {
+		logger.ErrorContext(ctx, "API key lookup failed", "error", err)
+		return nil, status.Error(codes.Unavailable, "authentication unavailable")
+	}
+	if !principal.Can(auth.ScopeRead) {
+		return nil, status.Error(codes.PermissionDenied, "requires read scope")
+	}
+	if a.limits != nil {
+		d, err := a.limits.Allow(ctx, principal.Label(), method)
+		if err != nil {
+			logger.ErrorContext(ctx, "Rate limit check failed", "error", err)
+		} else if !d.Allowed {
+			return nil, status.Errorf(codes.ResourceExhausted, "rate limit exceeded, retry in %s", d.RetryAfter.Round(time.Millisecond))
+		}
+	}
+	return auth.WithPrincipal(ctx, principal), nil
+}
+
+func (a authenticator) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	ctx = withRequestID(ctx)
+	if err := grpc.SetHeader(ctx, metadata.Pairs("x-request-id", logging.RequestID(ctx))); err != nil {
+		logger.DebugContext(ctx, "Failed to send request id", "error", err)
+	}
+	authed, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		logCall(ctx, info.FullMethod, start, err)
+		return nil, err
+	}
+	resp, err := handler(authed, req)
+	a.record(authed, info.FullMethod, req, err)
+	logCall(authed, info.FullMethod, start, err)
+	return resp, err
+}
+
+func (a authenticator) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	ctx := withRequestID(ss.Context())
+	if err := ss.SetHeader(metadata.Pairs("x-request-id", logging.RequestID(ctx))); err != nil {
+		logger.DebugContext(ctx, "Failed to send request id", "error", err)
+	}
+	authed, err := a.authorize(ctx, info.FullMethod)
+	if err != nil {
+		logCall(ctx, info.FullMethod, start, err)
+		return err
+	}
+	ps := &principalStream{ServerStream: ss, ctx: authed}
+	err = handler(srv, ps)
+	a.record(ps.ctx, info.FullMethod, ps.req, err)
+	logCall(ps.ctx, info.FullMethod, start, err)
+	return err
+}
+
+// withRequestID takes the caller's x-request-id metadata, or generates one,
+// and starts collecting the accounts the call returns
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get("x-request-id"); len(v) > 0 {
+			id = v[0]
+		}
+	}
+	return audit.WithNotes(logging.WithRequestID(ctx, logging.AcceptRequestID(id)))
+}
+
+// logCall writes the access log line for a finished call
+func logCall(ctx context.Context, method string, start time.Time, err error) {
+	level := slog.LevelInfo
+	if code := status.Code(err); code == codes.Internal || code == codes.Unavailable {
+		level = slog.LevelWarn
+	}
+	logger.Log(ctx, level, "Call completed",
+		"method", method,
+		"code", status.Code(err).String(),
+		"duration_ms", time.Since(start).Milliseconds(),
+		"accounts", len(audit.Noted(ctx)),
+	)
+}
+
+// record adds an access entry for a completed call, with the request as its
+// parameters and the gRPC status code as its status
+func (a authenticator) record(ctx context.Context, method string, req any, err error) {
+	if a.audit == nil {
+		return
+	}
+	var params []byte
+	if m, ok := req.(proto.Message); ok {
+		params, _ = protojson.Marshal(m)
+	}
+	a.audit.Record(models.AuditEntry{
+		Kind:      audit.KindAccess,
+		Principal: auth.PrincipalFrom(ctx).Label(),
+		Method:    "GRPC",
+		Route:     method,
+		Params:    params,
+		Accounts:  audit.Noted(ctx),
+		Status:    int(status.Code(err)),
+	})
+}
+
+// principalStream carries the authenticated context into stream handlers and
+// keeps the first request message for the audit log
+type principalStream struct {
+	grpc.ServerStream
+	ctx context.Context
+	req any
+}
+
+func (s *principalStream) Context() context.Context { return s.ctx }
+
+func (s *principalStream) RecvMsg(m any) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil && s.req == nil {
+		s.req = m
+	}
+	return err
+}
@@ -0,0 +1,226 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/api"
+	"github.com/AndrewCharlesHay/vest/internal/auth"
+	"github.com/AndrewCharlesHay/vest/internal/compliance"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/ratelimit"
+	"github.com/AndrewCharlesHay/vest/internal/rpc/vestv1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// fakeSource serves the sample report data without a database
+type fakeSource struct{}
+
+var sampleRows = []models.BlotterResponse{
+	{Date: "2025-01-15", AccountID: "ACC001", Ticker: "AAPL", Quantity: 100, MarketValue: 18550},
+	{Date: "2025-01-15", AccountID: "ACC001", Ticker: "MSFT", Quantity: 50, MarketValue: 21012.50},
+	{Date: "2025-01-15", AccountID: "ACC002", Ticker: "AAPL", Quantity: 200, MarketValue: 37100},
+}
+
+// errNoPrincipal shows the caller was not passed through to the query layer,
+// which would leave it unable to apply entitlements
+var errNoPrincipal = errors.New("no principal in context")
+
+func (fakeSource) QueryBlotter(ctx context.Context, f api.Filter) ([]models.BlotterResponse, error) {
+	if auth.PrincipalFrom(ctx) == nil {
+		return nil, errNoPrincipal
+	}
+	return sampleRows, nil
+}
+
+func (fakeSource) EachBlotterRow(ctx context.Context, f api.Filter, fn func(models.BlotterResponse) error) error {
+	if auth.PrincipalFrom(ctx) == nil {
+		return errNoPrincipal
+	}
+	if f.Group == "missing" {
+		return fmt.Errorf("%w: group %q", api.ErrNotFound, f.Group)
+	}
+	for _, b := range sampleRows {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (fakeSource) QueryPositions(ctx context.Context, o api.PositionOptions) ([]models.PositionResponse, error) {
+	if o.GroupBy == "colour" {
+		return nil, fmt.Errorf("%w: unsupported group_by %q", api.ErrInvalidArgument, o.GroupBy)
+	}
+	return []models.PositionResponse{{
+		AccountID:   "ACC001",
+		Exposure:    string(o.Exposure),
+		Total:       39562.50,
+		Allocations: map[string]float64{"AAPL": 46.89, "MSFT": 53.11},
+	}}, nil
+}
+
+func (fakeSource) QueryAlarms(ctx context.Context, o api.AlarmOptions) ([]models.AlarmResponse, error) {
+	holdings := []compliance.Holding{
+		{AccountID: "ACC001", Ticker: "AAPL", MarketValue: 18550},
+		{AccountID: "ACC001", Ticker: "MSFT", MarketValue: 21012.50},
+	}
+	return compliance.Evaluate(o.Date, holdings, compliance.Options{Exposure: o.Exposure, IncludeAll: o.IncludeAll}), nil
+}
+
+func dial(t *testing.T) vestv1.VestServiceClient {
+	t.Helper()
+	return dialLimited(t, nil)
+}
+
+func dialLimited(t *testing.T, limits *ratelimit.Limiter) vestv1.VestServiceClient {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := NewServer(fakeSource{}, &auth.Authenticator{Keys: &auth.KeyStore{Bootstrap: "test-secret"}}, limits, nil)
+	go func() {
+		if err := srv.Serve(lis); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+			t.Errorf("serve: %v", err)
+		}
+	}()
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return vestv1.NewVestServiceClient(conn)
+}
+
+func withKey(key string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+}
+
+func TestAuth(t *testing.T) {
+	client := dial(t)
+	req := &vestv1.BlotterRequest{Date: "2025-01-15"}
+
+	for name, ctx := range map[string]context.Context{
+		"missing": context.Background(),
+		"wrong":   withKey("wrong-secret"),
+	} {
+		if _, err := client.GetBlotter(ctx, req); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s key unary: expected Unauthenticated, got %v", name, err)
+		}
+		stream, err := client.StreamBlotter(ctx, req)
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		if status.Code(err) != codes.Unauthenticated {
+			t.Errorf("%s key stream: expected Unauthenticated, got %v", name, err)
+		}
+	}
+
+	if _, err := client.GetBlotter(withKey("test-secret"), req); err != nil {
+		t.Errorf("valid key: %v", err)
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	rules, err := ratelimit.ParseRules("*=1/m:2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := dialLimited(t, ratelimit.NewLimiter(rules, ratelimit.NewMemoryStore()))
+	req := &vestv1.BlotterRequest{Date: "2025-01-15"}
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetBlotter(withKey("test-secret"), req); err != nil {
+			t.Fatalf("call %d within burst: %v", i+1, err)
+		}
+	}
+	if _, err := client.GetBlotter(withKey("test-secret"), req); status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("expected ResourceExhausted once the burst is spent, got %v", err)
+	}
+}
+
+func TestStreamBlotter(t *testing.T) {
+	client := dial(t)
+	stream, err := client.StreamBlotter(withKey("test-secret"), &vestv1.BlotterRequest{Date: "2025-01-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []*vestv1.BlotterRow
+	for {
+		row, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, row)
+	}
+	if len(got) != len(sampleRows) {
+		t.Fatalf("expected %d rows, got %d", len(sampleRows), len(got))
+	}
+	if got[1].GetTicker() != "MSFT" || got[1].GetMarketValue() != 21012.50 {
+		t.Errorf("unexpected row: %v", got[1])
+	}
+
+	stream, err = client.StreamBlotter(withKey("test-secret"), &vestv1.BlotterRequest{Date: "2025-01-15", Group: "missing"})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("expected NotFound for unknown group, got %v", err)
+	}
+}
+
+func TestGetPositions(t *testing.T) {
+	client := dial(t)
+	resp, err := client.GetPositions(withKey("test-secret"), &vestv1.PositionsRequest{Date: "2025-01-15", Exposure: vestv1.Exposure_EXPOSURE_GROSS})
+	if err != nil {
+		t.Fatal(err)
+	}
+	acc := resp.GetAccounts()[0]
+	if acc.GetExposure() != vestv1.Exposure_EXPOSURE_GROSS {
+		t.Errorf("expected GROSS exposure, got %v", acc.GetExposure())
+	}
+	if allocs := acc.GetAllocations(); allocs[0].GetKey() != "MSFT" || allocs[1].GetKey() != "AAPL" {
+		t.Errorf("expected allocations largest first, got %v", allocs)
+	}
+
+	_, err = client.GetPositions(withKey("test-secret"), &vestv1.PositionsRequest{Date: "2025-01-15", GroupBy: "colour"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument for bad group_by, got %v", err)
+	}
+	_, err = client.GetPositions(withKey("test-secret"), &vestv1.PositionsRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("expected InvalidArgument without date, got %v", err)
+	}
+}
+
+func TestGetAlarms(t *testing.T) {
+	client := dial(t)
+	resp, err := client.GetAlarms(withKey("test-secret"), &vestv1.AlarmsRequest{Date: "2025-01-15"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(resp.GetAlarms()) != 1 {
+		t.Fatalf("expected 1 alarm, got %d", len(resp.GetAlarms()))
+	}
+	a := resp.GetAlarms()[0]
+	if !a.GetHasViolation() || len(a.GetViolations()) != 2 {
+		t.Errorf("expected both holdings in breach, got %v", a)
+	}
+	if v := a.GetViolations()[0]; v.GetTicker() != "MSFT" || v.GetRuleId() != compliance.ConcentrationRuleID {
+		t.Errorf("expected worst breach first, got %v", v)
+	}
+}
@@ -0,0 +1,1005 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.10
+// 	protoc        v5.29.3
+// source: vest/v1/vest.proto
+
+package vestv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Exposure int32
+
+const (
+	Exposure_EXPOSURE_UNSPECIFIED Exposure = 0 // Treated as net
+	Exposure_EXPOSURE_NET         Exposure = 1
+	Exposure_EXPOSURE_GROSS       Exposure = 2
+	Exposure_EXPOSURE_LONG        Exposure = 3
+	Exposure_EXPOSURE_SHORT       Exposure = 4
+)
+
+// Enum value maps for Exposure.
+var (
+	Exposure_name = map[int32]string{
+		0: "EXPOSURE_UNSPECIFIED",
+		1: "EXPOSURE_NET",
+		2: "EXPOSURE_GROSS",
+		3: "EXPOSURE_LONG",
+		4: "EXPOSURE_SHORT",
+	}
+	Exposure_value = map[string]int32{
+		"EXPOSURE_UNSPECIFIED": 0,
+		"EXPOSURE_NET":         1,
+		"EXPOSURE_GROSS":       2,
+		"EXPOSURE_LONG":        3,
+		"EXPOSURE_SHORT":       4,
+	}
+)
+
+func (x Exposure) Enum() *Exposure {
+	p := new(Exposure)
+	*p = x
+	return p
+}
+
+func (x Exposure) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (Exposure) Descriptor() protoreflect.EnumDescriptor {
+	return file_vest_v1_vest_proto_enumTypes[0].Descriptor()
+}
+
+func (Exposure) Type() protoreflect.EnumType {
+	return &file_vest_v1_vest_proto_enumTypes[0]
+}
+
+func (x Exposure) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use Exposure.Descriptor instead.
+func (Exposure) EnumDescriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{0}
+}
+
+type BlotterRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"` // YYYY-MM-DD
+	AccountId     string                 `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Group         string                 `protobuf:"bytes,3,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlotterRequest) Reset() {
+	*x = BlotterRequest{}
+	mi := &file_vest_v1_vest_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlotterRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlotterRequest) ProtoMessage() {}
+
+func (x *BlotterRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlotterRequest.ProtoReflect.Descriptor instead.
+func (*BlotterRequest) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *BlotterRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *BlotterRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *BlotterRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type BlotterRow struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	AccountId     string                 `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Ticker        string                 `protobuf:"bytes,3,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	Quantity      float64                `protobuf:"fixed64,4,opt,name=quantity,proto3" json:"quantity,omitempty"`
+	MarketValue   float64                `protobuf:"fixed64,5,opt,name=market_value,json=marketValue,proto3" json:"market_value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlotterRow) Reset() {
+	*x = BlotterRow{}
+	mi := &file_vest_v1_vest_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlotterRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlotterRow) ProtoMessage() {}
+
+func (x *BlotterRow) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlotterRow.ProtoReflect.Descriptor instead.
+func (*BlotterRow) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *BlotterRow) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *BlotterRow) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *BlotterRow) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *BlotterRow) GetQuantity() float64 {
+	if x != nil {
+		return x.Quantity
+	}
+	return 0
+}
+
+func (x *BlotterRow) GetMarketValue() float64 {
+	if x != nil {
+		return x.MarketValue
+	}
+	return 0
+}
+
+type BlotterResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Rows          []*BlotterRow          `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BlotterResponse) Reset() {
+	*x = BlotterResponse{}
+	mi := &file_vest_v1_vest_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BlotterResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BlotterResponse) ProtoMessage() {}
+
+func (x *BlotterResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BlotterResponse.ProtoReflect.Descriptor instead.
+func (*BlotterResponse) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *BlotterResponse) GetRows() []*BlotterRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+type PositionsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Exposure      Exposure               `protobuf:"varint,2,opt,name=exposure,proto3,enum=vest.v1.Exposure" json:"exposure,omitempty"`
+	GroupBy       string                 `protobuf:"bytes,3,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"` // Security master attribute, e.g. sector
+	AccountId     string                 `protobuf:"bytes,4,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Group         string                 `protobuf:"bytes,5,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PositionsRequest) Reset() {
+	*x = PositionsRequest{}
+	mi := &file_vest_v1_vest_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PositionsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PositionsRequest) ProtoMessage() {}
+
+func (x *PositionsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PositionsRequest.ProtoReflect.Descriptor instead.
+func (*PositionsRequest) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *PositionsRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *PositionsRequest) GetExposure() Exposure {
+	if x != nil {
+		return x.Exposure
+	}
+	return Exposure_EXPOSURE_UNSPECIFIED
+}
+
+func (x *PositionsRequest) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+func (x *PositionsRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *PositionsRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type Allocation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Key           string                 `protobuf:"bytes,1,opt,name=key,proto3" json:"key,omitempty"` // Ticker, or group_by value
+	Percent       float64                `protobuf:"fixed64,2,opt,name=percent,proto3" json:"percent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Allocation) Reset() {
+	*x = Allocation{}
+	mi := &file_vest_v1_vest_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Allocation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Allocation) ProtoMessage() {}
+
+func (x *Allocation) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Allocation.ProtoReflect.Descriptor instead.
+func (*Allocation) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Allocation) GetKey() string {
+	if x != nil {
+		return x.Key
+	}
+	return ""
+}
+
+func (x *Allocation) GetPercent() float64 {
+	if x != nil {
+		return x.Percent
+	}
+	return 0
+}
+
+type AccountPositions struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AccountId     string                 `protobuf:"bytes,1,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Exposure      Exposure               `protobuf:"varint,3,opt,name=exposure,proto3,enum=vest.v1.Exposure" json:"exposure,omitempty"`
+	GroupBy       string                 `protobuf:"bytes,4,opt,name=group_by,json=groupBy,proto3" json:"group_by,omitempty"`
+	Total         float64                `protobuf:"fixed64,5,opt,name=total,proto3" json:"total,omitempty"`
+	Warning       string                 `protobuf:"bytes,6,opt,name=warning,proto3" json:"warning,omitempty"`
+	Allocations   []*Allocation          `protobuf:"bytes,7,rep,name=allocations,proto3" json:"allocations,omitempty"` // Largest first
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AccountPositions) Reset() {
+	*x = AccountPositions{}
+	mi := &file_vest_v1_vest_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AccountPositions) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AccountPositions) ProtoMessage() {}
+
+func (x *AccountPositions) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AccountPositions.ProtoReflect.Descriptor instead.
+func (*AccountPositions) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *AccountPositions) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *AccountPositions) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *AccountPositions) GetExposure() Exposure {
+	if x != nil {
+		return x.Exposure
+	}
+	return Exposure_EXPOSURE_UNSPECIFIED
+}
+
+func (x *AccountPositions) GetGroupBy() string {
+	if x != nil {
+		return x.GroupBy
+	}
+	return ""
+}
+
+func (x *AccountPositions) GetTotal() float64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *AccountPositions) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+func (x *AccountPositions) GetAllocations() []*Allocation {
+	if x != nil {
+		return x.Allocations
+	}
+	return nil
+}
+
+type PositionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Accounts      []*AccountPositions    `protobuf:"bytes,1,rep,name=accounts,proto3" json:"accounts,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PositionsResponse) Reset() {
+	*x = PositionsResponse{}
+	mi := &file_vest_v1_vest_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PositionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PositionsResponse) ProtoMessage() {}
+
+func (x *PositionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PositionsResponse.ProtoReflect.Descriptor instead.
+func (*PositionsResponse) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *PositionsResponse) GetAccounts() []*AccountPositions {
+	if x != nil {
+		return x.Accounts
+	}
+	return nil
+}
+
+type AlarmsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	Exposure      Exposure               `protobuf:"varint,2,opt,name=exposure,proto3,enum=vest.v1.Exposure" json:"exposure,omitempty"`
+	IncludeAll    bool                   `protobuf:"varint,3,opt,name=include_all,json=includeAll,proto3" json:"include_all,omitempty"`
+	AccountId     string                 `protobuf:"bytes,4,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	Group         string                 `protobuf:"bytes,5,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlarmsRequest) Reset() {
+	*x = AlarmsRequest{}
+	mi := &file_vest_v1_vest_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlarmsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlarmsRequest) ProtoMessage() {}
+
+func (x *AlarmsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlarmsRequest.ProtoReflect.Descriptor instead.
+func (*AlarmsRequest) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *AlarmsRequest) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *AlarmsRequest) GetExposure() Exposure {
+	if x != nil {
+		return x.Exposure
+	}
+	return Exposure_EXPOSURE_UNSPECIFIED
+}
+
+func (x *AlarmsRequest) GetIncludeAll() bool {
+	if x != nil {
+		return x.IncludeAll
+	}
+	return false
+}
+
+func (x *AlarmsRequest) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *AlarmsRequest) GetGroup() string {
+	if x != nil {
+		return x.Group
+	}
+	return ""
+}
+
+type Violation struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RuleId        string                 `protobuf:"bytes,1,opt,name=rule_id,json=ruleId,proto3" json:"rule_id,omitempty"`
+	Ticker        string                 `protobuf:"bytes,2,opt,name=ticker,proto3" json:"ticker,omitempty"`
+	ObservedValue float64                `protobuf:"fixed64,3,opt,name=observed_value,json=observedValue,proto3" json:"observed_value,omitempty"`
+	Threshold     float64                `protobuf:"fixed64,4,opt,name=threshold,proto3" json:"threshold,omitempty"`
+	Severity      string                 `protobuf:"bytes,5,opt,name=severity,proto3" json:"severity,omitempty"`
+	MarketValue   float64                `protobuf:"fixed64,6,opt,name=market_value,json=marketValue,proto3" json:"market_value,omitempty"`
+	AccountTotal  float64                `protobuf:"fixed64,7,opt,name=account_total,json=accountTotal,proto3" json:"account_total,omitempty"`
+	Message       string                 `protobuf:"bytes,8,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Violation) Reset() {
+	*x = Violation{}
+	mi := &file_vest_v1_vest_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Violation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Violation) ProtoMessage() {}
+
+func (x *Violation) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Violation.ProtoReflect.Descriptor instead.
+func (*Violation) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *Violation) GetRuleId() string {
+	if x != nil {
+		return x.RuleId
+	}
+	return ""
+}
+
+func (x *Violation) GetTicker() string {
+	if x != nil {
+		return x.Ticker
+	}
+	return ""
+}
+
+func (x *Violation) GetObservedValue() float64 {
+	if x != nil {
+		return x.ObservedValue
+	}
+	return 0
+}
+
+func (x *Violation) GetThreshold() float64 {
+	if x != nil {
+		return x.Threshold
+	}
+	return 0
+}
+
+func (x *Violation) GetSeverity() string {
+	if x != nil {
+		return x.Severity
+	}
+	return ""
+}
+
+func (x *Violation) GetMarketValue() float64 {
+	if x != nil {
+		return x.MarketValue
+	}
+	return 0
+}
+
+func (x *Violation) GetAccountTotal() float64 {
+	if x != nil {
+		return x.AccountTotal
+	}
+	return 0
+}
+
+func (x *Violation) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type Alarm struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Date          string                 `protobuf:"bytes,1,opt,name=date,proto3" json:"date,omitempty"`
+	AccountId     string                 `protobuf:"bytes,2,opt,name=account_id,json=accountId,proto3" json:"account_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,3,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Exposure      Exposure               `protobuf:"varint,4,opt,name=exposure,proto3,enum=vest.v1.Exposure" json:"exposure,omitempty"`
+	HasViolation  bool                   `protobuf:"varint,5,opt,name=has_violation,json=hasViolation,proto3" json:"has_violation,omitempty"`
+	ViolationInfo string                 `protobuf:"bytes,6,opt,name=violation_info,json=violationInfo,proto3" json:"violation_info,omitempty"`
+	Warning       string                 `protobuf:"bytes,7,opt,name=warning,proto3" json:"warning,omitempty"`
+	Violations    []*Violation           `protobuf:"bytes,8,rep,name=violations,proto3" json:"violations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Alarm) Reset() {
+	*x = Alarm{}
+	mi := &file_vest_v1_vest_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Alarm) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Alarm) ProtoMessage() {}
+
+func (x *Alarm) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Alarm.ProtoReflect.Descriptor instead.
+func (*Alarm) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Alarm) GetDate() string {
+	if x != nil {
+		return x.Date
+	}
+	return ""
+}
+
+func (x *Alarm) GetAccountId() string {
+	if x != nil {
+		return x.AccountId
+	}
+	return ""
+}
+
+func (x *Alarm) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *Alarm) GetExposure() Exposure {
+	if x != nil {
+		return x.Exposure
+	}
+	return Exposure_EXPOSURE_UNSPECIFIED
+}
+
+func (x *Alarm) GetHasViolation() bool {
+	if x != nil {
+		return x.HasViolation
+	}
+	return false
+}
+
+func (x *Alarm) GetViolationInfo() string {
+	if x != nil {
+		return x.ViolationInfo
+	}
+	return ""
+}
+
+func (x *Alarm) GetWarning() string {
+	if x != nil {
+		return x.Warning
+	}
+	return ""
+}
+
+func (x *Alarm) GetViolations() []*Violation {
+	if x != nil {
+		return x.Violations
+	}
+	return nil
+}
+
+type AlarmsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Alarms        []*Alarm               `protobuf:"bytes,1,rep,name=alarms,proto3" json:"alarms,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AlarmsResponse) Reset() {
+	*x = AlarmsResponse{}
+	mi := &file_vest_v1_vest_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AlarmsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AlarmsResponse) ProtoMessage() {}
+
+func (x *AlarmsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_vest_v1_vest_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AlarmsResponse.ProtoReflect.Descriptor instead.
+func (*AlarmsResponse) Descriptor() ([]byte, []int) {
+	return file_vest_v1_vest_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *AlarmsResponse) GetAlarms() []*Alarm {
+	if x != nil {
+		return x.Alarms
+	}
+	return nil
+}
+
+var File_vest_v1_vest_proto protoreflect.FileDescriptor
+
+const file_vest_v1_vest_proto_rawDesc = "" +
+	"\n" +
+	"\x12vest/v1/vest.proto\x12\avest.v1\"Y\n" +
+	"\x0eBlotterRequest\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x02 \x01(\tR\taccountId\x12\x14\n" +
+	"\x05group\x18\x03 \x01(\tR\x05group\"\x96\x01\n" +
+	"\n" +
+	"BlotterRow\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x02 \x01(\tR\taccountId\x12\x16\n" +
+	"\x06ticker\x18\x03 \x01(\tR\x06ticker\x12\x1a\n" +
+	"\bquantity\x18\x04 \x01(\x01R\bquantity\x12!\n" +
+	"\fmarket_value\x18\x05 \x01(\x01R\vmarketValue\":\n" +
+	"\x0fBlotterResponse\x12'\n" +
+	"\x04rows\x18\x01 \x03(\v2\x13.vest.v1.BlotterRowR\x04rows\"\xa5\x01\n" +
+	"\x10PositionsRequest\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12-\n" +
+	"\bexposure\x18\x02 \x01(\x0e2\x11.vest.v1.ExposureR\bexposure\x12\x19\n" +
+	"\bgroup_by\x18\x03 \x01(\tR\agroupBy\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x04 \x01(\tR\taccountId\x12\x14\n" +
+	"\x05group\x18\x05 \x01(\tR\x05group\"8\n" +
+	"\n" +
+	"Allocation\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x18\n" +
+	"\apercent\x18\x02 \x01(\x01R\apercent\"\xfd\x01\n" +
+	"\x10AccountPositions\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x01 \x01(\tR\taccountId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12-\n" +
+	"\bexposure\x18\x03 \x01(\x0e2\x11.vest.v1.ExposureR\bexposure\x12\x19\n" +
+	"\bgroup_by\x18\x04 \x01(\tR\agroupBy\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\x01R\x05total\x12\x18\n" +
+	"\awarning\x18\x06 \x01(\tR\awarning\x125\n" +
+	"\vallocations\x18\a \x03(\v2\x13.vest.v1.AllocationR\vallocations\"J\n" +
+	"\x11PositionsResponse\x125\n" +
+	"\baccounts\x18\x01 \x03(\v2\x19.vest.v1.AccountPositionsR\baccounts\"\xa8\x01\n" +
+	"\rAlarmsRequest\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12-\n" +
+	"\bexposure\x18\x02 \x01(\x0e2\x11.vest.v1.ExposureR\bexposure\x12\x1f\n" +
+	"\vinclude_all\x18\x03 \x01(\bR\n" +
+	"includeAll\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x04 \x01(\tR\taccountId\x12\x14\n" +
+	"\x05group\x18\x05 \x01(\tR\x05group\"\xff\x01\n" +
+	"\tViolation\x12\x17\n" +
+	"\arule_id\x18\x01 \x01(\tR\x06ruleId\x12\x16\n" +
+	"\x06ticker\x18\x02 \x01(\tR\x06ticker\x12%\n" +
+	"\x0eobserved_value\x18\x03 \x01(\x01R\robservedValue\x12\x1c\n" +
+	"\tthreshold\x18\x04 \x01(\x01R\tthreshold\x12\x1a\n" +
+	"\bseverity\x18\x05 \x01(\tR\bseverity\x12!\n" +
+	"\fmarket_value\x18\x06 \x01(\x01R\vmarketValue\x12#\n" +
+	"\raccount_total\x18\a \x01(\x01R\faccountTotal\x12\x18\n" +
+	"\amessage\x18\b \x01(\tR\amessage\"\x9e\x02\n" +
+	"\x05Alarm\x12\x12\n" +
+	"\x04date\x18\x01 \x01(\tR\x04date\x12\x1d\n" +
+	"\n" +
+	"account_id\x18\x02 \x01(\tR\taccountId\x12\x19\n" +
+	"\bgroup_id\x18\x03 \x01(\tR\agroupId\x12-\n" +
+	"\bexposure\x18\x04 \x01(\x0e2\x11.vest.v1.ExposureR\bexposure\x12#\n" +
+	"\rhas_violation\x18\x05 \x01(\bR\fhasViolation\x12%\n" +
+	"\x0eviolation_info\x18\x06 \x01(\tR\rviolationInfo\x12\x18\n" +
+	"\awarning\x18\a \x01(\tR\awarning\x122\n" +
+	"\n" +
+	"violations\x18\b \x03(\v2\x12.vest.v1.ViolationR\n" +
+	"violations\"8\n" +
+	"\x0eAlarmsResponse\x12&\n" +
+	"\x06alarms\x18\x01 \x03(\v2\x0e.vest.v1.AlarmR\x06alarms*q\n" +
+	"\bExposure\x12\x18\n" +
+	"\x14EXPOSURE_UNSPECIFIED\x10\x00\x12\x10\n" +
+	"\fEXPOSURE_NET\x10\x01\x12\x12\n" +
+	"\x0eEXPOSURE_GROSS\x10\x02\x12\x11\n" +
+	"\rEXPOSURE_LONG\x10\x03\x12\x12\n" +
+	"\x0eEXPOSURE_SHORT\x10\x042\x94\x02\n" +
+	"\vVestService\x12?\n" +
+	"\n" +
+	"GetBlotter\x12\x17.vest.v1.BlotterRequest\x1a\x18.vest.v1.BlotterResponse\x12E\n" +
+	"\fGetPositions\x12\x19.vest.v1.PositionsRequest\x1a\x1a.vest.v1.PositionsResponse\x12<\n" +
+	"\tGetAlarms\x12\x16.vest.v1.AlarmsRequest\x1a\x17.vest.v1.AlarmsResponse\x12?\n" +
+	"\rStreamBlotter\x12\x17.vest.v1.BlotterRequest\x1a\x13.vest.v1.BlotterRow0\x01B=Z;github.com/AndrewCharlesHay/vest/internal/rpc/vestv1;vestv1b\x06proto3"
+
+var (
+	file_vest_v1_vest_proto_rawDescOnce sync.Once
+	file_vest_v1_vest_proto_rawDescData []byte
+)
+
+func file_vest_v1_vest_proto_rawDescGZIP() []byte {
+	file_vest_v1_vest_proto_rawDescOnce.Do(func() {
+		file_vest_v1_vest_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_vest_v1_vest_proto_rawDesc), len(file_vest_v1_vest_proto_rawDesc)))
+	})
+	return file_vest_v1_vest_proto_rawDescData
+}
+
+var file_vest_v1_vest_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_vest_v1_vest_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_vest_v1_vest_proto_goTypes = []any{
+	(Exposure)(0),             // 0: vest.v1.Exposure
+	(*BlotterRequest)(nil),    // 1: vest.v1.BlotterRequest
+	(*BlotterRow)(nil),        // 2: vest.v1.BlotterRow
+	(*BlotterResponse)(nil),   // 3: vest.v1.BlotterResponse
+	(*PositionsRequest)(nil),  // 4: vest.v1.PositionsRequest
+	(*Allocation)(nil),        // 5: vest.v1.Allocation
+	(*AccountPositions)(nil),  // 6: vest.v1.AccountPositions
+	(*PositionsResponse)(nil), // 7: vest.v1.PositionsResponse
+	(*AlarmsRequest)(nil),     // 8: vest.v1.AlarmsRequest
+	(*Violation)(nil),         // 9: vest.v1.Violation
+	(*Alarm)(nil),             // 10: vest.v1.Alarm
+	(*AlarmsResponse)(nil),    // 11: vest.v1.AlarmsResponse
+}
+var file_vest_v1_vest_proto_depIdxs = []int32{
+	2,  // 0: vest.v1.BlotterResponse.rows:type_name -> vest.v1.BlotterRow
+	0,  // 1: vest.v1.PositionsRequest.exposure:type_name -> vest.v1.Exposure
+	0,  // 2: vest.v1.AccountPositions.exposure:type_name -> vest.v1.Exposure
+	5,  // 3: vest.v1.AccountPositions.allocations:type_name -> vest.v1.Allocation
+	6,  // 4: vest.v1.PositionsResponse.accounts:type_name -> vest.v1.AccountPositions
+	0,  // 5: vest.v1.AlarmsRequest.exposure:type_name -> vest.v1.Exposure
+	0,  // 6: vest.v1.Alarm.exposure:type_name -> vest.v1.Exposure
+	9,  // 7: vest.v1.Alarm.violations:type_name -> vest.v1.Violation
+	10, // 8: vest.v1.AlarmsResponse.alarms:type_name -> vest.v1.Alarm
+	1,  // 9: vest.v1.VestService.GetBlotter:input_type -> vest.v1.BlotterRequest
+	4,  // 10: vest.v1.VestService.GetPositions:input_type -> vest.v1.PositionsRequest
+	8,  // 11: vest.v1.VestService.GetAlarms:input_type -> vest.v1.AlarmsRequest
+	1,  // 12: vest.v1.VestService.StreamBlotter:input_type -> vest.v1.BlotterRequest
+	3,  // 13: vest.v1.VestService.GetBlotter:output_type -> vest.v1.BlotterResponse
+	7,  // 14: vest.v1.VestService.GetPositions:output_type -> vest.v1.PositionsResponse
+	11, // 15: vest.v1.VestService.GetAlarms:output_type -> vest.v1.AlarmsResponse
+	2,  // 16: vest.v1.VestService.StreamBlotter:output_type -> vest.v1.BlotterRow
+	13, // [13:17] is the sub-list for method output_type
+	9,  // [9:13] is the sub-list for method input_type
+	9,  // [9:9] is the sub-list for extension type_name
+	9,  // [9:9] is the sub-list for extension extendee
+	0,  // [0:9] is the sub-list for field type_name
+}
+
+func init() { file_vest_v1_vest_proto_init() }
+func file_vest_v1_vest_proto_init() {
+	if File_vest_v1_vest_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_vest_v1_vest_proto_rawDesc), len(file_vest_v1_vest_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_vest_v1_vest_proto_goTypes,
+		DependencyIndexes: file_vest_v1_vest_proto_depIdxs,
+		EnumInfos:         file_vest_v1_vest_proto_enumTypes,
+		MessageInfos:      file_vest_v1_vest_proto_msgTypes,
+	}.Build()
+	File_vest_v1_vest_proto = out.File
+	file_vest_v1_vest_proto_goTypes = nil
+	file_vest_v1_vest_proto_depIdxs = nil
+}
@@ -0,0 +1,247 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.29.3
+// source: vest/v1/vest.proto
+
+package vestv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	VestService_GetBlotter_FullMethodName    = "/vest.v1.VestService/GetBlotter"
+	VestService_GetPositions_FullMethodName  = "/vest.v1.VestService/GetPositions"
+	VestService_GetAlarms_FullMethodName     = "/vest.v1.VestService/GetAlarms"
+	VestService_StreamBlotter_FullMethodName = "/vest.v1.VestService/StreamBlotter"
+)
+
+// VestServiceClient is the client API for VestService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// VestService mirrors the /blotter, /positions and /alarms REST endpoints.
+// Every call requires the API key in the "x-api-key" metadata header.
+type VestServiceClient interface {
+	GetBlotter(ctx context.Context, in *BlotterRequest, opts ...grpc.CallOption) (*BlotterResponse, error)
+	GetPositions(ctx context.Context, in *PositionsRequest, opts ...grpc.CallOption) (*PositionsResponse, error)
+	GetAlarms(ctx context.Context, in *AlarmsRequest, opts ...grpc.CallOption) (*AlarmsResponse, error)
+	// StreamBlotter sends rows as they are read, for dates too large to buffer
+	StreamBlotter(ctx context.Context, in *BlotterRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlotterRow], error)
+}
+
+type vestServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewVestServiceClient(cc grpc.ClientConnInterface) VestServiceClient {
+	return &vestServiceClient{cc}
+}
+
+func (c *vestServiceClient) GetBlotter(ctx context.Context, in *BlotterRequest, opts ...grpc.CallOption) (*BlotterResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BlotterResponse)
+	err := c.cc.Invoke(ctx, VestService_GetBlotter_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vestServiceClient) GetPositions(ctx context.Context, in *PositionsRequest, opts ...grpc.CallOption) (*PositionsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PositionsResponse)
+	err := c.cc.Invoke(ctx, VestService_GetPositions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vestServiceClient) GetAlarms(ctx context.Context, in *AlarmsRequest, opts ...grpc.CallOption) (*AlarmsResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AlarmsResponse)
+	err := c.cc.Invoke(ctx, VestService_GetAlarms_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *vestServiceClient) StreamBlotter(ctx context.Context, in *BlotterRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BlotterRow], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &VestService_ServiceDesc.Streams[0], VestService_StreamBlotter_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[BlotterRequest, BlotterRow]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VestService_StreamBlotterClient = grpc.ServerStreamingClient[BlotterRow]
+
+// VestServiceServer is the server API for VestService service.
+// All implementations must embed UnimplementedVestServiceServer
+// for forward compatibility.
+//
+// VestService mirrors the /blotter, /positions and /alarms REST endpoints.
+// Every call requires the API key in the "x-api-key" metadata header.
+type VestServiceServer interface {
+	GetBlotter(context.Context, *BlotterRequest) (*BlotterResponse, error)
+	GetPositions(context.Context, *PositionsRequest) (*PositionsResponse, error)
+	GetAlarms(context.Context, *AlarmsRequest) (*AlarmsResponse, error)
+	// StreamBlotter sends rows as they are read, for dates too large to buffer
+	StreamBlotter(*BlotterRequest, grpc.ServerStreamingServer[BlotterRow]) error
+	mustEmbedUnimplementedVestServiceServer()
+}
+
+// UnimplementedVestServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedVestServiceServer struct{}
+
+func (UnimplementedVestServiceServer) GetBlotter(context.Context, *BlotterRequest) (*BlotterResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBlotter not implemented")
+}
+func (UnimplementedVestServiceServer) GetPositions(context.Context, *PositionsRequest) (*PositionsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetPositions not implemented")
+}
+func (UnimplementedVestServiceServer) GetAlarms(context.Context, *AlarmsRequest) (*AlarmsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAlarms not implemented")
+}
+func (UnimplementedVestServiceServer) StreamBlotter(*BlotterRequest, grpc.ServerStreamingServer[BlotterRow]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamBlotter not implemented")
+}
+func (UnimplementedVestServiceServer) mustEmbedUnimplementedVestServiceServer() {}
+func (UnimplementedVestServiceServer) testEmbeddedByValue()                     {}
+
+// UnsafeVestServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to VestServiceServer will
+// result in compilation errors.
+type UnsafeVestServiceServer interface {
+	mustEmbedUnimplementedVestServiceServer()
+}
+
+func RegisterVestServiceServer(s grpc.ServiceRegistrar, srv VestServiceServer) {
+	// If the following call pancis, it indicates UnimplementedVestServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&VestService_ServiceDesc, srv)
+}
+
+func _VestService_GetBlotter_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BlotterRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VestServiceServer).GetBlotter(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VestService_GetBlotter_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VestServiceServer).GetBlotter(ctx, req.(*BlotterRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VestService_GetPositions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PositionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VestServiceServer).GetPositions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VestService_GetPositions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VestServiceServer).GetPositions(ctx, req.(*PositionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VestService_GetAlarms_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AlarmsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VestServiceServer).GetAlarms(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: VestService_GetAlarms_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VestServiceServer).GetAlarms(ctx, req.(*AlarmsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _VestService_StreamBlotter_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(BlotterRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(VestServiceServer).StreamBlotter(m, &grpc.GenericServerStream[BlotterRequest, BlotterRow]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type VestService_StreamBlotterServer = grpc.ServerStreamingServer[BlotterRow]
+
+// VestService_ServiceDesc is the grpc.ServiceDesc for VestService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var VestService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "vest.v1.VestService",
+	HandlerType: (*VestServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetBlotter",
+			Handler:    _VestService_GetBlotter_Handler,
+		},
+		{
+			MethodName: "GetPositions",
+			Handler:    _VestService_GetPositions_Handler,
+		},
+		{
+			MethodName: "GetAlarms",
+			Handler:    _VestService_GetAlarms_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamBlotter",
+			Handler:       _VestService_StreamBlotter_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "vest/v1/vest.proto",
+}
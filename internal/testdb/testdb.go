@@ -0,0 +1,74 @@
+// Package testdb gives tests a throwaway Postgres schema migrated to the
+// current version. Tests skip unless VEST_TEST_DATABASE_URL is set.
+package testdb
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/migrate"
+	_ "github.com/jackc/pgx/v5/stdlib" // PG driver
+)
+
+// Open runs every migration in a fresh Postgres schema dropped after the test
+func Open(t testing.TB) *sql.DB {
+	t.Helper()
+	db := OpenEmpty(t)
+	migrations, err := migrate.Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := migrate.NewMigrator(db, migrations).Up(context.Background()); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// OpenEmpty returns a connection to a fresh, empty Postgres schema dropped
+// after the test
+func OpenEmpty(t testing.TB) *sql.DB {
+	t.Helper()
+	dsn := os.Getenv("VEST_TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("set VEST_TEST_DATABASE_URL to run against Postgres")
+	}
+	admin, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	schema := fmt.Sprintf("vest_test_%d", time.Now().UnixNano())
+	if _, err := admin.Exec(`CREATE SCHEMA ` + schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`); err != nil {
+			t.Logf("Failed to drop %s: %v", schema, err)
+		}
+		admin.Close()
+	})
+
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		q := u.Query()
+		q.Set("search_path", schema)
+		u.RawQuery = q.Encode()
+		dsn = u.String()
+	} else {
+		dsn += " search_path=" + schema
+	}
+	db, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
@@ -0,0 +1,104 @@
+// Package tracing exports OpenTelemetry spans for HTTP and gRPC requests,
+// database queries, file ingestion and SFTP operations. Spans go to an OTLP
+// collector or, for local debugging, are written as JSON beside the logs.
+// Without an exporter the tracer is a no-op, though a caller's traceparent
+// is still carried into the logs.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Exporters
+const (
+	ExporterNone   = "none"
+	ExporterOTLP   = "otlp"
+	ExporterStdout = "stdout"
+)
+
+var ErrUnknownExporter = errors.New("tracing exporter must be none, otlp or stdout")
+
+// Options choose where spans go
+type Options struct {
+	Exporter string
+	// Endpoint is the OTLP collector's base URL; empty for the default or
+	// OTEL_EXPORTER_OTLP_ENDPOINT
+	Endpoint    string
+	SampleRatio float64 // Of new traces; a caller's sampling decision is kept
+	ServiceName string
+}
+
+// ValidExporter reports whether exporter names one Setup supports
+func ValidExporter(exporter string) bool {
+	switch exporter {
+	case "", ExporterNone, ExporterOTLP, ExporterStdout:
+		return true
+	}
+	return false
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The stdout exporter writes to w. The returned function flushes
+// buffered spans and must be called before exiting.
+func Setup(ctx context.Context, opts Options, w io.Writer) (shutdown func(context.Context) error, err error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	switch opts.Exporter {
+	case "", ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterOTLP:
+		var options []otlptracehttp.Option
+		if opts.Endpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(strings.TrimSuffix(opts.Endpoint, "/")+"/v1/traces"))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(w))
+	default:
+		return nil, fmt.Errorf("%w, got %q", ErrUnknownExporter, opts.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating the %s span exporter: %w", opts.Exporter, err)
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(opts.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(opts.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Tracer returns the tracer for one of vest's packages, such as "ingest"
+func Tracer(pkg string) trace.Tracer {
+	return otel.Tracer("github.com/AndrewCharlesHay/vest/internal/" + pkg)
+}
+
+// End records err on span, marking it failed, and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
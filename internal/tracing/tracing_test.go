@@ -0,0 +1,47 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestStdoutExporter(t *testing.T) {
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+	var buf bytes.Buffer
+	shutdown, err := Setup(context.Background(), Options{Exporter: ExporterStdout, SampleRatio: 1, ServiceName: "vest-test"}, &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, parent := Tracer("ingest").Start(context.Background(), "ingest.file")
+	_, child := Tracer("ingest").Start(ctx, "ingest.parse")
+	End(child, errors.New("not a format 1 file"))
+	End(parent, nil)
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{`"Name":"ingest.parse"`, `"Name":"ingest.file"`, `"Value":"vest-test"`, `"Description":"not a format 1 file"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected %s in %s", want, out)
+		}
+	}
+	if !strings.Contains(out, parent.SpanContext().SpanID().String()) {
+		t.Error("Expected the parse span's parent to be the file span")
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Options{Exporter: "jaeger"}, nil); !errors.Is(err, ErrUnknownExporter) {
+		t.Errorf("Expected ErrUnknownExporter, got %v", err)
+	}
+	if !ValidExporter("otlp") || ValidExporter("jaeger") {
+		t.Error("ValidExporter disagrees with Setup")
+	}
+}
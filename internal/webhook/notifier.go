@@ -0,0 +1,462 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AndrewCharlesHay/vest/internal/logging"
+	"github.com/AndrewCharlesHay/vest/internal/models"
+)
+
+var logger = logging.Component("webhook")
+
+// Event types sent to subscribers
+const (
+	EventAlarmOpened  = "alarm.opened"
+	EventAlarmCleared = "alarm.cleared"
+	EventTest         = "webhook.test"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// Headers set on every delivery. The signature is hex HMAC-SHA256 of "<timestamp>.<body>".
+const (
+	HeaderEvent     = "X-Vest-Event"
+	HeaderDelivery  = "X-Vest-Delivery"
+	HeaderTimestamp = "X-Vest-Timestamp"
+	HeaderSignature = "X-Vest-Signature"
+)
+
+var (
+	ErrSubscriptionNotFound = errors.New("webhook subscription not found")
+	ErrSubscriptionInactive = errors.New("webhook subscription is deactivated")
+)
+
+// Payload is the JSON body POSTed to subscribers
+type Payload struct {
+	DeliveryID int64     `json:"delivery_id"`
+	Event      string    `json:"event"`
+	CreatedAt  time.Time `json:"created_at"`
+	Data       any       `json:"data"`
+}
+
+// Notifier records webhook deliveries and sends them with retries
+type Notifier struct {
+	DB          *sql.DB
+	Client      *http.Client
+	MaxAttempts int
+	BaseBackoff time.Duration
+
+	kick chan struct{}
+}
+
+func NewNotifier(db *sql.DB) *Notifier {
+	return &Notifier{
+		DB:          db,
+		Client:      &http.Client{Timeout: 10 * time.Second},
+		MaxAttempts: 6,
+		BaseBackoff: 30 * time.Second,
+		kick:        make(chan struct{}, 1),
+	}
+}
+
+// Backoff returns the wait before retrying after the given (1-based) failed attempt
+func Backoff(base time.Duration, attempt int) time.Duration {
+	const maxBackoff = time.Hour
+	if attempt < 1 {
+		attempt = 1
+	}
+	d := base
+	for i := 1; i < attempt; i++ {
+		d *= 2
+		if d >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return d
+}
+
+// Sign computes the signature header value for a delivery body
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a signature header in constant time; receivers can reuse it
+func Verify(secret string, timestamp int64, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, timestamp, body)), []byte(signature))
+}
+
+// NewSecret generates a random signing secret for a subscription
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// Publish queues an event for every active subscription that wants it
+func (n *Notifier) Publish(ctx context.Context, event string, data any) error {
+	rows, err := n.DB.QueryContext(ctx, `
+		SELECT id FROM webhook_subscriptions
+		WHERE active AND (cardinality(events) = 0 OR $1 = ANY(events))
+	`, event)
+	if err != nil {
+		return err
+	}
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if _, err := n.enqueue(ctx, id, event, data, true); err != nil {
+			return err
+		}
+	}
+	if len(ids) > 0 {
+		n.wake()
+	}
+	return nil
+}
+
+// TestFire sends a test event to one subscription immediately and returns the outcome
+func (n *Notifier) TestFire(ctx context.Context, subscriptionID int64) (*models.WebhookDelivery, error) {
+	var active bool
+	err := n.DB.QueryRowContext(ctx, `SELECT active FROM webhook_subscriptions WHERE id = $1`, subscriptionID).Scan(&active)
+	if err == sql.ErrNoRows {
+		return nil, ErrSubscriptionNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !active {
+		return nil, ErrSubscriptionInactive
+	}
+
+	// Not queued as due, so the background sender doesn't race this first attempt
+	id, err := n.enqueue(ctx, subscriptionID, EventTest, map[string]string{"message": "Test delivery from vest"}, false)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := n.attempt(ctx, id); err != nil {
+		return nil, err
+	}
+	return n.delivery(ctx, id)
+}
+
+// enqueue records a delivery. The payload embeds the delivery ID so
+// receivers can de-duplicate retries, so the ID is allocated first and the
+// row is written with its payload in one statement; a sender never sees a
+// delivery without its body.
+func (n *Notifier) enqueue(ctx context.Context, subscriptionID int64, event string, data any, due bool) (int64, error) {
+	var id int64
+	var createdAt time.Time
+	err := n.DB.QueryRowContext(ctx, `
+		SELECT nextval(pg_get_serial_sequence('webhook_deliveries', 'id')), CURRENT_TIMESTAMP
+	`).Scan(&id, &createdAt)
+	if err != nil {
+		return 0, err
+	}
+
+	body, err := json.Marshal(Payload{DeliveryID: id, Event: event, CreatedAt: createdAt, Data: data})
+	if err != nil {
+		return 0, err
+	}
+	_, err = n.DB.ExecContext(ctx, `
+		INSERT INTO webhook_deliveries (id, subscription_id, event, payload, status, created_at, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, $6, CASE WHEN $7 THEN CURRENT_TIMESTAMP END)
+	`, id, subscriptionID, event, body, StatusPending, createdAt, due)
+	if err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (n *Notifier) wake() {
+	select {
+	case n.kick <- struct{}{}:
+	default:
+	}
+}
+
+// Start sends due deliveries until ctx is cancelled
+func (n *Notifier) Start(ctx context.Context) {
+	ticker := time.NewTicker(5 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-n.kick:
+		}
+		if err := n.ProcessDue(ctx); err != nil {
+			logger.ErrorContext(ctx, "Webhook delivery failed", "error", err)
+		}
+	}
+}
+
+// ProcessDue attempts every pending delivery whose retry time has passed
+func (n *Notifier) ProcessDue(ctx context.Context) error {
+	for {
+		found, err := n.attempt(ctx, 0)
+		if err != nil {
+			return err
+		}
+		if !found {
+			return nil
+		}
+	}
+}
+
+// claimLease is how long a claimed delivery is hidden from other senders.
+// It outlasts the client timeout; a sender that dies mid-send leaves the
+// delivery to be retried once the lease lapses.
+const claimLease = time.Minute
+
+// attempt sends one pending delivery, or the next due one when id is 0.
+// Deliveries to deactivated subscriptions are left pending and never sent.
+func (n *Notifier) attempt(ctx context.Context, id int64) (bool, error) {
+	// Claiming bumps attempts and pushes the retry time past the lease, and
+	// commits before sending, so no transaction or lock is held while the
+	// receiver responds
+	var url, secret, event string
+	var payload []byte
+	var attempts int
+	err := n.DB.QueryRowContext(ctx, `
+		UPDATE webhook_deliveries d
+		SET attempts = d.attempts + 1, next_attempt_at = CURRENT_TIMESTAMP + make_interval(secs => $3)
+		FROM webhook_subscriptions s
+		WHERE s.id = d.subscription_id
+		  AND d.id = (
+			SELECT c.id
+			FROM webhook_deliveries c
+			JOIN webhook_subscriptions cs ON cs.id = c.subscription_id
+			WHERE c.status = $1
+			  AND cs.active
+			  AND c.payload IS NOT NULL
+			  AND (($2::bigint = 0 AND c.next_attempt_at <= CURRENT_TIMESTAMP) OR c.id = $2::bigint)
+			ORDER BY c.next_attempt_at
+			LIMIT 1
+			FOR UPDATE OF c SKIP LOCKED
+		  )
+		RETURNING d.id, s.url, s.secret, d.event, d.payload, d.attempts
+	`, StatusPending, id, claimLease.Seconds()).Scan(&id, &url, &secret, &event, &payload, &attempts)
+	if err == sql.ErrNoRows {
+		// Nothing due, or claimed by another instance
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	code, sendErr := n.send(ctx, url, secret, id, event, payload)
+
+	// The attempt count fences the result: if the lease lapsed and another
+	// sender claimed the delivery since, its outcome is the one kept
+	var res sql.Result
+	switch {
+	case sendErr == nil:
+		res, err = n.DB.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $3, response_code = $4, last_error = NULL,
+				next_attempt_at = NULL, delivered_at = CURRENT_TIMESTAMP
+			WHERE id = $1 AND attempts = $2
+		`, id, attempts, StatusDelivered, code)
+	case attempts >= n.MaxAttempts:
+		logger.WarnContext(ctx, "Webhook delivery failed permanently", "delivery_id", id, "url", url, "error", sendErr)
+		res, err = n.DB.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET status = $3, response_code = NULLIF($4, 0), last_error = $5, next_attempt_at = NULL
+			WHERE id = $1 AND attempts = $2
+		`, id, attempts, StatusFailed, code, sendErr.Error())
+	default:
+		next := time.Now().Add(Backoff(n.BaseBackoff, attempts))
+		res, err = n.DB.ExecContext(ctx, `
+			UPDATE webhook_deliveries
+			SET response_code = NULLIF($3, 0), last_error = $4, next_attempt_at = $5
+			WHERE id = $1 AND attempts = $2
+		`, id, attempts, code, sendErr.Error(), next)
+	}
+	if err != nil {
+		return false, err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		logger.WarnContext(ctx, "Webhook delivery was reclaimed before its result was recorded", "delivery_id", id, "attempts", attempts)
+	}
+	return true, nil
+}
+
+// send POSTs a signed payload. Any non-2xx response is an error.
+func (n *Notifier) send(ctx context.Context, url, secret string, deliveryID int64, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderDelivery, strconv.FormatInt(deliveryID, 10))
+	req.Header.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	req.Header.Set(HeaderSignature, Sign(secret, ts, body))
+
+	resp, err := n.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("receiver returned %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// CreateSubscription registers a receiver, generating a secret when none is given
+func (n *Notifier) CreateSubscription(ctx context.Context, sub models.WebhookSubscription) (*models.WebhookSubscription, error) {
+	if sub.Secret == "" {
+		secret, err := NewSecret()
+		if err != nil {
+			return nil, err
+		}
+		sub.Secret = secret
+	}
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+
+	err := n.DB.QueryRowContext(ctx, `
+		INSERT INTO webhook_subscriptions (name, url, secret, events)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, active, created_at
+	`, sub.Name, sub.URL, sub.Secret, sub.Events).Scan(&sub.ID, &sub.Active, &sub.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// ListSubscriptions returns all subscriptions without their secrets
+func (n *Notifier) ListSubscriptions(ctx context.Context) ([]models.WebhookSubscription, error) {
+	rows, err := n.DB.QueryContext(ctx, `
+		SELECT id, name, url, array_to_string(events, ','), active, created_at
+		FROM webhook_subscriptions
+		ORDER BY id
+	`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []models.WebhookSubscription{}
+	for rows.Next() {
+		var s models.WebhookSubscription
+		var events string
+		if err := rows.Scan(&s.ID, &s.Name, &s.URL, &events, &s.Active, &s.CreatedAt); err != nil {
+			return nil, err
+		}
+		s.Events = []string{}
+		if events != "" {
+			s.Events = strings.Split(events, ",")
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+// DeactivateSubscription stops further deliveries to a subscription
+func (n *Notifier) DeactivateSubscription(ctx context.Context, id int64) error {
+	res, err := n.DB.ExecContext(ctx, `UPDATE webhook_subscriptions SET active = FALSE WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if count, _ := res.RowsAffected(); count == 0 {
+		return ErrSubscriptionNotFound
+	}
+	return nil
+}
+
+const deliveryColumns = `
+	id, subscription_id, event, status, attempts, COALESCE(response_code, 0), COALESCE(last_error, ''),
+	next_attempt_at, created_at, delivered_at
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanDelivery(row rowScanner) (*models.WebhookDelivery, error) {
+	var d models.WebhookDelivery
+	var next, delivered sql.NullTime
+	if err := row.Scan(&d.ID, &d.SubscriptionID, &d.Event, &d.Status, &d.Attempts, &d.ResponseCode,
+		&d.LastError, &next, &d.CreatedAt, &delivered); err != nil {
+		return nil, err
+	}
+	if next.Valid {
+		d.NextAttemptAt = &next.Time
+	}
+	if delivered.Valid {
+		d.DeliveredAt = &delivered.Time
+	}
+	return &d, nil
+}
+
+func (n *Notifier) delivery(ctx context.Context, id int64) (*models.WebhookDelivery, error) {
+	return scanDelivery(n.DB.QueryRowContext(ctx, `SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = $1`, id))
+}
+
+// Deliveries returns the most recent deliveries for a subscription
+func (n *Notifier) Deliveries(ctx context.Context, subscriptionID int64, limit int) ([]models.WebhookDelivery, error) {
+	rows, err := n.DB.QueryContext(ctx, `SELECT `+deliveryColumns+`
+		FROM webhook_deliveries
+		WHERE subscription_id = $1
+		ORDER BY id DESC
+		LIMIT $2
+	`, subscriptionID, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, *d)
+	}
+	return deliveries, rows.Err()
+}
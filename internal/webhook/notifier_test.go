@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestBackoff(t *testing.T) {
+	base := 30 * time.Second
+	cases := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		10: time.Hour, // capped
+	}
+	for attempt, want := range cases {
+		if got := Backoff(base, attempt); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	body := []byte(`{"event":"alarm.opened"}`)
+	sig := Sign("secret", 1700000000, body)
+
+	if !Verify("secret", 1700000000, body, sig) {
+		t.Error("Expected signature to verify")
+	}
+	if Verify("other", 1700000000, body, sig) {
+		t.Error("Expected wrong secret to fail")
+	}
+	if Verify("secret", 1700000001, body, sig) {
+		t.Error("Expected wrong timestamp to fail")
+	}
+}
+
+func TestSend(t *testing.T) {
+	var gotEvent string
+	var verified bool
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		gotEvent = r.Header.Get(HeaderEvent)
+		verified = Verify("whsec_test", ts, body, r.Header.Get(HeaderSignature))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	n := &Notifier{Client: receiver.Client()}
+	code, err := n.send(context.Background(), receiver.URL, "whsec_test", 42, EventAlarmOpened, []byte(`{"delivery_id":42}`))
+	if err != nil {
+		t.Fatalf("Expected delivery to succeed, got %v", err)
+	}
+	if code != http.StatusNoContent {
+		t.Errorf("Expected 204, got %d", code)
+	}
+	if gotEvent != EventAlarmOpened {
+		t.Errorf("Expected event header %s, got %s", EventAlarmOpened, gotEvent)
+	}
+	if !verified {
+		t.Error("Receiver could not verify signature")
+	}
+}
+
+func TestSend_ReceiverError(t *testing.T) {
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer receiver.Close()
+
+	n := &Notifier{Client: receiver.Client()}
+	code, err := n.send(context.Background(), receiver.URL, "s", 1, EventTest, []byte(`{}`))
+	if err == nil {
+		t.Fatal("Expected error for 503 response")
+	}
+	if code != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503, got %d", code)
+	}
+}
@@ -0,0 +1,161 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/AndrewCharlesHay/vest/internal/models"
+	"github.com/AndrewCharlesHay/vest/internal/testdb"
+)
+
+type received struct {
+	body     []byte
+	delivery string
+	verified bool
+}
+
+func TestPublishThenProcessDue(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	var mu sync.Mutex
+	var got []received
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(HeaderTimestamp), 10, 64)
+		mu.Lock()
+		got = append(got, received{body, r.Header.Get(HeaderDelivery), Verify("whsec_live", ts, body, r.Header.Get(HeaderSignature))})
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	n := NewNotifier(db)
+	n.Client = receiver.Client()
+	live, err := n.CreateSubscription(ctx, models.WebhookSubscription{Name: "live", URL: receiver.URL, Secret: "whsec_live", Events: []string{EventAlarmOpened}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	retired, err := n.CreateSubscription(ctx, models.WebhookSubscription{Name: "retired", URL: receiver.URL + "/retired", Secret: "whsec_retired"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.Publish(ctx, EventAlarmOpened, map[string]int{"alarm_id": 7}); err != nil {
+		t.Fatal(err)
+	}
+	// Queued for both, but deactivated before the sender runs
+	if err := n.DeactivateSubscription(ctx, retired.ID); err != nil {
+		t.Fatal(err)
+	}
+	// A row without a payload, as a crashed writer might leave, is never sent
+	if _, err := db.Exec(`
+		INSERT INTO webhook_deliveries (subscription_id, event, status, next_attempt_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+	`, live.ID, EventAlarmOpened, StatusPending); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := n.ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(got) != 1 {
+		t.Fatalf("Expected one delivery, to the live subscription, got %d", len(got))
+	}
+	if !got[0].verified {
+		t.Error("Receiver could not verify the signature")
+	}
+	var p struct {
+		DeliveryID int64          `json:"delivery_id"`
+		Event      string         `json:"event"`
+		Data       map[string]int `json:"data"`
+	}
+	if err := json.Unmarshal(got[0].body, &p); err != nil {
+		t.Fatalf("Expected a JSON body, got %q: %v", got[0].body, err)
+	}
+	if p.Event != EventAlarmOpened || p.Data["alarm_id"] != 7 || strconv.FormatInt(p.DeliveryID, 10) != got[0].delivery {
+		t.Errorf("Expected the event, its data and the delivery ID from the header, got %+v (header %s)", p, got[0].delivery)
+	}
+
+	deliveries, err := n.Deliveries(ctx, live.ID, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statuses := map[int64]string{}
+	for _, d := range deliveries {
+		statuses[d.ID] = d.Status
+	}
+	if statuses[p.DeliveryID] != StatusDelivered || len(statuses) != 2 {
+		t.Errorf("Expected the published delivery delivered and the empty one left, got %v", statuses)
+	}
+	if deliveries, _ := n.Deliveries(ctx, retired.ID, 10); len(deliveries) != 1 || deliveries[0].Status != StatusPending || deliveries[0].Attempts != 0 {
+		t.Errorf("Expected the deactivated subscription's delivery left unsent, got %+v", deliveries)
+	}
+	if _, err := n.TestFire(ctx, retired.ID); !errors.Is(err, ErrSubscriptionInactive) {
+		t.Errorf("Expected ErrSubscriptionInactive, got %v", err)
+	}
+}
+
+func TestSendHoldsNoConnection(t *testing.T) {
+	db := testdb.Open(t)
+	ctx := context.Background()
+
+	sending := make(chan struct{})
+	release := make(chan struct{})
+	var calls sync.WaitGroup
+	calls.Add(1)
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer calls.Done()
+		close(sending)
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receiver.Close()
+
+	n := NewNotifier(db)
+	n.Client = receiver.Client()
+	if _, err := n.CreateSubscription(ctx, models.WebhookSubscription{Name: "slow", URL: receiver.URL}); err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Publish(ctx, EventAlarmOpened, map[string]int{"alarm_id": 7}); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() { done <- n.ProcessDue(ctx) }()
+	<-sending
+
+	// The claim is committed, so a slow receiver ties up no connection, and
+	// another instance skips the delivery rather than sending it twice
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("Expected no connection held while sending, got %d", inUse)
+	}
+	if err := NewNotifier(db).ProcessDue(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	calls.Wait()
+
+	var status string
+	var attempts int
+	if err := db.QueryRow(`SELECT status, attempts FROM webhook_deliveries`).Scan(&status, &attempts); err != nil {
+		t.Fatal(err)
+	}
+	if status != StatusDelivered || attempts != 1 {
+		t.Errorf("Expected one delivered attempt, got %s after %d", status, attempts)
+	}
+}